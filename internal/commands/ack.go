@@ -10,7 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/docparse"
 )
 
 var (
@@ -46,7 +46,7 @@ func init() {
 }
 
 func runAck(cmd *cobra.Command, args []string) error {
-	g := git.New(rootDir)
+	g := openRepo()
 
 	ref := ackTo
 	if ref == "" {
@@ -65,7 +65,8 @@ func runAck(cmd *cobra.Command, args []string) error {
 	out := cmd.OutOrStdout()
 	for _, doc := range args {
 		doc = filepath.ToSlash(doc)
-		if _, statErr := os.Stat(filepath.Join(rootDir, doc)); statErr != nil {
+		path, _ := docparse.SplitAnchor(doc)
+		if _, statErr := os.Stat(filepath.Join(rootDir, path)); statErr != nil {
 			return fmt.Errorf("doc not found: %s", doc)
 		}
 		acks[doc] = sha
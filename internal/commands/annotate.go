@@ -0,0 +1,254 @@
+package commands
+
+// @doc README.md
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/StevenBock/docdiff/internal/language"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+var (
+	annotateDryRun        bool
+	annotateDocs          []string
+	annotateMinConfidence float64
+)
+
+var annotateCmd = &cobra.Command{
+	Use:   "annotate",
+	Short: "Insert the annotations suggest proposes into each orphaned file",
+	Long: `Apply 'docdiff suggest' in place: insert an annotation for its likely owning
+doc into each orphaned file.
+
+The annotation goes where the language expects a file-level comment: below the
+package clause in Go, into the leading docblock (or below <?php) in PHP, at
+the top of the <script> block in Vue, and elsewhere at the top of the file,
+after any shebang and encoding lines. It is written in the language's own
+comment syntax.
+
+Use --dry-run to see a unified diff instead, and --doc to apply only the
+suggestions for some docs. Files with no candidate at --min-confidence
+(suggest.min_confidence, default 0.35) or above are left alone.`,
+	RunE: runAnnotate,
+}
+
+func init() {
+	annotateCmd.Flags().BoolVar(&annotateDryRun, "dry-run", false, "print a unified diff instead of changing files")
+	annotateCmd.Flags().StringSliceVar(&annotateDocs, "doc", nil, "only apply suggestions for these docs")
+	annotateCmd.Flags().Float64Var(&annotateMinConfidence, "min-confidence", 0, "lowest confidence to annotate at (default: suggest.min_confidence, else 0.35)")
+	rootCmd.AddCommand(annotateCmd)
+}
+
+// annotateEdit is one planned insertion into a file.
+type annotateEdit struct {
+	file    string
+	doc     string
+	lines   []string // the file's lines, endings stripped
+	ins     language.Insertion
+	newline string // the file's line ending
+}
+
+type annotateSkip struct {
+	file, reason string
+}
+
+func runAnnotate(cmd *cobra.Command, args []string) error {
+	s := scanner.New(cfg, registry)
+	s.IndexSymbols() // for the mention signal
+	scanResult, err := s.Scan(rootDir)
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	suggestions, unmapped := suggestOwners(scanResult, minConfidence(cmd, annotateMinConfidence))
+
+	if len(annotateDocs) > 0 {
+		wanted := make(map[string]bool)
+		for _, doc := range annotateDocs {
+			wanted[cleanDocArg(doc)] = true
+		}
+		var kept []docSuggestion
+		for _, sg := range suggestions {
+			if wanted[sg.Doc] {
+				kept = append(kept, sg)
+			}
+		}
+		suggestions, unmapped = kept, nil
+	}
+
+	var edits []annotateEdit
+	var skipped []annotateSkip
+	for _, sg := range suggestions {
+		for _, file := range sg.Files {
+			edit, reason := planAnnotation(s, file, sg.Doc)
+			if reason != "" {
+				skipped = append(skipped, annotateSkip{file, reason})
+				continue
+			}
+			edits = append(edits, edit)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if annotateDryRun {
+		for _, e := range edits {
+			writeInsertionDiff(out, e)
+		}
+	} else {
+		for _, e := range edits {
+			if err := applyAnnotation(e); err != nil {
+				return err
+			}
+		}
+	}
+	writeAnnotateSummary(out, edits, skipped, unmapped)
+	return nil
+}
+
+// planAnnotation works out where file's annotation for doc goes. A non-empty
+// reason says why the file can't be annotated.
+func planAnnotation(s *scanner.Scanner, file, doc string) (annotateEdit, string) {
+	content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(file)))
+	if err != nil {
+		return annotateEdit{}, err.Error()
+	}
+	strategy, ok := s.Strategy(file, content)
+	if !ok {
+		return annotateEdit{}, "no language detected"
+	}
+	annotator, ok := strategy.(language.Annotator)
+	if !ok {
+		return annotateEdit{}, fmt.Sprintf("%s can't place annotations", strategy.Name())
+	}
+
+	newline := "\n"
+	if bytes.Contains(content, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	ins, ok := annotator.PlaceAnnotation(lines, cfg.AnnotationTag+" "+doc)
+	if !ok {
+		return annotateEdit{}, fmt.Sprintf("no comment syntax known for %s", strategy.Name())
+	}
+	e := annotateEdit{file: file, doc: doc, lines: lines, ins: ins, newline: newline}
+
+	// Only keep an insertion the scan will read back as intended.
+	_, details, _ := s.ExtractContent(file, []byte(e.content()))
+	for _, d := range details {
+		if scanner.ResolveDocPath(file, d.Path) == doc {
+			return e, ""
+		}
+	}
+	return annotateEdit{}, "the inserted comment wouldn't be read back as an annotation"
+}
+
+// content is the file with the insertion applied.
+func (e annotateEdit) content() string {
+	out := make([]string, 0, len(e.lines)+len(e.ins.Lines))
+	out = append(out, e.lines[:e.ins.At]...)
+	out = append(out, e.ins.Lines...)
+	out = append(out, e.lines[e.ins.At:]...)
+	return strings.Join(out, e.newline)
+}
+
+// annotationLine is the 1-based line the annotation lands on.
+func (e annotateEdit) annotationLine() int {
+	for i, l := range e.ins.Lines {
+		if strings.Contains(l, cfg.AnnotationTag) {
+			return e.ins.At + i + 1
+		}
+	}
+	return e.ins.At + 1
+}
+
+func applyAnnotation(e annotateEdit) error {
+	full := filepath.Join(rootDir, filepath.FromSlash(e.file))
+	info, err := os.Stat(full)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(full, []byte(e.content()), info.Mode().Perm())
+}
+
+// writeInsertionDiff prints the edit as a unified diff with three lines of
+// context.
+func writeInsertionDiff(out io.Writer, e annotateEdit) {
+	body := e.lines
+	noEOL := len(body) > 0 && body[len(body)-1] != ""
+	if !noEOL && len(body) > 0 {
+		body = body[:len(body)-1] // the empty string after a final newline isn't a line
+	}
+	at := min(e.ins.At, len(body))
+	start := max(0, at-3)
+	end := min(len(body), at+3)
+	oldStart, oldCount := start+1, end-start
+	if oldCount == 0 {
+		oldStart = start
+	}
+
+	fmt.Fprintf(out, "--- a/%s\n+++ b/%s\n", e.file, e.file)
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, start+1, oldCount+len(e.ins.Lines))
+	if noEOL && at == len(body) && at > 0 {
+		// Appending to a last line without a newline rewrites that line.
+		for _, l := range body[start : at-1] {
+			fmt.Fprintf(out, " %s\n", l)
+		}
+		fmt.Fprintf(out, "-%s\n%s+%s\n", body[at-1], noNewline, body[at-1])
+		for _, l := range e.ins.Lines {
+			fmt.Fprintf(out, "+%s\n", l)
+		}
+		fmt.Fprint(out, noNewline)
+		return
+	}
+	for _, l := range body[start:at] {
+		fmt.Fprintf(out, " %s\n", l)
+	}
+	for _, l := range e.ins.Lines {
+		fmt.Fprintf(out, "+%s\n", l)
+	}
+	for _, l := range body[at:end] {
+		fmt.Fprintf(out, " %s\n", l)
+	}
+	if noEOL && end == len(body) {
+		fmt.Fprint(out, noNewline)
+	}
+}
+
+const noNewline = "\\ No newline at end of file\n"
+
+func writeAnnotateSummary(out io.Writer, edits []annotateEdit, skipped []annotateSkip, unmapped []string) {
+	if annotateDryRun && len(edits) > 0 {
+		fmt.Fprintln(out)
+	}
+	switch {
+	case len(edits) == 0:
+		fmt.Fprintln(out, "No annotations to add.")
+	case annotateDryRun:
+		fmt.Fprintf(out, "Would annotate %d file(s). Run without --dry-run to apply.\n", len(edits))
+	default:
+		fmt.Fprintf(out, "Annotated %d file(s):\n", len(edits))
+		for _, e := range edits {
+			fmt.Fprintf(out, "  %s:%d  %s %s\n", e.file, e.annotationLine(), cfg.AnnotationTag, e.doc)
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(out, "\nSkipped (%d):\n", len(skipped))
+		for _, sk := range skipped {
+			fmt.Fprintf(out, "  %s: %s\n", sk.file, sk.reason)
+		}
+	}
+	if len(unmapped) > 0 {
+		fmt.Fprintf(out, "\n%d file(s) have no confident doc candidate; see 'docdiff suggest'.\n", len(unmapped))
+	}
+	if len(edits) > 0 && !annotateDryRun {
+		fmt.Fprintln(out, "\nReview the annotations, then commit the source and its doc together.")
+	}
+}
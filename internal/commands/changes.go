@@ -11,6 +11,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/StevenBock/docdiff/internal/docparse"
 	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
@@ -60,7 +61,7 @@ func runChanges(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	g := git.New(rootDir)
+	g := openRepo()
 
 	acks, err := loadAcks(rootDir)
 	if err != nil {
@@ -77,26 +78,30 @@ func runChanges(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Scoped annotations narrow every view below to the commits, files and
+	// hunks that touched the doc's owned regions.
+	scope := newDocScope(g, doc, scanResult.Ownership())
+
 	if changesWorkTree || changesStaged {
-		return outputWorkTree(out, g, doc, lastHash, files, changesStaged)
+		return outputWorkTree(out, g, scope, doc, lastHash, files, changesStaged)
 	}
 
 	if changesAI {
-		return outputAI(out, g, doc, lastHash, files)
+		return outputAI(out, g, scope, doc, lastHash, files)
 	}
 
 	if changesSummary {
-		return outputSummary(out, g, doc, lastHash, files)
+		return outputSummary(out, g, scope, doc, lastHash, files)
 	}
 
 	if changesCommits {
-		return outputCommitsOnly(out, g, doc, lastHash, files)
+		return outputCommitsOnly(out, scope, doc, lastHash, files)
 	}
 
-	return outputDefault(out, g, doc, lastHash, files)
+	return outputDefault(out, g, scope, doc, lastHash, files)
 }
 
-func outputDefault(out io.Writer, g *git.Git, doc, lastHash string, files []string) error {
+func outputDefault(out io.Writer, g git.Repo, scope *docScope, doc, lastHash string, files []string) error {
 	lastCommitInfo, _ := g.CommitInfo(lastHash)
 	currentHead, _ := g.HeadShort()
 
@@ -104,8 +109,9 @@ func outputDefault(out io.Writer, g *git.Git, doc, lastHash string, files []stri
 	fmt.Fprintln(out, strings.Repeat("=", 60))
 	fmt.Fprintln(out)
 
-	commits, _ := g.CommitsBetween(lastHash, "HEAD", files)
+	commits := scope.commits(lastHash, files)
 	changed, _ := g.ChangedFilesBetween(lastHash, "HEAD", files)
+	changed = scope.committedFiles(lastHash, changed)
 
 	fmt.Fprintf(out, "Commits: %d\n", len(commits))
 	fmt.Fprintf(out, "Files changed: %d\n", len(changed))
@@ -118,33 +124,38 @@ func outputDefault(out io.Writer, g *git.Git, doc, lastHash string, files []stri
 
 	fmt.Fprintln(out, "--- Commits ---")
 	for _, c := range commits {
-		fmt.Fprintln(out, c)
+		fmt.Fprintf(out, "%s %s\n", c.Short, c.Subject)
 	}
 	fmt.Fprintln(out)
 
+	if len(changed) == 0 {
+		return nil
+	}
 	fmt.Fprintln(out, "--- Diff ---")
-	diff, _ := g.Diff(lastHash, "HEAD", files)
+	diff, _ := g.Diff(lastHash, "HEAD", changed)
+	diff = filterDiffHunks(diff, scope.hunkFilter(scope.headAnnotations(scope.scopedFiles(changed))))
 	fmt.Fprintln(out, maybeHideAnnotations(diff))
 
 	return nil
 }
 
-func outputWorkTree(out io.Writer, g *git.Git, doc, lastHash string, files []string, staged bool) error {
+func outputWorkTree(out io.Writer, g git.Repo, scope *docScope, doc, lastHash string, files []string, staged bool) error {
 	lastCommitInfo, _ := g.CommitInfo(lastHash)
-	scope := "working tree"
+	label := "working tree"
 	if staged {
-		scope = "staged changes"
+		label = "staged changes"
 	}
 
-	fmt.Fprintf(out, "Changes to %s files since %s (including %s)\n", doc, lastCommitInfo, scope)
+	fmt.Fprintf(out, "Changes to %s files since %s (including %s)\n", doc, lastCommitInfo, label)
 	fmt.Fprintln(out, strings.Repeat("=", 60))
 	fmt.Fprintln(out)
 
 	changed, _ := g.ChangedFilesSince(lastHash, staged, files)
+	changed = scope.worktreeFiles(lastHash, staged, changed)
 	fmt.Fprintf(out, "Files changed: %d\n\n", len(changed))
 
 	if len(changed) == 0 {
-		fmt.Fprintf(out, "No %s changes to %s files since the doc was last committed.\n", scope, doc)
+		fmt.Fprintf(out, "No %s changes to %s files since the doc was last committed.\n", label, doc)
 		return nil
 	}
 
@@ -154,14 +165,15 @@ func outputWorkTree(out io.Writer, g *git.Git, doc, lastHash string, files []str
 	fmt.Fprintln(out)
 
 	fmt.Fprintln(out, "--- Diff ---")
-	diff, _ := g.DiffSince(lastHash, staged, files)
+	diff, _ := g.DiffSince(lastHash, staged, changed)
+	diff = filterDiffHunks(diff, scope.hunkFilter(scope.anns))
 	fmt.Fprintln(out, maybeHideAnnotations(diff))
 
 	return nil
 }
 
-func outputCommitsOnly(out io.Writer, g *git.Git, doc, lastHash string, files []string) error {
-	commits, _ := g.CommitsBetween(lastHash, "HEAD", files)
+func outputCommitsOnly(out io.Writer, scope *docScope, doc, lastHash string, files []string) error {
+	commits := scope.commits(lastHash, files)
 
 	if len(commits) == 0 {
 		fmt.Fprintln(out, "No commits since last documentation update.")
@@ -170,13 +182,13 @@ func outputCommitsOnly(out io.Writer, g *git.Git, doc, lastHash string, files []
 
 	fmt.Fprintf(out, "Commits affecting %s files since %s:\n\n", doc, lastHash)
 	for _, c := range commits {
-		fmt.Fprintln(out, c)
+		fmt.Fprintf(out, "%s %s\n", c.Short, c.Subject)
 	}
 
 	return nil
 }
 
-func outputSummary(out io.Writer, g *git.Git, doc, lastHash string, files []string) error {
+func outputSummary(out io.Writer, g git.Repo, scope *docScope, doc, lastHash string, files []string) error {
 	currentHead, _ := g.HeadShort()
 	lastDate, _ := g.CommitDate(lastHash)
 	currentDate, _ := g.CommitDate(currentHead)
@@ -193,8 +205,8 @@ func outputSummary(out io.Writer, g *git.Git, doc, lastHash string, files []stri
 	}
 	fmt.Fprintln(out)
 
-	commits, _ := g.CommitsBetween(lastHash, "HEAD", files)
-	if len(commits) == 0 {
+	details := scope.commits(lastHash, files)
+	if len(details) == 0 {
 		fmt.Fprintln(out, "## No changes since last doc update")
 		return nil
 	}
@@ -202,16 +214,14 @@ func outputSummary(out io.Writer, g *git.Git, doc, lastHash string, files []stri
 	fmt.Fprintln(out, "## Changes since last doc update:")
 	fmt.Fprintln(out)
 
-	details, _ := g.CommitDetails(lastHash, "HEAD", files)
 	for _, commit := range details {
 		fmt.Fprintf(out, "### %s - %s\n\n", commit.Short, commit.Subject)
 
-		changedFiles, _ := g.FilesChangedInCommit(commit.Hash, files)
+		changedFiles, diff := scopedCommitDiff(g, scope, commit.Hash, files)
 		if len(changedFiles) > 0 {
 			fmt.Fprintf(out, "**Files:** %s\n\n", strings.Join(changedFiles, ", "))
 		}
 
-		diff, _ := g.ShowCommitDiff(commit.Hash, files)
 		diff = maybeHideAnnotations(diff)
 		if strings.TrimSpace(diff) != "" {
 			fmt.Fprintln(out, "```diff")
@@ -224,7 +234,7 @@ func outputSummary(out io.Writer, g *git.Git, doc, lastHash string, files []stri
 	return nil
 }
 
-func outputAI(out io.Writer, g *git.Git, doc, lastHash string, files []string) error {
+func outputAI(out io.Writer, g git.Repo, scope *docScope, doc, lastHash string, files []string) error {
 	currentHead, _ := g.HeadShort()
 	lastDate, _ := g.CommitDate(lastHash)
 	currentDate, _ := g.CommitDate(currentHead)
@@ -235,8 +245,15 @@ func outputAI(out io.Writer, g *git.Git, doc, lastHash string, files []string) e
 	fmt.Fprintln(out, doc)
 	fmt.Fprintln(out)
 
-	docPath := filepath.Join(rootDir, doc)
-	docContent, err := os.ReadFile(docPath)
+	docPath, anchor := docparse.SplitAnchor(doc)
+	docContent, err := os.ReadFile(filepath.Join(rootDir, docPath))
+	if err == nil && anchor != "" {
+		// A section target only needs its own section as context.
+		if h, ok := formats.Section(docPath, docContent, anchor); ok {
+			lines := strings.Split(string(docContent), "\n")
+			docContent = []byte(strings.Join(lines[h.Line-1:h.End], "\n") + "\n")
+		}
+	}
 	if err == nil {
 		fmt.Fprintln(out, "## Current Documentation")
 		fmt.Fprintln(out, "```markdown")
@@ -258,25 +275,23 @@ func outputAI(out io.Writer, g *git.Git, doc, lastHash string, files []string) e
 	fmt.Fprintf(out, "Doc last committed: %s (%s)\n", lastHash, lastDate)
 	fmt.Fprintf(out, "Current HEAD: %s (%s)\n", currentHead, currentDate)
 
-	commits, _ := g.CommitsBetween(lastHash, "HEAD", files)
-	fmt.Fprintf(out, "Commits: %d\n", len(commits))
+	details := scope.commits(lastHash, files)
+	fmt.Fprintf(out, "Commits: %d\n", len(details))
 	fmt.Fprintln(out)
 
-	if len(commits) == 0 {
+	if len(details) == 0 {
 		fmt.Fprintln(out, "No changes since last documentation update.")
 		return nil
 	}
 
-	details, _ := g.CommitDetails(lastHash, "HEAD", files)
 	for _, commit := range details {
 		fmt.Fprintf(out, "### Commit: %s - %s\n", commit.Short, commit.Subject)
 
-		changedFiles, _ := g.FilesChangedInCommit(commit.Hash, files)
+		changedFiles, diff := scopedCommitDiff(g, scope, commit.Hash, files)
 		if len(changedFiles) > 0 {
 			fmt.Fprintf(out, "**Files:** %s\n\n", strings.Join(changedFiles, ", "))
 		}
 
-		diff, _ := g.ShowCommitDiff(commit.Hash, files)
 		diff = maybeHideAnnotations(diff)
 		if strings.TrimSpace(diff) != "" {
 			fmt.Fprintln(out, "```diff")
@@ -292,3 +307,16 @@ func outputAI(out io.Writer, g *git.Git, doc, lastHash string, files []string) e
 
 	return nil
 }
+
+// scopedCommitDiff returns the linked files a commit changed within the doc's
+// owned regions, and that commit's diff limited to them and their regions.
+func scopedCommitDiff(g git.Repo, scope *docScope, hash string, files []string) ([]string, string) {
+	touched, _ := g.FilesChangedInCommit(hash, files)
+	touched = scope.commitFiles(hash, touched)
+	if len(touched) == 0 {
+		return nil, ""
+	}
+	diff, _ := g.ShowCommitDiff(hash, touched)
+	anns := scope.commitAnnotations(hash, scope.scopedFiles(touched))
+	return touched, filterDiffHunks(diff, scope.hunkFilter(anns))
+}
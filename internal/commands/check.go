@@ -6,11 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/StevenBock/docdiff/internal/docparse"
 	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
@@ -58,18 +61,54 @@ type checkResult struct {
 }
 
 type annotationProvenance struct {
-	File  string `json:"file"`
-	Line  int    `json:"line,omitempty"`
-	Kind  string `json:"kind"`
-	Scope string `json:"scope,omitempty"`
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	EndLine int    `json:"end_line,omitempty"` // end of the owned declaration, under declaration ownership
+	Kind    string `json:"kind"`
+	Scope   string `json:"scope,omitempty"`
+	Pattern string `json:"pattern,omitempty"` // the declaring glob, for a front matter or mapping link
 }
 
 func runCheck(cmd *cobra.Command, args []string) error {
-	g := git.New(rootDir)
+	o, err := evaluateCheck(openRepo(), scanner.New(cfg, registry), cmd.ErrOrStderr(), true)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if checkJSON {
+		writeCheckJSON(out, o)
+	} else {
+		writeCheckHuman(out, o)
+	}
 
+	if o.needsUpdate > 0 {
+		return ErrDocsNeedUpdate
+	}
+	if cfg.CI.FailOnBrokenAnnotations && len(o.broken) > 0 {
+		return ErrBrokenAnnotationsFound
+	}
+	return nil
+}
+
+// checkOutcome is everything `check` reports about one changeset.
+type checkOutcome struct {
+	source         string
+	results        []checkResult
+	undocRefs      []scanner.UndocumentedRef
+	broken         []scanner.BrokenAnnotation // in changed files
+	unrelatedStale int
+	needsUpdate    int
+	warnings       []string
+}
+
+// evaluateCheck scans with s and works out which docs the current changeset
+// affects. Counting unrelated stale docs walks the whole history, so callers
+// that don't show the count can skip it.
+func evaluateCheck(g git.Repo, s *scanner.Scanner, errOut io.Writer, countUnrelated bool) (*checkOutcome, error) {
 	changed, source, err := changedSet(g)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	inChange := make(map[string]bool, len(changed))
@@ -88,26 +127,38 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		hunks, _ = g.ChangedHunksSince("HEAD", true, changed)
 	}
 
-	s := scanner.New(cfg, registry)
 	scanResult, err := s.Scan(rootDir)
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		return nil, fmt.Errorf("scan failed: %w", err)
 	}
 
 	acks, err := loadAcks(rootDir)
 	if err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load %s: %v\n", acksFile, err)
+		fmt.Fprintf(errOut, "Warning: failed to load %s: %v\n", acksFile, err)
 		acks = map[string]string{}
 	}
 
-	results := make([]checkResult, 0)
+	o := &checkOutcome{source: source, results: make([]checkResult, 0), warnings: make([]string, 0)}
+
+	// A broken annotation's doc doesn't exist, so there's nothing to update:
+	// report the annotation itself instead of a phantom doc.
+	phantom := make(map[string]bool)
+	for _, b := range scanResult.BrokenAnnotations {
+		phantom[b.DocPath] = true
+		if inChange[b.SourceFile] {
+			o.broken = append(o.broken, b)
+		}
+	}
+
 	affected := make(map[string]bool)
-	warnings := make([]string, 0)
 	seenWarnings := make(map[string]bool)
 	for doc, files := range scanResult.FilesByDoc {
+		if phantom[doc] {
+			continue
+		}
 		var linkedChanged []string
 		for _, f := range files {
-			if inChange[f] && fileHitsDoc(scanResult.Annotations[f], doc, hunks) {
+			if inChange[f] && fileHitsDoc(scanResult.Ownership()[f], doc, hunks) {
 				linkedChanged = append(linkedChanged, f)
 			}
 		}
@@ -116,8 +167,9 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		}
 		sort.Strings(linkedChanged)
 
-		if !inChange[doc] {
-			linkedChanged = changedFilesSinceBaseline(g, doc, linkedChanged, source, acks, cmd.ErrOrStderr())
+		docChanged := docInChange(doc, inChange, hunks)
+		if !docChanged {
+			linkedChanged = changedFilesSinceBaseline(g, doc, linkedChanged, source, acks, errOut)
 			if len(linkedChanged) == 0 {
 				continue
 			}
@@ -125,70 +177,87 @@ func runCheck(cmd *cobra.Command, args []string) error {
 		affected[doc] = true
 
 		status := "needs update"
-		if inChange[doc] {
+		if docChanged {
 			status = "updated"
 		}
 		provenance := make([]annotationProvenance, 0)
 		for _, f := range linkedChanged {
-			provenance = append(provenance, provenanceForDoc(scanResult.Annotations[f], doc, f)...)
+			provenance = append(provenance, provenanceForDoc(scanResult, doc, f)...)
 			for _, warning := range annotationWarnings(scanResult.Annotations[f], doc) {
 				if !seenWarnings[warning] {
 					seenWarnings[warning] = true
-					warnings = append(warnings, warning)
+					o.warnings = append(o.warnings, warning)
 				}
 			}
 		}
-		results = append(results, checkResult{
+		o.results = append(o.results, checkResult{
 			Doc:             doc,
 			Status:          status,
-			DocInChangeset:  inChange[doc],
+			DocInChangeset:  docChanged,
 			ChangedFiles:    linkedChanged,
 			LinkedFileCount: len(files),
 			Annotations:     provenance,
 		})
 	}
 
-	sort.Slice(results, func(i, j int) bool { return results[i].Doc < results[j].Doc })
-	sort.Strings(warnings)
+	sort.Slice(o.results, func(i, j int) bool { return o.results[i].Doc < o.results[j].Doc })
+	sort.Strings(o.warnings)
 
 	// Count stale docs that are NOT related to the current change, so we can
 	// say how much noise we hid. Best-effort: needs the metadata file.
-	unrelatedStale := unrelatedStaleCount(g, scanResult.FilesByDoc, affected, cmd.ErrOrStderr())
+	if countUnrelated {
+		o.unrelatedStale = unrelatedStaleCount(g, scanResult, affected, errOut)
+	}
 
 	// Surface missing back-links for the docs this change touches, so they're
 	// caught in the normal flow instead of a separate `report --undocumented`.
 	// This is hygiene, not a blocker: it never gates the exit code.
-	var undocRefs []scanner.UndocumentedRef
 	if !checkNoBacklinks {
 		for _, ref := range scanResult.UndocumentedRefs {
 			if affected[ref.DocPath] {
-				undocRefs = append(undocRefs, ref)
+				o.undocRefs = append(o.undocRefs, ref)
 			}
 		}
 	}
 
-	needsUpdate := 0
-	for _, r := range results {
+	for _, r := range o.results {
 		if r.Status == "needs update" {
-			needsUpdate++
+			o.needsUpdate++
 		}
 	}
+	return o, nil
+}
 
-	out := cmd.OutOrStdout()
-	if checkJSON {
-		writeCheckJSON(out, source, results, undocRefs, unrelatedStale, needsUpdate, warnings)
-	} else {
-		writeCheckHuman(out, source, results, undocRefs, unrelatedStale, needsUpdate, warnings)
+// docInChange reports whether the changeset edits doc. A section target
+// such as docs/API.md#auth counts only when a changed hunk overlaps the
+// section's lines; without hunk info (--files) any change to its file does.
+func docInChange(doc string, inChange map[string]bool, hunks map[string][]git.LineRange) bool {
+	file, anchor := docparse.SplitAnchor(doc)
+	if !inChange[file] {
+		return false
 	}
-
-	if needsUpdate > 0 {
-		return ErrDocsNeedUpdate
+	ranges, ok := hunks[file]
+	if anchor == "" || !ok {
+		return true
 	}
-	return nil
+	content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(file)))
+	if err != nil {
+		return true
+	}
+	section, found := formats.Section(file, content, anchor)
+	if !found {
+		return true // the heading moved or was renamed; the file did change
+	}
+	for _, h := range ranges {
+		if h.Start <= section.End && h.End >= section.Line {
+			return true
+		}
+	}
+	return false
 }
 
 // changedSet resolves the set of changed source files and a label for it.
-func changedSet(g *git.Git) ([]string, string, error) {
+func changedSet(g git.Repo) ([]string, string, error) {
 	switch {
 	case len(checkFiles) > 0:
 		files := make([]string, 0, len(checkFiles))
@@ -210,9 +279,9 @@ func changedSet(g *git.Git) ([]string, string, error) {
 	}
 }
 
-func unrelatedStaleCount(g *git.Git, filesByDoc map[string][]string, affected map[string]bool, errOut io.Writer) int {
+func unrelatedStaleCount(g git.Repo, scanResult *scanner.Result, affected map[string]bool, errOut io.Writer) int {
 	count := 0
-	for doc := range computeStaleDocs(g, filesByDoc, errOut) {
+	for doc := range computeStaleDocs(g, scanResult, errOut) {
 		if !affected[doc] {
 			count++
 		}
@@ -220,7 +289,7 @@ func unrelatedStaleCount(g *git.Git, filesByDoc map[string][]string, affected ma
 	return count
 }
 
-func changedFilesSinceBaseline(g *git.Git, doc string, files []string, source string, acks map[string]string, errOut io.Writer) []string {
+func changedFilesSinceBaseline(g git.Repo, doc string, files []string, source string, acks map[string]string, errOut io.Writer) []string {
 	if source == "working tree" {
 		return files
 	}
@@ -260,11 +329,13 @@ func changedFilesSinceBaseline(g *git.Git, doc string, files []string, source st
 	return filtered
 }
 
-func writeCheckHuman(out io.Writer, source string, results []checkResult, undocRefs []scanner.UndocumentedRef, unrelatedStale, needsUpdate int, warnings []string) {
+func writeCheckHuman(out io.Writer, o *checkOutcome) {
+	source, results, needsUpdate := o.source, o.results, o.needsUpdate
 	if len(results) == 0 {
 		fmt.Fprintf(out, "No docs are linked to your %s changes.\n", source)
-		if unrelatedStale > 0 {
-			fmt.Fprintf(out, "(%d unrelated stale docs hidden — run 'docdiff report' to see them.)\n", unrelatedStale)
+		writeBrokenAnnotations(out, o.broken)
+		if o.unrelatedStale > 0 {
+			fmt.Fprintf(out, "(%d unrelated stale docs hidden — run 'docdiff report' to see them.)\n", o.unrelatedStale)
 		}
 		return
 	}
@@ -294,23 +365,34 @@ func writeCheckHuman(out io.Writer, source string, results []checkResult, undocR
 		}
 	}
 
-	if len(warnings) > 0 {
+	writeBrokenAnnotations(out, o.broken)
+
+	if len(o.warnings) > 0 {
 		fmt.Fprintf(out, "\nAnnotation warnings:\n")
-		for _, warning := range warnings {
+		for _, warning := range o.warnings {
 			fmt.Fprintf(out, "  %s\n", warning)
 		}
 	}
 
 	// Section 3 — hygiene: missing back-links. Never gates the exit code.
-	if len(undocRefs) > 0 {
-		fmt.Fprintf(out, "\nBack-link hygiene — optional (%d):\n", len(undocRefs))
-		for _, ref := range undocRefs {
-			fmt.Fprintf(out, "  %s references %s (add: %s %s)\n", ref.DocPath, ref.SourceFile, cfg.AnnotationTag, ref.DocPath)
+	if len(o.undocRefs) > 0 {
+		fmt.Fprintf(out, "\nBack-link hygiene — optional (%d):\n", len(o.undocRefs))
+		for _, ref := range o.undocRefs {
+			what := ref.SourceFile
+			if ref.Symbol != "" {
+				what = fmt.Sprintf("`%s` in %s", ref.Symbol, ref.SourceFile)
+			}
+			if ref.Start > 0 {
+				fmt.Fprintf(out, "  %s references %s lines %d-%d (add at line %d: %s %s #<scope>)\n",
+					ref.DocPath, what, ref.Start, ref.End, ref.Start, cfg.AnnotationTag, ref.DocPath)
+				continue
+			}
+			fmt.Fprintf(out, "  %s references %s (add: %s %s)\n", ref.DocPath, what, cfg.AnnotationTag, ref.DocPath)
 		}
 	}
 
-	if unrelatedStale > 0 {
-		fmt.Fprintf(out, "\nUnrelated stale docs: %d hidden (run 'docdiff report' for the full picture).\n", unrelatedStale)
+	if o.unrelatedStale > 0 {
+		fmt.Fprintf(out, "\nUnrelated stale docs: %d hidden (run 'docdiff report' for the full picture).\n", o.unrelatedStale)
 	}
 
 	if needsUpdate > 0 {
@@ -320,6 +402,22 @@ func writeCheckHuman(out io.Writer, source string, results []checkResult, undocR
 	}
 }
 
+// writeBrokenAnnotations lists annotations in the changeset whose doc doesn't
+// exist, with the closest existing doc when there is one.
+func writeBrokenAnnotations(out io.Writer, broken []scanner.BrokenAnnotation) {
+	if len(broken) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nBroken annotations (%d):\n", len(broken))
+	for _, b := range broken {
+		fmt.Fprintf(out, "  %s:%d %s %s: doc %s", b.SourceFile, b.Line, cfg.AnnotationTag, b.DocPath, b.Reason)
+		if b.Suggestion != "" {
+			fmt.Fprintf(out, " — did you mean %s?", b.Suggestion)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
 const broadDocLinkedFileThreshold = 20
 
 func broadHint(r checkResult) string {
@@ -332,6 +430,10 @@ func broadHint(r checkResult) string {
 func writeProvenance(out io.Writer, r checkResult) {
 	for _, p := range r.Annotations {
 		switch {
+		case p.Pattern != "":
+			fmt.Fprintf(out, "    via %s (%s)\n", linkOrigin(p.Kind, p.Pattern, p.Scope), linkRegion(p.File, p.Line, p.EndLine))
+		case p.Kind == "scoped" && p.EndLine > 0:
+			fmt.Fprintf(out, "    via %s:%d-%d scoped %s #%s\n", p.File, p.Line, p.EndLine, cfg.AnnotationTag, p.Scope)
 		case p.Kind == "scoped":
 			fmt.Fprintf(out, "    via %s:%d scoped %s #%s\n", p.File, p.Line, cfg.AnnotationTag, p.Scope)
 		case p.Line > 0:
@@ -342,7 +444,15 @@ func writeProvenance(out io.Writer, r checkResult) {
 	}
 }
 
-func provenanceForDoc(ann *scanner.Annotation, doc, file string) []annotationProvenance {
+func provenanceForDoc(scanResult *scanner.Result, doc, file string) []annotationProvenance {
+	if links := scanResult.LinksFor(doc, file); len(links) > 0 {
+		out := make([]annotationProvenance, 0, len(links))
+		for _, l := range links {
+			out = append(out, annotationProvenance{File: file, Line: l.Start, EndLine: l.End, Kind: l.Kind, Scope: l.Scope, Pattern: l.Pattern})
+		}
+		return out
+	}
+	ann := scanResult.Annotations[file]
 	if ann == nil {
 		return []annotationProvenance{{File: file, Kind: "unknown annotation"}}
 	}
@@ -357,10 +467,11 @@ func provenanceForDoc(ann *scanner.Annotation, doc, file string) []annotationPro
 			kind = "scoped"
 		}
 		out = append(out, annotationProvenance{
-			File:  file,
-			Line:  d.Line,
-			Kind:  kind,
-			Scope: d.Scope,
+			File:    file,
+			Line:    d.Line,
+			EndLine: d.End,
+			Kind:    kind,
+			Scope:   d.Scope,
 		})
 	}
 	if len(out) == 0 {
@@ -369,6 +480,28 @@ func provenanceForDoc(ann *scanner.Annotation, doc, file string) []annotationPro
 	return out
 }
 
+// linkOrigin describes where a declared link comes from, such as "front
+// matter glob src/billing/**" or "mapping path src/settings.go #mobile".
+func linkOrigin(kind, pattern, scope string) string {
+	what := "path"
+	if scanner.IsGlob(pattern) {
+		what = "glob"
+	}
+	origin := fmt.Sprintf("%s %s %s", strings.ReplaceAll(kind, "-", " "), what, pattern)
+	if scope != "" {
+		origin += " #" + scope
+	}
+	return origin
+}
+
+// linkRegion is file, with the lines a scoped link owns when it has some.
+func linkRegion(file string, start, end int) string {
+	if start == 0 {
+		return file
+	}
+	return fmt.Sprintf("%s:%d-%d", file, start, end)
+}
+
 func annotationWarnings(ann *scanner.Annotation, doc string) []string {
 	if ann == nil {
 		return nil
@@ -391,27 +524,35 @@ func annotationWarnings(ann *scanner.Annotation, doc string) []string {
 	return nil
 }
 
-func writeCheckJSON(out io.Writer, source string, results []checkResult, undocRefs []scanner.UndocumentedRef, unrelatedStale, needsUpdate int, warnings []string) {
+func writeCheckJSON(out io.Writer, o *checkOutcome) {
+	undocRefs := o.undocRefs
 	if undocRefs == nil {
 		undocRefs = []scanner.UndocumentedRef{}
 	}
+	broken := o.broken
+	if broken == nil {
+		broken = []scanner.BrokenAnnotation{}
+	}
+	warnings := o.warnings
 	if warnings == nil {
 		warnings = []string{}
 	}
 	payload := struct {
-		Source           string                    `json:"source"`
-		Affected         []checkResult             `json:"affected"`
-		UndocumentedRefs []scanner.UndocumentedRef `json:"undocumented_refs"`
-		NeedsUpdate      int                       `json:"needs_update"`
-		UnrelatedStale   int                       `json:"unrelated_stale"`
-		Warnings         []string                  `json:"warnings"`
+		Source            string                     `json:"source"`
+		Affected          []checkResult              `json:"affected"`
+		UndocumentedRefs  []scanner.UndocumentedRef  `json:"undocumented_refs"`
+		BrokenAnnotations []scanner.BrokenAnnotation `json:"broken_annotations"`
+		NeedsUpdate       int                        `json:"needs_update"`
+		UnrelatedStale    int                        `json:"unrelated_stale"`
+		Warnings          []string                   `json:"warnings"`
 	}{
-		Source:           source,
-		Affected:         results,
-		UndocumentedRefs: undocRefs,
-		NeedsUpdate:      needsUpdate,
-		UnrelatedStale:   unrelatedStale,
-		Warnings:         warnings,
+		Source:            o.source,
+		Affected:          o.results,
+		UndocumentedRefs:  undocRefs,
+		BrokenAnnotations: broken,
+		NeedsUpdate:       o.needsUpdate,
+		UnrelatedStale:    o.unrelatedStale,
+		Warnings:          warnings,
 	}
 	data, _ := json.MarshalIndent(payload, "", "  ")
 	fmt.Fprintln(out, string(data))
@@ -2,6 +2,8 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/StevenBock/docdiff/internal/config"
 	"github.com/StevenBock/docdiff/internal/language"
+	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 func setupTestProject(t *testing.T) string {
@@ -80,7 +83,10 @@ func initTestEnv(t *testing.T, dir string) {
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	registry = language.DefaultRegistry()
+	registry, err = language.FromConfig(cfg.Languages)
+	if err != nil {
+		t.Fatalf("Failed to build registry: %v", err)
+	}
 }
 
 // commitAll stages everything and commits it in dir.
@@ -193,6 +199,33 @@ func Handler() { /* changed */ }
 	}
 }
 
+func TestReport_Fresh_RevertedChange(t *testing.T) {
+	dir := setupTestProject(t)
+
+	// A code change undone by `git revert` leaves the file as the doc last
+	// saw it, so the doc is still current.
+	os.WriteFile(filepath.Join(dir, "src", "handler.go"), []byte(`package main
+
+// @doc docs/API.md
+func Handler() { /* changed */ }
+`), 0644)
+	commitAll(t, dir, "Change handler")
+	runGit(t, dir, "revert", "--no-edit", "HEAD")
+
+	initTestEnv(t, dir)
+	reportStale = false
+	reportOrphaned = false
+
+	var stdout bytes.Buffer
+	reportCmd.SetOut(&stdout)
+
+	reportCmd.RunE(reportCmd, nil)
+
+	if strings.Contains(stdout.String(), "STALE DOCS") {
+		t.Errorf("a reverted change should not make the doc stale, got:\n%s", stdout.String())
+	}
+}
+
 func TestAck_SuppressesStale(t *testing.T) {
 	dir := setupTestProject(t)
 
@@ -616,6 +649,43 @@ func Handler() {
 	}
 }
 
+func TestCheck_SectionTarget(t *testing.T) {
+	dir := setupTestProject(t)
+	doc := "# API\n\n## Auth\n\nTokens.\n\n## Limits\n\nTen a second.\n"
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(doc), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#auth\nfunc Login() {}\n"), 0644)
+	commitAll(t, dir, "Add auth")
+
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkJSON = false
+	checkNoBacklinks = true
+	checkFiles = nil
+	defer func() { checkNoBacklinks = false }()
+	run := func() (string, error) {
+		t.Helper()
+		var stdout bytes.Buffer
+		checkCmd.SetOut(&stdout)
+		err := checkCmd.RunE(checkCmd, nil)
+		return stdout.String(), err
+	}
+
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#auth\nfunc Login() { /* changed */ }\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(strings.Replace(doc, "Ten", "Twenty", 1)), 0644)
+	if out, err := run(); err != ErrDocsNeedUpdate || !strings.Contains(out, "docs/API.md#auth: needs update") {
+		t.Fatalf("editing another section should not count, err = %v, got:\n%s", err, out)
+	}
+
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(strings.Replace(doc, "Tokens.", "Bearer tokens.", 1)), 0644)
+	out, err := run()
+	if err != nil {
+		t.Fatalf("editing the linked section should pass, err = %v, got:\n%s", err, out)
+	}
+	if !strings.Contains(out, "docs/API.md#auth") || !strings.Contains(out, "Already updated") {
+		t.Errorf("expected docs/API.md#auth among the already-updated docs, got:\n%s", out)
+	}
+}
+
 func TestCheck_PrintsProvenanceAndBroadHint(t *testing.T) {
 	dir := setupTestProject(t)
 
@@ -661,6 +731,69 @@ func Extra() { /* changed */ }
 	}
 }
 
+func TestCheck_FrontMatterSources(t *testing.T) {
+	dir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(dir, "src", "billing"), 0755)
+	os.WriteFile(filepath.Join(dir, "docs", "BILLING.md"), []byte("---\ndocdiff: { sources: [\"src/billing/**\"] }\n---\n# Billing\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "billing", "tax.go"), []byte("package billing\n\nfunc Tax() {}\n"), 0644)
+	commitAll(t, dir, "Add billing")
+
+	os.WriteFile(filepath.Join(dir, "src", "billing", "tax.go"), []byte("package billing\n\nfunc Tax() int { return 1 }\n"), 0644)
+
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkJSON = false
+	checkFiles = nil
+
+	var stdout bytes.Buffer
+	checkCmd.SetOut(&stdout)
+	if err := checkCmd.RunE(checkCmd, nil); err != ErrDocsNeedUpdate {
+		t.Fatalf("check error = %v, want ErrDocsNeedUpdate", err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "docs/BILLING.md: needs update") {
+		t.Fatalf("a doc whose front matter glob matches a changed file needs update, got:\n%s", out)
+	}
+	if !strings.Contains(out, "via front matter glob src/billing/** (src/billing/tax.go)") {
+		t.Errorf("check should name the front matter glob as provenance, got:\n%s", out)
+	}
+}
+
+func TestCheck_MappingScopes(t *testing.T) {
+	dir := setupTestProject(t)
+	settings := "package main\n\nvar MobileSettings = map[string]int{\n\t\"a\": 1,\n}\n\nvar General = 1\n"
+	os.WriteFile(filepath.Join(dir, "docs", "MOBILE.md"), []byte("# Mobile\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "settings.go"), []byte(settings), 0644)
+	os.WriteFile(filepath.Join(dir, "docdiff.map.yaml"), []byte("links:\n  - files: src/settings.go\n    doc: docs/MOBILE.md\n    scopes: [MobileSettings]\n"), 0644)
+	commitAll(t, dir, "Map settings")
+
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkJSON = false
+	checkFiles = nil
+	run := func() (string, error) {
+		t.Helper()
+		var stdout bytes.Buffer
+		checkCmd.SetOut(&stdout)
+		err := checkCmd.RunE(checkCmd, nil)
+		return stdout.String(), err
+	}
+
+	os.WriteFile(filepath.Join(dir, "src", "settings.go"), []byte(strings.Replace(settings, "General = 1", "General = 2", 1)), 0644)
+	if out, err := run(); err != nil || strings.Contains(out, "docs/MOBILE.md") {
+		t.Fatalf("a change outside the mapped scope should not flag the doc, err = %v, got:\n%s", err, out)
+	}
+
+	os.WriteFile(filepath.Join(dir, "src", "settings.go"), []byte(strings.Replace(settings, `"a": 1`, `"a": 2`, 1)), 0644)
+	out, err := run()
+	if err != ErrDocsNeedUpdate {
+		t.Fatalf("check error = %v, want ErrDocsNeedUpdate, got:\n%s", err, out)
+	}
+	if !strings.Contains(out, "via mapping path src/settings.go #MobileSettings (src/settings.go:3-5)") {
+		t.Errorf("check should name the mapping rule and its region, got:\n%s", out)
+	}
+}
+
 func TestReport_NoBacklinksFlag(t *testing.T) {
 	dir := setupTestProject(t)
 	initTestEnv(t, dir)
@@ -723,3 +856,589 @@ func TestIsCI(t *testing.T) {
 		t.Error("isCI() should return true when GITHUB_ACTIONS=true")
 	}
 }
+
+// TestScopedCommittedStaleness covers report/explain/changes on a central file
+// with two scoped regions. Later commits and an uncommitted edit shift lines, so
+// owned regions only line up with older hunks if they are mapped back.
+func TestScopedCommittedStaleness(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "docs", "GENERAL.md"), []byte("# General\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "MOBILE.md"), []byte("# Mobile\n"), 0644)
+	central := func(lead string, mobile int) string {
+		return "package main\n" + lead +
+			"// @doc docs/GENERAL.md #general\n" +
+			"var General = 1\n" +
+			"// @doc docs/MOBILE.md #mobile\n" +
+			"var Mobile = " + strconv.Itoa(mobile) + "\n"
+	}
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("", 1)), 0644)
+	commitAll(t, dir, "Add central settings and docs")
+
+	// Commit 2 touches only the mobile region (line 5).
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("", 2)), 0644)
+	commitAll(t, dir, "Tweak mobile")
+	// Commit 3 inserts lines above both regions, touching neither.
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("\nimport \"fmt\"\n\n", 2)), 0644)
+	commitAll(t, dir, "Add import")
+	// Uncommitted: shift everything down again.
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n", 2)), 0644)
+
+	initTestEnv(t, dir)
+	reportStale = false
+	reportOrphaned = false
+	reportUndocumented = false
+	reportSARIF = false
+	reportCI = false
+	reportJSON = true
+	defer func() { reportJSON = false }()
+
+	var stdout bytes.Buffer
+	reportCmd.SetOut(&stdout)
+	if err := reportCmd.RunE(reportCmd, nil); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, `"Path": "docs/MOBILE.md"`) {
+		t.Errorf("MOBILE should be stale (its region changed), got:\n%s", out)
+	}
+	if strings.Contains(out, `"Path": "docs/GENERAL.md"`) {
+		t.Errorf("GENERAL should not be stale (only lines outside its region changed), got:\n%s", out)
+	}
+
+	var explainOut bytes.Buffer
+	explainCmd.SetOut(&explainOut)
+	if err := explainCmd.RunE(explainCmd, []string{"docs/GENERAL.md"}); err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	if !strings.Contains(explainOut.String(), "src/central.go (scoped #general)") {
+		t.Errorf("explain should show the scoped link, got:\n%s", explainOut.String())
+	}
+	if !strings.Contains(explainOut.String(), "Verdict: up to date") {
+		t.Errorf("explain should not call GENERAL stale, got:\n%s", explainOut.String())
+	}
+
+	changesCommits = true
+	changesSummary = false
+	changesAI = false
+	changesWorkTree = false
+	changesStaged = false
+	defer func() { changesCommits = false }()
+	for doc, want := range map[string]string{
+		"docs/MOBILE.md":  "Tweak mobile",
+		"docs/GENERAL.md": "No commits since last documentation update.",
+	} {
+		var changesOut bytes.Buffer
+		changesCmd.SetOut(&changesOut)
+		if err := changesCmd.RunE(changesCmd, []string{doc}); err != nil {
+			t.Fatalf("changes %s failed: %v", doc, err)
+		}
+		got := changesOut.String()
+		if !strings.Contains(got, want) || strings.Contains(got, "Add import") {
+			t.Errorf("changes --commits %s = %q, want %q and no unrelated commits", doc, got, want)
+		}
+	}
+}
+
+func TestDeclarationScopeOwnership(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "docs", "GENERAL.md"), []byte("# General\n"), 0644)
+	central := func(helper string) string {
+		return "package main\n\n" +
+			"// @doc docs/GENERAL.md #general\n" +
+			"func General() {\n" +
+			"\treturn\n" +
+			"}\n\n" +
+			"func helper() int { return " + helper + " }\n"
+	}
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("1")), 0644)
+	commitAll(t, dir, "Add central settings and docs")
+	// Only the unrelated helper below the annotated function changes.
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("2")), 0644)
+	commitAll(t, dir, "Tweak helper")
+
+	reportStale = false
+	reportOrphaned = false
+	reportUndocumented = false
+	reportSARIF = false
+	reportCI = false
+	reportJSON = true
+	defer func() { reportJSON = false }()
+
+	for _, tt := range []struct {
+		mode      string
+		wantStale bool
+	}{
+		{"next-annotation", true},
+		{"declaration", false},
+	} {
+		os.WriteFile(filepath.Join(dir, ".docdiff.yaml"), []byte("scope_ownership: "+tt.mode+"\n"), 0644)
+		initTestEnv(t, dir)
+
+		var stdout bytes.Buffer
+		reportCmd.SetOut(&stdout)
+		if err := reportCmd.RunE(reportCmd, nil); err != nil {
+			t.Fatalf("report failed: %v", err)
+		}
+		if got := strings.Contains(stdout.String(), `"Path": "docs/GENERAL.md"`); got != tt.wantStale {
+			t.Errorf("%s: GENERAL stale = %v, want %v; got:\n%s", tt.mode, got, tt.wantStale, stdout.String())
+		}
+	}
+}
+
+func TestSectionAnchorBaseline(t *testing.T) {
+	dir := setupTestProject(t)
+	api := func(auth, limits string) string {
+		return "# API\n\n## Authentication\n\n" + auth + "\n\n## Limits\n\n" + limits + "\n"
+	}
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(api("Use tokens.", "100 rps.")), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#authentication\nfunc Login() {}\n"), 0644)
+	commitAll(t, dir, "Add auth and API docs")
+
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#authentication\nfunc Login(token string) {}\n"), 0644)
+	commitAll(t, dir, "Require a token")
+	// A typo fix in another section must not count as reviewing Authentication.
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(api("Use tokens.", "100 req/s.")), 0644)
+	commitAll(t, dir, "Fix limits typo")
+
+	initTestEnv(t, dir)
+	reportStale = false
+	reportOrphaned = false
+	reportUndocumented = false
+	reportSARIF = false
+	reportCI = false
+	reportJSON = true
+	defer func() { reportJSON = false }()
+
+	runReport := func() string {
+		var stdout bytes.Buffer
+		reportCmd.SetOut(&stdout)
+		if err := reportCmd.RunE(reportCmd, nil); err != nil {
+			t.Fatalf("report failed: %v", err)
+		}
+		return stdout.String()
+	}
+	if out := runReport(); !strings.Contains(out, `"Path": "docs/API.md#authentication"`) {
+		t.Errorf("the Authentication section should be stale, got:\n%s", out)
+	}
+
+	var explainOut bytes.Buffer
+	explainCmd.SetOut(&explainOut)
+	if err := explainCmd.RunE(explainCmd, []string{"docs/API.md#authentication"}); err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	if !strings.Contains(explainOut.String(), "src/auth.go") || !strings.Contains(explainOut.String(), "Require a token") {
+		t.Errorf("explain should show the section's drift, got:\n%s", explainOut.String())
+	}
+
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte(api("Send a bearer token.", "100 req/s.")), 0644)
+	commitAll(t, dir, "Document token auth")
+	if out := runReport(); strings.Contains(out, `"Path": "docs/API.md#authentication"`) {
+		t.Errorf("editing the Authentication section should review it, got:\n%s", out)
+	}
+}
+
+func TestExplainSectionBreakdown(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte("# API\n\n## Authentication\n\nTokens.\n\n## Limits\n\n100 rps.\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#authentication\nfunc Login() {}\n"), 0644)
+	central := func(misc string) string {
+		return "package main\n\n// @doc docs/API.md #limits\nvar Limit = 100\n\n// @doc docs/API.md #misc\nvar Misc = " + misc + "\n"
+	}
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("1")), 0644)
+	commitAll(t, dir, "Add API docs")
+
+	os.WriteFile(filepath.Join(dir, "src", "auth.go"), []byte("package main\n\n// @doc docs/API.md#authentication\nfunc Login(token string) {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "central.go"), []byte(central("2")), 0644)
+	commitAll(t, dir, "Require a token")
+
+	initTestEnv(t, dir)
+	var stdout bytes.Buffer
+	explainCmd.SetOut(&stdout)
+	if err := explainCmd.RunE(explainCmd, []string{"docs/API.md"}); err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	out := stdout.String()
+
+	auth := out[strings.Index(out, "## Authentication"):strings.Index(out, "## Limits")]
+	for _, want := range []string{
+		"src/auth.go (anchor)",
+		"last change: ",
+		"Require a token",
+		"Verdict: STALE — run 'docdiff changes docs/API.md#authentication'",
+	} {
+		if !strings.Contains(auth, want) {
+			t.Errorf("Authentication section missing %q, got:\n%s", want, auth)
+		}
+	}
+	limits := out[strings.Index(out, "## Limits"):]
+	for _, want := range []string{
+		"src/central.go:3-5 (scoped #limits)",
+		"Add API docs",
+		"Verdict: up to date",
+	} {
+		if !strings.Contains(limits, want) {
+			t.Errorf("Limits section missing %q, got:\n%s", want, limits)
+		}
+	}
+	if !strings.Contains(out, "Sections (2 of 3 headings have linked code)") {
+		t.Errorf("explain should summarize linked headings, got:\n%s", out)
+	}
+	// The whole-doc verdict still counts the #misc region, which no heading names.
+	if !strings.Contains(out, "Verdict: STALE — linked code changed") {
+		t.Errorf("whole-doc verdict should be stale, got:\n%s", out)
+	}
+}
+
+func TestBrokenAnnotations(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "src", "typo.go"), []byte("package main\n\n// @doc docs/APi.md\nfunc Typo() {}\n"), 0644)
+
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkJSON = false
+	checkFiles = nil
+
+	var stdout bytes.Buffer
+	checkCmd.SetOut(&stdout)
+	if err := checkCmd.RunE(checkCmd, nil); err != nil {
+		t.Errorf("with fail_on_broken_annotations off by default, check error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "@doc docs/APi.md: doc missing") {
+		t.Errorf("check should list the broken annotation even when not failing, got:\n%s", stdout.String())
+	}
+
+	cfg.CI.FailOnBrokenAnnotations = true
+	stdout.Reset()
+	err := checkCmd.RunE(checkCmd, nil)
+	if err != ErrBrokenAnnotationsFound {
+		t.Errorf("check error = %v, want ErrBrokenAnnotationsFound", err)
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "src/typo.go:3 @doc docs/APi.md: doc missing — did you mean docs/API.md?") {
+		t.Errorf("check should list the broken annotation, got:\n%s", out)
+	}
+	if strings.Contains(out, "docs/APi.md: needs update") {
+		t.Errorf("a missing doc can't need an update, got:\n%s", out)
+	}
+
+	commitAll(t, dir, "Add typo")
+	initTestEnv(t, dir)
+	cfg.CI.FailOnBrokenAnnotations = true
+	reportStale, reportOrphaned, reportUndocumented = false, false, false
+	reportJSON, reportSARIF, reportCI = false, false, true
+	defer func() { reportCI = false }()
+	stdout.Reset()
+	reportCmd.SetOut(&stdout)
+	if err := reportCmd.RunE(reportCmd, nil); err != ErrBrokenAnnotationsFound {
+		t.Errorf("report --ci error = %v, want ErrBrokenAnnotationsFound", err)
+	}
+	for _, want := range []string{
+		"BROKEN ANNOTATIONS (doc path does not exist):",
+		"  x src/typo.go:3 @doc docs/APi.md (missing)",
+		"    Did you mean docs/API.md?",
+		"  Broken annotations: 1",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("report missing %q, got:\n%s", want, stdout.String())
+		}
+	}
+
+	reportJSON, reportCI = true, false
+	stdout.Reset()
+	if err := reportCmd.RunE(reportCmd, nil); err != nil {
+		t.Fatalf("report --json failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), `"suggestion": "docs/API.md"`) {
+		t.Errorf("JSON report should include the broken annotation, got:\n%s", stdout.String())
+	}
+	reportJSON = false
+}
+
+func TestRelativeAnnotationPaths(t *testing.T) {
+	dir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755)
+	nested := filepath.Join(dir, "src", "pkg", "nested.go")
+	os.WriteFile(nested, []byte("package pkg\n\n// @doc ../../docs/GUIDE.md\nfunc A() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "rooted.go"), []byte("package main\n\n// @doc /docs/GUIDE.md\nfunc B() {}\n"), 0644)
+	commitAll(t, dir, "Link the guide")
+	os.WriteFile(nested, []byte("package pkg\n\n// @doc ../../docs/GUIDE.md\nfunc A(n int) {}\n"), 0644)
+	commitAll(t, dir, "Change A")
+
+	initTestEnv(t, dir)
+	reportStale, reportOrphaned, reportUndocumented = false, false, false
+	reportSARIF, reportCI, reportJSON = false, false, true
+	defer func() { reportJSON = false }()
+	var stdout bytes.Buffer
+	reportCmd.SetOut(&stdout)
+	if err := reportCmd.RunE(reportCmd, nil); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+
+	var got struct {
+		StaleDocs         map[string]any      `json:"stale_docs"`
+		FilesByDoc        map[string][]string `json:"files_by_doc"`
+		BrokenAnnotations []any               `json:"broken_annotations"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if files := got.FilesByDoc["docs/GUIDE.md"]; len(files) != 2 {
+		t.Errorf("relative and root-anchored paths should both key docs/GUIDE.md, got %v", got.FilesByDoc)
+	}
+	if got.StaleDocs["docs/GUIDE.md"] == nil {
+		t.Errorf("docs/GUIDE.md should be stale through the relative annotation, got %v", got.StaleDocs)
+	}
+	if len(got.BrokenAnnotations) != 0 {
+		t.Errorf("resolved paths aren't broken, got %v", got.BrokenAnnotations)
+	}
+}
+
+func TestMv(t *testing.T) {
+	dir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "pkg", "rel.go"), []byte("package pkg\n\n// @doc ../../docs/API.md#api-docs\nfunc A() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "rooted.go"), []byte("package main\n\n// @doc /docs/API.md\n// @doc docs/GUIDE.md\nfunc B() {}\n"), 0644)
+	commitAll(t, dir, "Link the API doc")
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte("# API Docs\n\nReviewed.\n"), 0644)
+	commitAll(t, dir, "Review API doc")
+	reviewed := runGit(t, dir, "rev-parse", "--short", "HEAD")
+	os.WriteFile(filepath.Join(dir, acksFile), []byte(`{"docs/API.md#api-docs": "`+reviewed+`"}`), 0644)
+	commitAll(t, dir, "Ack")
+	initTestEnv(t, dir)
+
+	read := func(rel string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		return string(data)
+	}
+
+	mvDryRun = true
+	var stdout bytes.Buffer
+	mvCmd.SetOut(&stdout)
+	if err := mvCmd.RunE(mvCmd, []string{"docs/API.md", "docs/api/http.md"}); err != nil {
+		t.Fatalf("mv --dry-run failed: %v", err)
+	}
+	mvDryRun = false
+	if !strings.Contains(stdout.String(), "Would rewrite 3 annotation(s) in 3 file(s)") {
+		t.Errorf("dry run should list the rewrites, got:\n%s", stdout.String())
+	}
+	if strings.Contains(read("src/handler.go"), "api/http.md") || read("docs/API.md") == "" {
+		t.Fatal("dry run must not touch any file")
+	}
+
+	stdout.Reset()
+	if err := mvCmd.RunE(mvCmd, []string{"docs/API.md", "docs/api/http.md"}); err != nil {
+		t.Fatalf("mv failed: %v", err)
+	}
+	for file, want := range map[string]string{
+		"src/handler.go": "// @doc docs/api/http.md\n",
+		"src/pkg/rel.go": "// @doc ../../docs/api/http.md#api-docs\n",
+		"src/rooted.go":  "// @doc /docs/api/http.md\n// @doc docs/GUIDE.md\n",
+	} {
+		if !strings.Contains(read(file), want) {
+			t.Errorf("%s should contain %q, got:\n%s", file, want, read(file))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docs", "API.md")); !os.IsNotExist(err) {
+		t.Error("docs/API.md should have moved")
+	}
+	if !strings.Contains(read(acksFile), `"docs/api/http.md#api-docs"`) {
+		t.Errorf("ack should follow the doc, got %s", read(acksFile))
+	}
+	stdout.Reset()
+	if err := mvCmd.RunE(mvCmd, []string{"docs/API.md", "docs/api/http.md"}); err != nil || !strings.Contains(stdout.String(), "No annotations link to docs/API.md") {
+		t.Errorf("a second run should find nothing left to rewrite, got %v:\n%s", err, stdout.String())
+	}
+	if err := mvCmd.RunE(mvCmd, []string{"docs/GUIDE.md", "docs/api/http.md"}); err == nil {
+		t.Error("moving a doc onto one that exists should fail")
+	}
+
+	commitAll(t, dir, "Move API doc")
+	if got, _ := lastReviewCommit(openRepo(), "docs/api/http.md"); got != reviewed {
+		t.Errorf("baseline after the move = %s, want the review commit %s", got, reviewed)
+	}
+	scanResult, _ := scanner.New(cfg, registry).Scan(dir)
+	stale := computeStaleDocs(openRepo(), scanResult, io.Discard)
+	for _, doc := range []string{"docs/api/http.md", "docs/api/http.md#api-docs"} {
+		if stale[doc] != nil {
+			t.Errorf("repointing annotations shouldn't make %s stale, got %+v", doc, stale[doc])
+		}
+	}
+
+	t.Run("aliases", func(t *testing.T) {
+		os.WriteFile(filepath.Join(dir, ".docdiff.yaml"), []byte("aliases:\n  docs/API.md: docs/api/http.md\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "src", "legacy.go"), []byte("package main\n\n// @doc docs/API.md\nfunc L() {}\n"), 0644)
+		initTestEnv(t, dir)
+		scanResult, err := scanner.New(cfg, registry).Scan(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files := scanResult.FilesByDoc["docs/api/http.md"]; len(files) != 3 {
+			t.Errorf("the old path should resolve through the alias, got %v", scanResult.FilesByDoc)
+		}
+		if len(scanResult.BrokenAnnotations) != 0 {
+			t.Errorf("an aliased path isn't broken, got %v", scanResult.BrokenAnnotations)
+		}
+	})
+}
+
+func TestMv_MappingRules(t *testing.T) {
+	dir := setupTestProject(t)
+	os.MkdirAll(filepath.Join(dir, "schemas"), 0755)
+	os.WriteFile(filepath.Join(dir, "schemas", "user.json"), []byte("{}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".docdiff.yaml"), []byte("links:\n  # the schema reference\n  - files: \"schemas/*.json\"\n    doc: \"docs/GUIDE.md#schemas\"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docdiff.map.yaml"), []byte("links:\n  - {files: src/util.go, doc: ./docs/GUIDE.md}\n  - files: src/handler.go\n    doc: docs/API.md\n"), 0644)
+	commitAll(t, dir, "Map files")
+	initTestEnv(t, dir)
+	read := func(rel string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		return string(data)
+	}
+
+	var stdout bytes.Buffer
+	mvCmd.SetOut(&stdout)
+	if err := mvCmd.RunE(mvCmd, []string{"docs/GUIDE.md", "docs/guides/intro.md"}); err != nil {
+		t.Fatalf("mv failed: %v", err)
+	}
+	for _, want := range []string{
+		"Rewrote 2 doc path(s) in config:",
+		".docdiff.yaml:4  doc: docs/GUIDE.md#schemas -> docs/guides/intro.md#schemas",
+		"docdiff.map.yaml:2  doc: ./docs/GUIDE.md -> ./docs/guides/intro.md",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("mv output missing %q, got:\n%s", want, stdout.String())
+		}
+	}
+	if got, want := read(".docdiff.yaml"), "links:\n  # the schema reference\n  - files: \"schemas/*.json\"\n    doc: \"docs/guides/intro.md#schemas\"\n"; got != want {
+		t.Errorf(".docdiff.yaml =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := read("docdiff.map.yaml"), "links:\n  - {files: src/util.go, doc: ./docs/guides/intro.md}\n  - files: src/handler.go\n    doc: docs/API.md\n"; got != want {
+		t.Errorf("docdiff.map.yaml =\n%s\nwant\n%s", got, want)
+	}
+
+	initTestEnv(t, dir)
+	scanResult, err := scanner.New(cfg, registry).Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := scanResult.FilesByDoc["docs/guides/intro.md"]; strings.Join(files, ",") != "src/util.go" {
+		t.Errorf("mapped files should follow the doc, got %v", scanResult.FilesByDoc)
+	}
+	if files := scanResult.FilesByDoc["docs/guides/intro.md#schemas"]; strings.Join(files, ",") != "schemas/user.json" {
+		t.Errorf("mapped section should follow the doc, got %v", scanResult.FilesByDoc)
+	}
+}
+
+func TestMv_Aliases(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, ".docdiff.yaml"), []byte("aliases:\n  docs/OLD.md: docs/API.md # renamed last year\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "legacy.go"), []byte("package main\n\n// @doc docs/OLD.md#auth\nfunc L() {}\n"), 0644)
+	commitAll(t, dir, "Alias the old path")
+	initTestEnv(t, dir)
+	read := func(rel string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		return string(data)
+	}
+
+	var stdout bytes.Buffer
+	mvCmd.SetOut(&stdout)
+	if err := mvCmd.RunE(mvCmd, []string{"docs/API.md", "docs/api/http.md"}); err != nil {
+		t.Fatalf("mv failed: %v", err)
+	}
+	for _, want := range []string{
+		"Rewrote 2 annotation(s) in 2 file(s):",
+		"src/legacy.go:3  docs/OLD.md#auth -> docs/api/http.md#auth",
+		".docdiff.yaml:2  aliases[docs/OLD.md]: docs/API.md -> docs/api/http.md",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("mv output missing %q, got:\n%s", want, stdout.String())
+		}
+	}
+	if got, want := read(".docdiff.yaml"), "aliases:\n  docs/OLD.md: docs/api/http.md # renamed last year\n"; got != want {
+		t.Errorf(".docdiff.yaml =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(read("src/legacy.go"), "// @doc docs/api/http.md#auth\n") {
+		t.Errorf("an annotation using the alias should follow the doc, got:\n%s", read("src/legacy.go"))
+	}
+
+	initTestEnv(t, dir)
+	scanResult, err := scanner.New(cfg, registry).Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scanResult.BrokenAnnotations) != 0 {
+		t.Errorf("nothing should be broken after the move, got %+v", scanResult.BrokenAnnotations)
+	}
+}
+
+func TestDeadRefs(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "src", "billing.go"), []byte("package main\n\n// @doc docs/API.md\nfunc InvoiceTotal() int { return 0 }\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "old.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte("# API Docs\n\n`InvoiceTotal` sums lines, `Handler` serves.\n\nSee src/old.go and src/util.go here.\n"), 0644)
+	commitAll(t, dir, "Document billing")
+
+	os.WriteFile(filepath.Join(dir, "src", "billing.go"), []byte("package main\n\n// @doc docs/API.md\nfunc Total() int { return 0 }\n"), 0644)
+	os.Remove(filepath.Join(dir, "src", "old.go"))
+	commitAll(t, dir, "Rename and drop")
+	baseline := runGit(t, dir, "rev-parse", "--short", "HEAD~1")
+	initTestEnv(t, dir)
+
+	reportJSON = true
+	defer func() { reportJSON = false }()
+	var stdout bytes.Buffer
+	reportCmd.SetOut(&stdout)
+	reportCmd.SetErr(io.Discard)
+	if err := reportCmd.RunE(reportCmd, nil); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	var got struct {
+		DeadRefs []struct {
+			DocPath, Kind, Ref, Baseline string
+			Line                         int
+			DeclaredIn                   string `json:"declared_in"`
+		} `json:"dead_refs"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("bad JSON: %v\n%s", err, stdout.String())
+	}
+	if len(got.DeadRefs) != 2 {
+		t.Fatalf("dead_refs = %+v, want the removed symbol and file", got.DeadRefs)
+	}
+	sym, file := got.DeadRefs[0], got.DeadRefs[1]
+	if sym.Kind != "symbol" || sym.Ref != "InvoiceTotal" || sym.Line != 3 || sym.DeclaredIn != "src/billing.go" || sym.Baseline != baseline {
+		t.Errorf("symbol ref = %+v", sym)
+	}
+	if file.Kind != "file" || file.Ref != "src/old.go" || file.Line != 5 {
+		t.Errorf("file ref = %+v", file)
+	}
+
+	reportNoDeadRefs = true
+	stdout.Reset()
+	err := reportCmd.RunE(reportCmd, nil)
+	reportNoDeadRefs = false
+	if err != nil {
+		t.Fatalf("report --no-dead-refs failed: %v", err)
+	}
+	got.DeadRefs = nil
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("bad JSON: %v\n%s", err, stdout.String())
+	}
+	if len(got.DeadRefs) != 0 {
+		t.Errorf("with --no-dead-refs, dead_refs = %+v, want none", got.DeadRefs)
+	}
+
+	stdout.Reset()
+	explainCmd.SetOut(&stdout)
+	if err := explainCmd.RunE(explainCmd, []string{"docs/API.md"}); err != nil {
+		t.Fatalf("explain failed: %v", err)
+	}
+	for _, want := range []string{
+		"Dead references (2) — mentioned here, removed since " + baseline,
+		"line 3: `InvoiceTotal` is no longer declared (was in src/billing.go at " + baseline + ")",
+		"line 5: src/old.go no longer exists (present at " + baseline + ")",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("explain output missing %q, got:\n%s", want, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package commands
+
+// @doc README.md
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/language"
+	"github.com/StevenBock/docdiff/internal/report"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+// deadRefFinder finds the file paths and backticked symbols a doc mentions
+// that existed at the doc's baseline but are gone now. The scan must have
+// indexed symbols (Scanner.IndexSymbols), so it can tell which names are still
+// declared somewhere.
+type deadRefFinder struct {
+	g       git.Repo
+	scanner *scanner.Scanner
+	scan    *scanner.Result
+	parser  *docparse.Parser
+	removed map[string]map[string]string // baseline -> symbol -> file declaring it there
+}
+
+func newDeadRefFinder(g git.Repo, s *scanner.Scanner, scanResult *scanner.Result) *deadRefFinder {
+	return &deadRefFinder{
+		g:       g,
+		scanner: s,
+		scan:    scanResult,
+		parser:  docparse.New(nil, registry.AllExtensions()),
+		removed: make(map[string]map[string]string),
+	}
+}
+
+var symbolName = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+// find returns doc's dead references against baseline, in line order.
+func (f *deadRefFinder) find(doc, baseline string) []report.DeadRef {
+	content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(doc)))
+	if err != nil || baseline == "" {
+		return nil
+	}
+	content = formats.Prose(doc, content)
+
+	var refs []report.DeadRef
+	mentions := f.parser.Mentions(content)
+	var missing []string
+	checked := make(map[string]bool)
+	for _, m := range mentions {
+		if checked[m.Path] {
+			continue
+		}
+		checked[m.Path] = true
+		if _, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(m.Path))); err != nil {
+			missing = append(missing, m.Path)
+		}
+	}
+	existed := f.existedAt(baseline, missing)
+	for _, m := range mentions {
+		if existed[m.Path] {
+			refs = append(refs, report.DeadRef{DocPath: doc, Line: m.Line, Kind: report.DeadRefFile, Ref: m.Path, Baseline: baseline})
+		}
+	}
+
+	// Only a name nothing declares now can be a removed symbol, so baseline
+	// files are read only for docs that mention one.
+	var undeclared []docparse.CodeSpan
+	seen := make(map[string]bool)
+	for _, span := range docparse.CodeSpans(content) {
+		name := docparse.SpanIdentifier(span.Text)
+		if seen[name] || !symbolName.MatchString(name) || len(f.scan.Symbols.Files(name)) > 0 {
+			continue
+		}
+		seen[name] = true
+		undeclared = append(undeclared, docparse.CodeSpan{Text: name, Line: span.Line})
+	}
+	if len(undeclared) > 0 {
+		removed := f.removedSymbols(baseline)
+		for _, span := range undeclared {
+			if file, gone := removed[span.Text]; gone {
+				refs = append(refs, report.DeadRef{DocPath: doc, Line: span.Line, Kind: report.DeadRefSymbol, Ref: span.Text, DeclaredIn: file, Baseline: baseline})
+			}
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Line < refs[j].Line })
+	return refs
+}
+
+// existedAt returns which of paths, all missing from the tree now, existed at
+// baseline. Only a path that differs between baseline and HEAD can have been
+// removed since, and with History loaded that question costs no git process
+// per path; just the paths it leaves are looked up at baseline.
+func (f *deadRefFinder) existedAt(baseline string, paths []string) map[string]bool {
+	existed := make(map[string]bool)
+	if len(paths) == 0 {
+		return existed
+	}
+	changed, err := f.g.ChangedFilesBetween(baseline, "HEAD", paths)
+	if err != nil {
+		return existed
+	}
+	for _, path := range changed {
+		if _, ok, _ := f.g.FileAt(baseline, path); ok {
+			existed[path] = true
+		}
+	}
+	return existed
+}
+
+// removedSymbols lists the names files changed since baseline declared there
+// that nothing in the tree declares now. Only files changed since baseline
+// can have lost a declaration, so nothing else is read.
+func (f *deadRefFinder) removedSymbols(baseline string) map[string]string {
+	if removed, ok := f.removed[baseline]; ok {
+		return removed
+	}
+	removed := make(map[string]string)
+	f.removed[baseline] = removed
+
+	changed, err := f.g.ChangedFilesBetween(baseline, "HEAD", nil)
+	if err != nil {
+		return removed
+	}
+	sort.Strings(changed)
+	for _, path := range changed {
+		content, existed, err := f.g.FileAt(baseline, path)
+		if err != nil || !existed {
+			continue
+		}
+		strategy, ok := f.scanner.Strategy(path, content)
+		if !ok {
+			continue
+		}
+		declarer, ok := strategy.(language.SymbolDeclarer)
+		if !ok {
+			continue
+		}
+		for _, name := range declarer.DeclaredSymbols(content) {
+			if _, dup := removed[name]; !dup && len(f.scan.Symbols.Files(name)) == 0 {
+				removed[name] = path
+			}
+		}
+	}
+	return removed
+}
+
+// computeDeadRefs checks every doc the scan read for references. Warnings go
+// to errOut.
+func computeDeadRefs(g git.Repo, s *scanner.Scanner, scanResult *scanner.Result, errOut io.Writer) []report.DeadRef {
+	acks, err := loadAcks(rootDir)
+	if err != nil {
+		acks = map[string]string{} // computeStaleDocs already warned
+	}
+	finder := newDeadRefFinder(g, s, scanResult)
+	refs := make([]report.DeadRef, 0)
+	for _, doc := range scanResult.Docs {
+		baseline, err := baselineForDoc(g, doc, acks)
+		if err != nil {
+			fmt.Fprintf(errOut, "Warning: failed to find last commit for %s: %v\n", doc, err)
+			continue
+		}
+		refs = append(refs, finder.find(doc, baseline.Effective)...)
+	}
+	return refs
+}
@@ -1,6 +1,12 @@
 package commands
 
-import "strings"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/git"
+)
 
 // maybeHideAnnotations strips annotation-only hunks from a unified diff when the
 // --hide-annotations flag is set, so behaviorally relevant changes aren't buried
@@ -20,7 +26,52 @@ func maybeHideAnnotations(diff string) string {
 // annotation counts as a real change and is kept. Good enough; upgrade to a
 // token-level diff only if mixed-line noise actually shows up.
 func filterAnnotationDiff(diff, tag string) string {
-	if tag == "" || !strings.Contains(diff, "diff --git ") {
+	if tag == "" {
+		return diff
+	}
+	return filterDiff(diff, func(_ string, hunk []string) bool {
+		return !annotationOnlyHunk(hunk, tag)
+	})
+}
+
+// filterDiffHunks drops hunks whose new-side span fails keep, e.g. hunks
+// outside a scoped annotation's owned region. The span includes the diff's
+// context lines, so a hunk just beside a region may be kept; it is a display
+// filter, not the staleness decision.
+func filterDiffHunks(diff string, keep func(file string, r git.LineRange) bool) string {
+	if keep == nil {
+		return diff
+	}
+	return filterDiff(diff, func(file string, hunk []string) bool {
+		r, ok := hunkNewRange(hunk[0])
+		return !ok || keep(file, r)
+	})
+}
+
+// hunkNewRange parses the new-side span of a "@@ -a,b +c,d @@" header.
+func hunkNewRange(header string) (git.LineRange, bool) {
+	m := diffHunkHeader.FindStringSubmatch(header)
+	if m == nil {
+		return git.LineRange{}, false
+	}
+	start, _ := strconv.Atoi(m[1])
+	count := 1
+	if m[2] != "" {
+		count, _ = strconv.Atoi(m[2])
+	}
+	if count == 0 {
+		return git.LineRange{Start: start, End: start}, true
+	}
+	return git.LineRange{Start: start, End: start + count - 1}, true
+}
+
+var diffHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// filterDiff splits a `git diff`-style unified diff into per-file blocks and
+// hunks, keeping hunks for which keep returns true. A block left with no hunks
+// is dropped; blocks without hunks (renames, mode changes) are kept.
+func filterDiff(diff string, keep func(file string, hunk []string) bool) string {
+	if !strings.Contains(diff, "diff --git ") {
 		return diff
 	}
 
@@ -53,16 +104,16 @@ func filterAnnotationDiff(diff, tag string) string {
 		out = append(out, p...)
 	}
 	for _, block := range blocks {
-		if kept := filterBlock(block, tag); kept != nil {
+		if kept := filterBlock(block, keep); kept != nil {
 			out = append(out, kept...)
 		}
 	}
 	return strings.Join(out, "\n")
 }
 
-// filterBlock returns the block with annotation-only hunks removed, or nil if
-// every hunk was annotation-only (drop the whole file block).
-func filterBlock(block []string, tag string) []string {
+// filterBlock returns the block with rejected hunks removed, or nil if every
+// hunk was rejected (drop the whole file block).
+func filterBlock(block []string, keep func(file string, hunk []string) bool) []string {
 	firstHunk := -1
 	for i, ln := range block {
 		if strings.HasPrefix(ln, "@@") {
@@ -75,6 +126,12 @@ func filterBlock(block []string, tag string) []string {
 	}
 
 	header := block[:firstHunk]
+	file := ""
+	for _, ln := range header {
+		if strings.HasPrefix(ln, "+++ ") {
+			file = strings.TrimPrefix(strings.TrimPrefix(ln, "+++ "), "b/")
+		}
+	}
 	var hunks [][]string
 	var cur []string
 	for _, ln := range block[firstHunk:] {
@@ -94,7 +151,7 @@ func filterBlock(block []string, tag string) []string {
 	out := append([]string{}, header...)
 	keptAny := false
 	for _, h := range hunks {
-		if !annotationOnlyHunk(h, tag) {
+		if keep(file, h) {
 			out = append(out, h...)
 			keptAny = true
 		}
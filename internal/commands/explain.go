@@ -4,11 +4,15 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/StevenBock/docdiff/internal/docparse"
 	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/report"
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
@@ -18,7 +22,15 @@ var explainCmd = &cobra.Command{
 	Long: `Show the full staleness reasoning for a single doc in one place: its
 linked files, its review anchor (last commit), any ack floor, the effective
 baseline, the newest linked commit, and whether uncommitted working-tree
-changes contribute — so you don't have to run several 'changes' commands.`,
+changes contribute — so you don't have to run several 'changes' commands.
+
+Files and backticked symbols the doc mentions that existed at its baseline
+but are gone now are listed as dead references, with their line numbers.
+
+When code links to individual headings — via section anchors
+(@doc docs/API.md#auth) or scopes named after a heading (@doc docs/API.md #auth)
+— a per-section breakdown follows: each heading, the code regions feeding it,
+their last change, and a per-section verdict.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runExplain,
 }
@@ -32,32 +44,74 @@ func runExplain(cmd *cobra.Command, args []string) error {
 	out := cmd.OutOrStdout()
 
 	s := scanner.New(cfg, registry)
+	s.IndexSymbols() // for dead references
 	scanResult, err := s.Scan(rootDir)
 	if err != nil {
 		return fmt.Errorf("scan failed: %w", err)
 	}
 
+	g := openRepo()
+	acks, err := loadAcks(rootDir)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load %s: %v\n", acksFile, err)
+		acks = map[string]string{}
+	}
+
+	feeds, unlinked := sectionFeeds(g, scanResult, doc, acks)
+	if err := explainDoc(out, g, scanResult, doc, acks, len(feeds) > 0); err != nil {
+		return err
+	}
+	if len(feeds) > 0 {
+		writeSectionBreakdown(out, g, scanResult, feeds, unlinked)
+	}
+	writeExplainDeadRefs(out, g, s, scanResult, doc, acks)
+	return nil
+}
+
+// writeExplainDeadRefs lists the files and symbols the doc mentions that were
+// removed since its baseline. Nothing is printed when there are none.
+func writeExplainDeadRefs(out io.Writer, g git.Repo, s *scanner.Scanner, scanResult *scanner.Result, doc string, acks map[string]string) {
+	path, _ := docparse.SplitAnchor(doc)
+	baseline, err := baselineForDoc(g, path, acks)
+	if err != nil {
+		return
+	}
+	refs := newDeadRefFinder(g, s, scanResult).find(path, baseline.Effective)
+	if len(refs) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nDead references (%d) — mentioned here, removed since %s:\n", len(refs), baseline.Effective)
+	for _, r := range refs {
+		fmt.Fprintf(out, "  line %d: %s\n", r.Line, report.DeadRefText(r))
+	}
+}
+
+// explainDoc prints the whole-doc reasoning and verdict.
+func explainDoc(out io.Writer, g git.Repo, scanResult *scanner.Result, doc string, acks map[string]string, hasSections bool) error {
 	files := scanResult.FilesByDoc[doc]
 	sort.Strings(files)
 
 	fmt.Fprintf(out, "Doc: %s\n", doc)
 	if len(files) == 0 {
-		fmt.Fprintf(out, "\nNo source files link to this doc with %s annotations — nothing to be stale against.\n", cfg.AnnotationTag)
+		if hasSections {
+			fmt.Fprintf(out, "\nNo source files link to this doc as a whole; its sections are linked below.\n")
+		} else {
+			fmt.Fprintf(out, "\nNo source files link to this doc with %s annotations — nothing to be stale against.\n", cfg.AnnotationTag)
+		}
 		return nil
 	}
 
 	fmt.Fprintf(out, "\nLinked files (%d):\n", len(files))
 	for _, f := range files {
-		fmt.Fprintf(out, "  %s\n", f)
+		if links := scanResult.LinksFor(doc, f); len(links) > 0 {
+			for _, l := range links {
+				fmt.Fprintf(out, "  %s (%s)\n", linkRegion(f, l.Start, l.End), linkOrigin(l.Kind, l.Pattern, l.Scope))
+			}
+			continue
+		}
+		fmt.Fprintf(out, "  %s%s\n", f, scopeSuffix(scanResult.Annotations[f], doc))
 	}
 
-	g := git.New(rootDir)
-
-	acks, err := loadAcks(rootDir)
-	if err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to load %s: %v\n", acksFile, err)
-		acks = map[string]string{}
-	}
 	baselineInfo, err := baselineForDoc(g, doc, acks)
 	if err != nil {
 		return fmt.Errorf("failed to find last commit for %s: %w", doc, err)
@@ -93,9 +147,12 @@ func runExplain(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(out, "  effective:       %s\n", baseline)
 	}
 
-	// Committed drift: linked files with commits after the baseline.
+	// Committed drift: linked files with commits after the baseline. Files
+	// linked only by scoped annotations count when their owned region changed.
+	scope := newDocScope(g, doc, scanResult.Ownership())
 	committed, _ := g.ChangedFilesBetween(baseline, "HEAD", files)
-	commits, _ := g.CommitsBetween(baseline, "HEAD", files)
+	committed = scope.committedFiles(baseline, committed)
+	commits := scope.commits(baseline, files)
 
 	fmt.Fprintln(out, "\nSince baseline:")
 	if len(committed) == 0 {
@@ -103,7 +160,7 @@ func runExplain(cmd *cobra.Command, args []string) error {
 	} else {
 		newest := ""
 		if len(commits) > 0 {
-			newest = commits[0] // git log is newest-first
+			newest = commits[0].Short + " " + commits[0].Subject // git log is newest-first
 		}
 		fmt.Fprintf(out, "  committed changes: %d file(s) over %d commit(s)\n", len(committed), len(commits))
 		if newest != "" {
@@ -117,6 +174,7 @@ func runExplain(cmd *cobra.Command, args []string) error {
 	// Working-tree contribution: uncommitted changes to linked files on top of
 	// what's already committed since the baseline.
 	sinceWorkTree, _ := g.ChangedFilesSince(baseline, false, files)
+	sinceWorkTree = scope.worktreeFiles(baseline, false, sinceWorkTree)
 	committedSet := map[string]bool{}
 	for _, f := range committed {
 		committedSet[f] = true
@@ -148,3 +206,18 @@ func runExplain(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// scopeSuffix lists the scopes through which a scoped-only file links to doc,
+// e.g. " (scoped #auth, #session)". Whole-file links get no suffix.
+func scopeSuffix(ann *scanner.Annotation, doc string) string {
+	if !scopedFor(ann, doc) {
+		return ""
+	}
+	var scopes []string
+	for _, d := range ann.Details {
+		if d.Path == doc {
+			scopes = append(scopes, "#"+d.Scope)
+		}
+	}
+	return " (scoped " + strings.Join(scopes, ", ") + ")"
+}
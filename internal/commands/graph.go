@@ -7,7 +7,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/graph"
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
@@ -38,9 +37,9 @@ func runGraph(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("scan failed: %w", err)
 	}
 
-	g := git.New(rootDir)
+	g := openRepo()
 	staleDocs := make(map[string]bool)
-	for doc := range computeStaleDocs(g, scanResult.FilesByDoc, cmd.ErrOrStderr()) {
+	for doc := range computeStaleDocs(g, scanResult, cmd.ErrOrStderr()) {
 		staleDocs[doc] = true
 	}
 
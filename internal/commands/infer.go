@@ -0,0 +1,267 @@
+package commands
+
+// @doc README.md
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+var (
+	inferJSON       bool
+	inferMinCommits int
+	inferMinRatio   float64
+	inferDeadAfter  int
+	inferMaxFiles   int
+)
+
+var inferLinksCmd = &cobra.Command{
+	Use:   "infer-links",
+	Short: "Mine git history for doc links that are missing or dead",
+	Long: `Mine git history for docs and source files that change together.
+
+A source file with no annotation for a doc it is committed together with often
+(at least --min-commits shared commits, making up at least --min-ratio of the
+file's commits) is reported as a likely missing link. An annotated file that
+has changed --dead-after times since it last changed together with its doc is
+reported as a likely dead link.
+
+Commits touching more than --max-files paths are ignored. Thresholds default to
+the infer_links section of the config.`,
+	RunE: runInferLinks,
+}
+
+func init() {
+	inferLinksCmd.Flags().BoolVar(&inferJSON, "json", false, "output as JSON")
+	inferLinksCmd.Flags().IntVar(&inferMinCommits, "min-commits", 0, "shared commits before a missing link is reported (default: infer_links.min_commits, else 3)")
+	inferLinksCmd.Flags().Float64Var(&inferMinRatio, "min-ratio", 0, "share of the source's commits that must touch the doc (default: infer_links.min_ratio, else 0.5)")
+	inferLinksCmd.Flags().IntVar(&inferDeadAfter, "dead-after", 0, "source commits without the doc before a link is reported dead (default: infer_links.dead_after, else 20)")
+	inferLinksCmd.Flags().IntVar(&inferMaxFiles, "max-files", 0, "ignore commits touching more paths than this, 0 for none (default: infer_links.max_files, else 30)")
+	rootCmd.AddCommand(inferLinksCmd)
+}
+
+// missingLink is an unannotated source file that keeps changing with a doc.
+type missingLink struct {
+	Doc           string  `json:"doc"`
+	File          string  `json:"file"`
+	Commits       int     `json:"commits"`        // commits touching both
+	SourceCommits int     `json:"source_commits"` // commits touching the file
+	Ratio         float64 `json:"ratio"`
+}
+
+// deadLink is an annotation whose file keeps changing without its doc.
+type deadLink struct {
+	Doc          string `json:"doc"`
+	File         string `json:"file"`
+	CommitsSince int    `json:"commits_since"`  // file commits since they last changed together
+	LastCoChange string `json:"last_co_change"` // short hash, "" if they never did
+}
+
+type inferredLinks struct {
+	Thresholds config.InferLinksConfig `json:"thresholds"`
+	Missing    []missingLink           `json:"missing"`
+	Dead       []deadLink              `json:"dead"`
+}
+
+func runInferLinks(cmd *cobra.Command, args []string) error {
+	t := inferThresholds(cmd)
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	s := scanner.New(cfg, registry)
+	scanResult, err := s.Scan(rootDir)
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	history, err := openRepo().History()
+	if err != nil {
+		return fmt.Errorf("infer-links needs git history: %w", err)
+	}
+
+	links := inferLinks(scanResult, history, t)
+	out := cmd.OutOrStdout()
+	if inferJSON {
+		data, err := json.MarshalIndent(links, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+	writeInferHuman(out, links)
+	return nil
+}
+
+// inferThresholds is the config's infer_links section with any flags given on
+// the command line applied over it.
+func inferThresholds(cmd *cobra.Command) config.InferLinksConfig {
+	t := cfg.InferLinks
+	flags := cmd.Flags()
+	if flags.Changed("min-commits") {
+		t.MinCommits = inferMinCommits
+	}
+	if flags.Changed("min-ratio") {
+		t.MinRatio = inferMinRatio
+	}
+	if flags.Changed("dead-after") {
+		t.DeadAfter = inferDeadAfter
+	}
+	if flags.Changed("max-files") {
+		t.MaxFiles = inferMaxFiles
+	}
+	return t
+}
+
+// inferLinks walks history once, newest commit first. Docs are the tree's
+// Markdown files plus every annotated doc; sources are the scanned files.
+// Section links count for their whole doc.
+func inferLinks(scanResult *scanner.Result, history *git.History, t config.InferLinksConfig) inferredLinks {
+	docs := make(map[string]bool)
+	for _, doc := range scanResult.DocFiles {
+		docs[doc] = true
+	}
+	linked := make(map[string]map[string]bool) // file -> docs it annotates
+	for target, files := range scanResult.FilesByDoc {
+		doc, _ := docparse.SplitAnchor(target)
+		docs[doc] = true
+		for _, f := range files {
+			if linked[f] == nil {
+				linked[f] = make(map[string]bool)
+			}
+			linked[f][doc] = true
+		}
+	}
+	sources := make(map[string]bool)
+	for _, f := range scanResult.AllFiles {
+		if !docs[f] {
+			sources[f] = true
+		}
+	}
+
+	type pair struct{ doc, file string }
+	fileCommits := make(map[string]int)
+	shared := make(map[pair]int)
+	since := make(map[pair]int)         // linked pairs: file commits newer than the last shared one
+	lastShared := make(map[pair]string) // linked pairs: that shared commit
+	for _, c := range history.Commits() {
+		if len(c.Files) == 0 || (t.MaxFiles > 0 && len(c.Files) > t.MaxFiles) {
+			continue
+		}
+		var touchedDocs, touchedFiles []string
+		inCommit := make(map[string]bool, len(c.Files))
+		for _, f := range c.Files {
+			inCommit[f] = true
+			switch {
+			case docs[f]:
+				touchedDocs = append(touchedDocs, f)
+			case sources[f]:
+				touchedFiles = append(touchedFiles, f)
+			}
+		}
+		for _, f := range touchedFiles {
+			fileCommits[f]++
+			for _, doc := range touchedDocs {
+				shared[pair{doc, f}]++
+			}
+			for doc := range linked[f] {
+				p := pair{doc, f}
+				if _, found := lastShared[p]; found {
+					continue
+				}
+				if inCommit[doc] {
+					lastShared[p] = c.Short
+				} else {
+					since[p]++
+				}
+			}
+		}
+	}
+
+	links := inferredLinks{Thresholds: t, Missing: []missingLink{}, Dead: []deadLink{}}
+	for p, n := range shared {
+		if linked[p.file][p.doc] || n < t.MinCommits {
+			continue
+		}
+		ratio := float64(n) / float64(fileCommits[p.file])
+		if ratio < t.MinRatio {
+			continue
+		}
+		links.Missing = append(links.Missing, missingLink{
+			Doc: p.doc, File: p.file, Commits: n, SourceCommits: fileCommits[p.file],
+			Ratio: float64(int(ratio*100+0.5)) / 100,
+		})
+	}
+	for file, linkedDocs := range linked {
+		for doc := range linkedDocs {
+			p := pair{doc, file}
+			if since[p] >= t.DeadAfter && since[p] > 0 {
+				links.Dead = append(links.Dead, deadLink{Doc: doc, File: file, CommitsSince: since[p], LastCoChange: lastShared[p]})
+			}
+		}
+	}
+
+	sort.Slice(links.Missing, func(i, j int) bool {
+		a, b := links.Missing[i], links.Missing[j]
+		if a.Ratio != b.Ratio {
+			return a.Ratio > b.Ratio
+		}
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Doc < b.Doc
+	})
+	sort.Slice(links.Dead, func(i, j int) bool {
+		a, b := links.Dead[i], links.Dead[j]
+		if a.CommitsSince != b.CommitsSince {
+			return a.CommitsSince > b.CommitsSince
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Doc < b.Doc
+	})
+	return links
+}
+
+func writeInferHuman(out io.Writer, links inferredLinks) {
+	if len(links.Missing) == 0 && len(links.Dead) == 0 {
+		fmt.Fprintln(out, "No missing or dead links found in history.")
+		return
+	}
+
+	if len(links.Missing) > 0 {
+		fmt.Fprintf(out, "Likely missing links (%d):\n", len(links.Missing))
+		for _, m := range links.Missing {
+			fmt.Fprintf(out, "  %s %s %s   ->   %s   (changed together in %d of its %d commits)\n",
+				commentToken(m.File), cfg.AnnotationTag, m.Doc, m.File, m.Commits, m.SourceCommits)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(links.Dead) > 0 {
+		fmt.Fprintf(out, "Likely dead links (%d):\n", len(links.Dead))
+		for _, d := range links.Dead {
+			last := "never changed together"
+			if d.LastCoChange != "" {
+				last = "last together in " + d.LastCoChange
+			}
+			fmt.Fprintf(out, "  %s -> %s   (%d commits without the doc; %s)\n", d.File, d.Doc, d.CommitsSince, last)
+		}
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintln(out, "Co-change is a hint, not proof: check each pair before adding or removing an annotation.")
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInferLinks(t *testing.T) {
+	dir := setupTestProject(t)
+	initial := runGit(t, dir, "rev-parse", "--short", "HEAD")
+	write := func(rel, content string) {
+		os.WriteFile(filepath.Join(dir, filepath.FromSlash(rel)), []byte(content), 0644)
+	}
+	for i := range 3 {
+		n := strconv.Itoa(i)
+		write("src/util.go", "package main\n\nfunc Util() int { return "+n+" }\n")
+		write("docs/GUIDE.md", "# Guide\n\nRevision "+n+"\n")
+		commitAll(t, dir, "Tune util")
+	}
+	for i := range 2 {
+		write("src/handler.go", "package main\n\n// @doc docs/API.md\nfunc Handler() int { return "+strconv.Itoa(i)+" }\n")
+		commitAll(t, dir, "Tweak handler")
+	}
+	initTestEnv(t, dir)
+
+	run := func(flags map[string]string) inferredLinks {
+		t.Helper()
+		for name, value := range flags {
+			inferLinksCmd.Flags().Set(name, value)
+		}
+		defer func() {
+			for name := range flags {
+				f := inferLinksCmd.Flags().Lookup(name)
+				f.Value.Set(f.DefValue)
+				f.Changed = false
+			}
+		}()
+		var stdout bytes.Buffer
+		inferLinksCmd.SetOut(&stdout)
+		if err := inferLinksCmd.RunE(inferLinksCmd, nil); err != nil {
+			t.Fatalf("infer-links failed: %v", err)
+		}
+		var links inferredLinks
+		if err := json.Unmarshal(stdout.Bytes(), &links); err != nil {
+			t.Fatalf("bad JSON: %v\n%s", err, stdout.String())
+		}
+		return links
+	}
+
+	links := run(map[string]string{"json": "true"})
+	wantMissing := []missingLink{{Doc: "docs/GUIDE.md", File: "src/util.go", Commits: 4, SourceCommits: 4, Ratio: 1}}
+	if !reflect.DeepEqual(links.Missing, wantMissing) {
+		t.Errorf("Missing = %+v, want %+v", links.Missing, wantMissing)
+	}
+	if len(links.Dead) != 0 {
+		t.Errorf("two commits shouldn't make a link dead at the default threshold, got %+v", links.Dead)
+	}
+
+	links = run(map[string]string{"json": "true", "dead-after": "2", "min-commits": "5"})
+	if len(links.Missing) != 0 {
+		t.Errorf("--min-commits 5 should drop the missing link, got %+v", links.Missing)
+	}
+	wantDead := []deadLink{{Doc: "docs/API.md", File: "src/handler.go", CommitsSince: 2, LastCoChange: initial}}
+	if !reflect.DeepEqual(links.Dead, wantDead) {
+		t.Errorf("Dead = %+v, want %+v", links.Dead, wantDead)
+	}
+	if links.Thresholds.DeadAfter != 2 || links.Thresholds.MinRatio != cfg.InferLinks.MinRatio {
+		t.Errorf("Thresholds = %+v, want flags over the config", links.Thresholds)
+	}
+
+	var stdout bytes.Buffer
+	inferLinksCmd.SetOut(&stdout)
+	if err := inferLinksCmd.RunE(inferLinksCmd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "// @doc docs/GUIDE.md   ->   src/util.go   (changed together in 4 of its 4 commits)"; !strings.Contains(stdout.String(), want) {
+		t.Errorf("human output missing %q, got:\n%s", want, stdout.String())
+	}
+}
@@ -0,0 +1,608 @@
+package commands
+
+// @doc README.md
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/language"
+	"github.com/StevenBock/docdiff/internal/lsp"
+	"github.com/StevenBock/docdiff/internal/report"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for annotations over stdio",
+	Long: `Speak the Language Server Protocol over stdin/stdout so editors can show
+docdiff's view of the code you're editing:
+
+  - diagnostics on annotations whose doc is stale or doesn't exist
+  - go-to-definition from an annotation to its doc (and to the heading named
+    by a #scope or section anchor)
+  - hover with the doc's review baseline and staleness
+  - completion of doc paths under docs_directory, and of headings after '#'
+
+Staleness is recomputed when a file is saved; annotations in unsaved buffers
+are re-read as you type. Logs go to stderr.`,
+	RunE: runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	if abs, err := filepath.Abs(rootDir); err == nil {
+		rootDir = abs // buffers arrive as absolute file:// URIs
+	}
+	srv := &lspServer{
+		conn:      lsp.NewConn(cmd.InOrStdin(), cmd.OutOrStdout()),
+		log:       cmd.ErrOrStderr(),
+		open:      make(map[string]string),
+		published: make(map[string]bool),
+	}
+	srv.reset()
+	return srv.serve()
+}
+
+// lspServer answers one editor. Requests are handled one at a time, in order.
+type lspServer struct {
+	conn *lsp.Conn
+	log  io.Writer
+
+	g         git.Repo
+	s         *scanner.Scanner
+	scan      *scanner.Result
+	stale     map[string]*report.StaleDoc
+	docs      map[string][]byte // doc contents read since the last refresh; nil if missing
+	open      map[string]string // buffer text of open documents, by URI
+	published map[string]bool   // URIs last sent non-empty diagnostics
+	shutdown  bool
+}
+
+// reset points the server at rootDir's repository and drops everything read
+// from it.
+func (srv *lspServer) reset() {
+	srv.g = openRepo()
+	srv.s = scanner.New(cfg, registry)
+	srv.s.Retain()
+	srv.scan = scanner.NewResult()
+	srv.stale = map[string]*report.StaleDoc{}
+	srv.docs = map[string][]byte{}
+}
+
+var errExitBeforeShutdown = errors.New("lsp: exit without shutdown")
+
+func (srv *lspServer) serve() error {
+	for {
+		req, err := srv.conn.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var rpcErr *lsp.Error
+		if errors.As(err, &rpcErr) {
+			srv.conn.Reply(json.RawMessage("null"), nil, rpcErr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			if !srv.shutdown {
+				return errExitBeforeShutdown
+			}
+			return nil
+		}
+		result, rpcErr := srv.handle(req)
+		if req.IsNotification() {
+			if rpcErr != nil {
+				fmt.Fprintf(srv.log, "docdiff lsp: %s: %v\n", req.Method, rpcErr)
+			}
+			continue
+		}
+		if err := srv.conn.Reply(req.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (srv *lspServer) handle(req *lsp.Request) (any, *lsp.Error) {
+	switch req.Method {
+	case "initialize":
+		var p lsp.InitializeParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		return srv.initialize(p)
+	case "initialized":
+		srv.refresh()
+		srv.publishAll()
+	case "shutdown":
+		srv.shutdown = true
+
+	case "textDocument/didOpen":
+		var p lsp.DidOpenTextDocumentParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		srv.open[p.TextDocument.URI] = p.TextDocument.Text
+		srv.publish(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p lsp.DidChangeTextDocumentParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			srv.open[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		srv.publish(p.TextDocument.URI)
+	case "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		srv.refresh()
+		srv.publishAll()
+	case "textDocument/didClose":
+		var p lsp.DidCloseTextDocumentParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		delete(srv.open, p.TextDocument.URI)
+		srv.publish(p.TextDocument.URI)
+
+	case "textDocument/definition":
+		var p lsp.TextDocumentPositionParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		return srv.definition(p), nil
+	case "textDocument/hover":
+		var p lsp.TextDocumentPositionParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		return srv.hover(p), nil
+	case "textDocument/completion":
+		var p lsp.TextDocumentPositionParams
+		if err := unmarshalParams(req, &p); err != nil {
+			return nil, err
+		}
+		return srv.completion(p), nil
+
+	default:
+		if !req.IsNotification() {
+			return nil, &lsp.Error{Code: lsp.CodeMethodNotFound, Message: "method not supported: " + req.Method}
+		}
+	}
+	return nil, nil
+}
+
+func unmarshalParams(req *lsp.Request, v any) *lsp.Error {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &lsp.Error{Code: lsp.CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// initialize adopts the client's workspace root when it names a different
+// directory than --dir, reloading the config from there.
+func (srv *lspServer) initialize(p lsp.InitializeParams) (any, *lsp.Error) {
+	root := lsp.URIToPath(p.RootURI)
+	if root == "" {
+		root = p.RootPath
+	}
+	if root != "" && filepath.Clean(root) != filepath.Clean(rootDir) {
+		c, err := config.Load(root)
+		if err != nil {
+			return nil, &lsp.Error{Code: lsp.CodeInternalError, Message: err.Error()}
+		}
+		reg, err := language.FromConfig(c.Languages)
+		if err != nil {
+			return nil, &lsp.Error{Code: lsp.CodeInternalError, Message: "invalid config: " + err.Error()}
+		}
+		rootDir, cfg, registry = filepath.Clean(root), c, reg
+		srv.reset()
+	}
+
+	return lsp.InitializeResult{
+		Capabilities: lsp.ServerCapabilities{
+			TextDocumentSync:   lsp.TextDocumentSyncOptions{OpenClose: true, Change: lsp.SyncFull, Save: true},
+			DefinitionProvider: true,
+			HoverProvider:      true,
+			CompletionProvider: &lsp.CompletionOptions{TriggerCharacters: []string{"/", "#", " "}},
+		},
+		ServerInfo: lsp.ServerInfo{Name: "docdiff"},
+	}, nil
+}
+
+// refresh rescans the tree and recomputes staleness. The scan is retained, so
+// only files saved since the last refresh are re-read.
+func (srv *lspServer) refresh() {
+	result, err := srv.s.Scan(rootDir)
+	if err != nil {
+		fmt.Fprintf(srv.log, "docdiff lsp: scan failed: %v\n", err)
+		return
+	}
+	srv.scan = result
+	srv.docs = map[string][]byte{}
+	srv.stale = map[string]*report.StaleDoc{}
+	if srv.g.IsRepo() {
+		srv.stale = computeStaleDocs(srv.g, result, srv.log)
+	}
+}
+
+// publishAll sends diagnostics for every annotated file with a problem, every
+// open document, and every file that had diagnostics before (to clear them).
+func (srv *lspServer) publishAll() {
+	uris := make(map[string]bool)
+	for uri := range srv.open {
+		uris[uri] = true
+	}
+	for uri := range srv.published {
+		uris[uri] = true
+	}
+	for file, ann := range srv.scan.Annotations {
+		for _, d := range ann.Details {
+			if srv.stale[d.Path] != nil || srv.docContent(d.Path) == nil {
+				uris[lsp.PathToURI(filepath.Join(rootDir, filepath.FromSlash(file)))] = true
+				break
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(uris))
+	for uri := range uris {
+		sorted = append(sorted, uri)
+	}
+	sort.Strings(sorted)
+	for _, uri := range sorted {
+		srv.publish(uri)
+	}
+}
+
+func (srv *lspServer) publish(uri string) {
+	diags := srv.diagnostics(uri)
+	if len(diags) == 0 && !srv.published[uri] {
+		return
+	}
+	srv.published[uri] = len(diags) > 0
+	if diags == nil {
+		diags = []lsp.Diagnostic{}
+	}
+	if err := srv.conn.Notify("textDocument/publishDiagnostics", lsp.PublishDiagnosticsParams{URI: uri, Diagnostics: diags}); err != nil {
+		fmt.Fprintf(srv.log, "docdiff lsp: %v\n", err)
+	}
+}
+
+// lspAnnotation is one annotation in a buffer, with its doc path resolved and
+// the range of the target as written.
+type lspAnnotation struct {
+	language.DocAnnotation
+	Range lsp.Range
+}
+
+// annotations returns the file behind uri relative to the root and the
+// annotations in its current text: the open buffer, else the file on disk.
+func (srv *lspServer) annotations(uri string) (string, []lspAnnotation) {
+	path := lsp.URIToPath(uri)
+	if path == "" {
+		return "", nil
+	}
+	rel, err := filepath.Rel(rootDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	text, ok := srv.open[uri]
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return rel, nil
+		}
+		text = string(content)
+	}
+	_, details, ok := srv.s.ExtractContent(rel, []byte(text))
+	if !ok {
+		return rel, nil
+	}
+
+	lines := strings.Split(text, "\n")
+	anns := make([]lspAnnotation, 0, len(details))
+	for _, d := range details {
+		r := annotationRange(lines, d) // located by the path as written
+		d.Path = scanner.ResolveAlias(cfg.Aliases, scanner.ResolveDocPath(rel, d.Path))
+		anns = append(anns, lspAnnotation{DocAnnotation: d, Range: r})
+	}
+	return rel, anns
+}
+
+// annotationRange spans the target of d on its line — the doc path and any
+// #scope after it — or the whole line if the target can't be found.
+func annotationRange(lines []string, d language.DocAnnotation) lsp.Range {
+	if d.Line < 1 || d.Line > len(lines) {
+		return lsp.Range{}
+	}
+	line := strings.TrimSuffix(lines[d.Line-1], "\r")
+	n := d.Line - 1
+	whole := lsp.Range{Start: lsp.Position{Line: n}, End: lsp.Position{Line: n, Character: lsp.Column(line, len(line))}}
+
+	tag := strings.Index(line, cfg.AnnotationTag)
+	if tag < 0 {
+		return whole
+	}
+	start := strings.Index(line[tag:], d.Path)
+	if start < 0 {
+		return whole
+	}
+	start += tag
+	end := start + len(d.Path)
+	if d.Scope != "" {
+		if i := strings.Index(line[end:], "#"+d.Scope); i >= 0 {
+			end += i + 1 + len(d.Scope)
+		}
+	}
+	return lsp.Range{
+		Start: lsp.Position{Line: n, Character: lsp.Column(line, start)},
+		End:   lsp.Position{Line: n, Character: lsp.Column(line, end)},
+	}
+}
+
+// annotationAt is the annotation under pos: the one whose target contains it,
+// else the first on its line.
+func annotationAt(anns []lspAnnotation, pos lsp.Position) (lspAnnotation, bool) {
+	var onLine []lspAnnotation
+	for _, a := range anns {
+		if a.Range.Start.Line == pos.Line {
+			onLine = append(onLine, a)
+		}
+	}
+	for _, a := range onLine {
+		if pos.Character >= a.Range.Start.Character && pos.Character <= a.Range.End.Character {
+			return a, true
+		}
+	}
+	if len(onLine) > 0 {
+		return onLine[0], true
+	}
+	return lspAnnotation{}, false
+}
+
+// docContent reads a doc target's file once per refresh; nil if the scanner
+// would call the annotation broken (see scanner.BrokenReason).
+func (srv *lspServer) docContent(target string) []byte {
+	path, _ := docparse.SplitAnchor(target)
+	if content, ok := srv.docs[path]; ok {
+		return content
+	}
+	var content []byte
+	if scanner.BrokenReason(rootDir, path) == "" {
+		var err error
+		if content, err = os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(path))); err == nil && content == nil {
+			content = []byte{}
+		}
+	}
+	srv.docs[path] = content
+	return content
+}
+
+func (srv *lspServer) diagnostics(uri string) []lsp.Diagnostic {
+	rel, anns := srv.annotations(uri)
+	var diags []lsp.Diagnostic
+	add := func(a lspAnnotation, severity int, code, msg string) {
+		diags = append(diags, lsp.Diagnostic{Range: a.Range, Severity: severity, Code: code, Source: "docdiff", Message: msg})
+	}
+	for _, a := range anns {
+		path, anchor := docparse.SplitAnchor(a.Path)
+		content := srv.docContent(a.Path)
+		if content == nil {
+			code, msg := "missing-doc", fmt.Sprintf("%s does not exist", path)
+			if scanner.BrokenReason(rootDir, path) == scanner.BrokenOutsideRepo {
+				code, msg = "doc-outside-repo", fmt.Sprintf("%s is outside the repository", path)
+			}
+			if s := scanner.SuggestDoc(path, srv.scan.DocFiles); s != "" {
+				msg += fmt.Sprintf(" — did you mean %s?", s)
+			}
+			add(a, lsp.SeverityError, code, msg)
+			continue
+		}
+		if anchor != "" {
+			if _, ok := formats.Section(path, content, anchor); !ok {
+				add(a, lsp.SeverityError, "missing-heading", fmt.Sprintf("%s has no heading with anchor #%s", path, anchor))
+				continue
+			}
+		}
+
+		sd := srv.stale[a.Path]
+		if sd == nil {
+			continue
+		}
+		msg := fmt.Sprintf("%s is stale: %d linked file(s) changed since %s", a.Path, sd.FilesChanged, sd.LastCommitInfo)
+		severity := lsp.SeverityInformation
+		for _, f := range sd.ChangedFiles {
+			if f == rel {
+				msg += ", including this one"
+				severity = lsp.SeverityWarning
+				break
+			}
+		}
+		add(a, severity, "stale-doc", msg+fmt.Sprintf(". Run 'docdiff changes %s'.", a.Path))
+	}
+	return diags
+}
+
+// definition jumps to the doc, at the heading named by a section anchor or a
+// #scope when the doc has one.
+func (srv *lspServer) definition(p lsp.TextDocumentPositionParams) any {
+	_, anns := srv.annotations(p.TextDocument.URI)
+	a, ok := annotationAt(anns, p.Position)
+	if !ok {
+		return nil
+	}
+	path, anchor := docparse.SplitAnchor(a.Path)
+	content := srv.docContent(a.Path)
+	if content == nil {
+		return nil
+	}
+	if anchor == "" {
+		anchor = a.Scope
+	}
+	line := 0
+	if anchor != "" {
+		if h, ok := formats.Section(path, content, anchor); ok {
+			line = h.Line - 1
+		}
+	}
+	pos := lsp.Position{Line: line}
+	return lsp.Location{
+		URI:   lsp.PathToURI(filepath.Join(rootDir, filepath.FromSlash(path))),
+		Range: lsp.Range{Start: pos, End: pos},
+	}
+}
+
+// hover shows the doc's review baseline and whether it's stale.
+func (srv *lspServer) hover(p lsp.TextDocumentPositionParams) any {
+	_, anns := srv.annotations(p.TextDocument.URI)
+	a, ok := annotationAt(anns, p.Position)
+	if !ok {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", a.Path)
+	if a.Scope != "" {
+		fmt.Fprintf(&b, " (scoped #%s)", a.Scope)
+	}
+	b.WriteString("\n\n")
+	switch {
+	case srv.docContent(a.Path) == nil:
+		b.WriteString("Doc does not exist.\n")
+	case !srv.g.IsRepo():
+		b.WriteString("Not in a git repository — no review baseline.\n")
+	default:
+		srv.writeBaseline(&b, a.Path)
+	}
+	r := a.Range
+	return lsp.Hover{Contents: lsp.MarkupContent{Kind: "markdown", Value: b.String()}, Range: &r}
+}
+
+func (srv *lspServer) writeBaseline(b *strings.Builder, doc string) {
+	acks, err := loadAcks(rootDir)
+	if err != nil {
+		fmt.Fprintf(srv.log, "Warning: failed to load %s: %v\n", acksFile, err)
+		acks = map[string]string{}
+	}
+	baseline, err := baselineForDoc(srv.g, doc, acks)
+	if err != nil {
+		fmt.Fprintf(b, "Baseline unknown: %v\n", err)
+		return
+	}
+	if baseline.Effective == "" {
+		b.WriteString("Baseline: none — the doc was never committed and has no ack.\n")
+		return
+	}
+
+	info, _ := srv.g.CommitInfo(baseline.Effective)
+	subject, _ := srv.g.CommitSubject(baseline.Effective)
+	fmt.Fprintf(b, "Baseline: `%s` %s", info, subject)
+	if baseline.Effective == baseline.AckFloor && baseline.AckFloor != baseline.DocCommit {
+		b.WriteString(" (from ack floor)")
+	}
+	b.WriteString("\n\n")
+	if sd := srv.stale[doc]; sd != nil {
+		fmt.Fprintf(b, "**Stale** — %d linked file(s) changed since: %s. Run `docdiff changes %s`.\n", sd.FilesChanged, strings.Join(sd.ChangedFiles, ", "), doc)
+	} else {
+		b.WriteString("Up to date with its linked code.\n")
+	}
+}
+
+// completion offers doc paths right after the annotation tag, and heading
+// anchors after `path#` or `path #`.
+func (srv *lspServer) completion(p lsp.TextDocumentPositionParams) any {
+	uri := p.TextDocument.URI
+	text, ok := srv.open[uri]
+	if !ok {
+		content, err := os.ReadFile(lsp.URIToPath(uri))
+		if err != nil {
+			return nil
+		}
+		text = string(content)
+	}
+	lines := strings.Split(text, "\n")
+	if p.Position.Line >= len(lines) {
+		return nil
+	}
+	line := strings.TrimSuffix(lines[p.Position.Line], "\r")
+	before := line[:lsp.Offset(line, p.Position.Character)]
+
+	tag := regexp.QuoteMeta(cfg.AnnotationTag)
+	editRange := func(typed string) lsp.Range {
+		start := lsp.Position{Line: p.Position.Line, Character: lsp.Column(line, len(before)-len(typed))}
+		return lsp.Range{Start: start, End: p.Position}
+	}
+
+	list := lsp.CompletionList{Items: []lsp.CompletionItem{}}
+	if m := regexp.MustCompile(tag + `\s+(\S+?)(?:#|\s+#)(\S*)$`).FindStringSubmatch(before); m != nil {
+		content := srv.docContent(m[1])
+		for _, h := range formats.Headings(m[1], content) {
+			if strings.HasPrefix(h.Anchor, m[2]) {
+				list.Items = append(list.Items, lsp.CompletionItem{
+					Label:    h.Anchor,
+					Detail:   strings.Repeat("#", h.Level) + " " + h.Title,
+					TextEdit: &lsp.TextEdit{Range: editRange(m[2]), NewText: h.Anchor},
+				})
+			}
+		}
+		return list
+	}
+	if m := regexp.MustCompile(tag + `\s+(\S*)$`).FindStringSubmatch(before); m != nil {
+		for _, doc := range docFiles() {
+			if strings.HasPrefix(doc, m[1]) {
+				list.Items = append(list.Items, lsp.CompletionItem{
+					Label:    doc,
+					Kind:     lsp.CompletionKindFile,
+					TextEdit: &lsp.TextEdit{Range: editRange(m[1]), NewText: doc},
+				})
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// docFiles lists the files under docs_directory, relative to the root.
+func docFiles() []string {
+	var docs []string
+	filepath.WalkDir(cfg.DocsPath(rootDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != cfg.DocsPath(rootDir) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if rel, err := filepath.Rel(rootDir, path); err == nil {
+				docs = append(docs, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	sort.Strings(docs)
+	return docs
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/lsp"
+)
+
+// lspSession frames client messages for a scripted run of the server.
+type lspSession struct {
+	in     bytes.Buffer
+	nextID int
+}
+
+func (s *lspSession) send(method string, params any) int {
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	id := 0
+	if !strings.HasPrefix(method, "textDocument/did") && method != "initialized" && method != "exit" {
+		s.nextID++
+		id = s.nextID
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(&s.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return id
+}
+
+type lspMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *lsp.Error      `json:"error"`
+}
+
+func readLSPMessages(t *testing.T, out []byte) []lspMessage {
+	t.Helper()
+	r := bufio.NewReader(bytes.NewReader(out))
+	var msgs []lspMessage
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return msgs
+		}
+		if err != nil {
+			t.Fatalf("bad header: %v", err)
+		}
+		n, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, n)
+		io.ReadFull(r, body)
+		var m lspMessage
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatalf("bad body %s: %v", body, err)
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+func TestLSP(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte("# API Docs\n\nIntro.\n\n## Limits\n\n100 rps.\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "gone.go"), []byte("package main\n\n// @doc docs/GONE.md\nfunc Gone() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "cased.go"), []byte("package main\n\n// @doc docs/api.md\nfunc Cased() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "escape.go"), []byte("package main\n\n// @doc ../../outside.md\nfunc Escape() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "..", "outside.md"), []byte("# Outside\n"), 0644)
+	commitAll(t, dir, "Document limits")
+	os.WriteFile(filepath.Join(dir, "src", "handler.go"), []byte("package main\n\n// @doc docs/API.md\nfunc Handler(name string) {}\n"), 0644)
+	commitAll(t, dir, "Take a name")
+	initTestEnv(t, dir)
+
+	uri := func(rel string) string { return lsp.PathToURI(filepath.Join(dir, filepath.FromSlash(rel))) }
+	buffer := "package main\n\n// @doc docs/API.md #limits\nvar Limit = 100\n\n// @doc docs/AP\n// @doc docs/API.md#li\n"
+
+	var s lspSession
+	s.send("initialize", map[string]any{"rootUri": uri("")})
+	s.send("initialized", map[string]any{})
+	s.send("textDocument/didOpen", map[string]any{"textDocument": map[string]any{
+		"uri": uri("src/limits.go"), "languageId": "go", "version": 1, "text": buffer,
+	}})
+	at := func(line, char int) map[string]any {
+		return map[string]any{"textDocument": map[string]any{"uri": uri("src/limits.go")}, "position": map[string]any{"line": line, "character": char}}
+	}
+	defID := s.send("textDocument/definition", at(2, 12))
+	hoverID := s.send("textDocument/hover", at(2, 12))
+	pathsID := s.send("textDocument/completion", at(5, 15))
+	anchorsID := s.send("textDocument/completion", at(6, 21))
+	s.send("shutdown", nil)
+	s.send("exit", nil)
+
+	var out bytes.Buffer
+	lspCmd.SetIn(&s.in)
+	lspCmd.SetOut(&out)
+	lspCmd.SetErr(io.Discard)
+	if err := lspCmd.RunE(lspCmd, nil); err != nil {
+		t.Fatalf("lsp failed: %v", err)
+	}
+
+	results := map[int]json.RawMessage{}
+	diagnostics := map[string][]lsp.Diagnostic{}
+	for _, m := range readLSPMessages(t, out.Bytes()) {
+		if m.Error != nil {
+			t.Errorf("request %d failed: %v", *m.ID, m.Error)
+		}
+		if m.ID != nil {
+			results[*m.ID] = m.Result
+			continue
+		}
+		if m.Method == "textDocument/publishDiagnostics" {
+			var p lsp.PublishDiagnosticsParams
+			json.Unmarshal(m.Params, &p)
+			diagnostics[p.URI] = p.Diagnostics
+		}
+	}
+
+	t.Run("diagnostics", func(t *testing.T) {
+		gone := diagnostics[uri("src/gone.go")]
+		if len(gone) != 1 || gone[0].Code != "missing-doc" || gone[0].Range.Start.Line != 2 {
+			t.Errorf("missing doc should be flagged on its annotation, got %+v", gone)
+		}
+		cased := diagnostics[uri("src/cased.go")]
+		if len(cased) != 1 || cased[0].Code != "missing-doc" || !strings.Contains(cased[0].Message, "did you mean docs/API.md?") {
+			t.Errorf("a doc path in the wrong case should be flagged like check does, got %+v", cased)
+		}
+		escape := diagnostics[uri("src/escape.go")]
+		if len(escape) != 1 || escape[0].Code != "doc-outside-repo" {
+			t.Errorf("a doc outside the repository should be flagged even if it exists, got %+v", escape)
+		}
+		handler := diagnostics[uri("src/handler.go")]
+		if len(handler) != 1 || handler[0].Code != "stale-doc" || handler[0].Severity != lsp.SeverityWarning ||
+			!strings.Contains(handler[0].Message, "including this one") {
+			t.Errorf("stale doc should be flagged on the file that changed, got %+v", handler)
+		}
+		want := lsp.Range{Start: lsp.Position{Line: 2, Character: 8}, End: lsp.Position{Line: 2, Character: 19}}
+		if len(handler) == 1 && handler[0].Range != want {
+			t.Errorf("diagnostic range = %+v, want the doc path %+v", handler[0].Range, want)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var loc lsp.Location
+		json.Unmarshal(results[defID], &loc)
+		if loc.URI != uri("docs/API.md") || loc.Range.Start.Line != 4 {
+			t.Errorf("definition should land on the ## Limits heading, got %+v", loc)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var h lsp.Hover
+		json.Unmarshal(results[hoverID], &h)
+		for _, want := range []string{"**docs/API.md** (scoped #limits)", "Baseline: `", "Document limits", "**Stale**", "src/handler.go"} {
+			if !strings.Contains(h.Contents.Value, want) {
+				t.Errorf("hover missing %q, got:\n%s", want, h.Contents.Value)
+			}
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		labels := func(id int) []string {
+			var list lsp.CompletionList
+			json.Unmarshal(results[id], &list)
+			var out []string
+			for _, item := range list.Items {
+				out = append(out, item.Label)
+			}
+			return out
+		}
+		if got := labels(pathsID); len(got) != 1 || got[0] != "docs/API.md" {
+			t.Errorf("path completion = %v, want [docs/API.md]", got)
+		}
+		if got := labels(anchorsID); len(got) != 1 || got[0] != "limits" {
+			t.Errorf("anchor completion = %v, want [limits]", got)
+		}
+	})
+}
@@ -0,0 +1,397 @@
+package commands
+
+// @doc README.md
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+var mvDryRun bool
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <old> <new>",
+	Short: "Move a doc and rewrite every annotation that links to it",
+	Long: `Move a doc (or a directory of docs) and rewrite every annotation that links to
+it, so nothing is left pointing at the old path.
+
+Each rewritten annotation keeps the style it was written in: root-anchored
+paths stay root-anchored, relative paths are recomputed from their file, and
+anchors are kept. Annotations that reach the doc through an alias are pointed
+at the new path. Acks recorded for the old path move to the new one, and so do
+alias targets and the doc of every links: rule in the config and in
+docdiff.map.yaml.
+
+If the doc was already moved (say with git mv), only the annotations and acks
+are rewritten. Commit the move on its own, without editing the doc: a commit
+that only renames a doc doesn't reset its review baseline.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runMv,
+}
+
+func init() {
+	mvCmd.Flags().BoolVar(&mvDryRun, "dry-run", false, "show what would change without touching any file")
+	rootCmd.AddCommand(mvCmd)
+}
+
+// mvEdit is one annotation, or one doc path in a config or mapping file,
+// rewritten by mv.
+type mvEdit struct {
+	file     string
+	line     int
+	key      string // for config edits: what the path is, e.g. "doc"
+	from, to string
+}
+
+func runMv(cmd *cobra.Command, args []string) error {
+	from, to := cleanDocArg(args[0]), cleanDocArg(args[1])
+	if from == "" || to == "" || from == to {
+		return fmt.Errorf("mv needs two different doc paths")
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return fmt.Errorf("cannot move %s into itself", from)
+	}
+
+	_, fromErr := os.Stat(filepath.Join(rootDir, filepath.FromSlash(from)))
+	_, toErr := os.Stat(filepath.Join(rootDir, filepath.FromSlash(to)))
+	move := fromErr == nil
+	switch {
+	case move && toErr == nil:
+		return fmt.Errorf("%s already exists", to)
+	case !move && toErr != nil:
+		return fmt.Errorf("doc not found: %s", from)
+	}
+
+	s := scanner.New(cfg, registry)
+	scanResult, err := s.Scan(rootDir)
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+
+	files := make([]string, 0, len(scanResult.Annotations))
+	for file := range scanResult.Annotations {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var edits []mvEdit
+	for _, file := range files {
+		fileEdits, err := rewriteAnnotations(s, file, from, to, !mvDryRun)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, fileEdits...)
+	}
+
+	var configEdits []mvEdit
+	for _, file := range mappingFiles() {
+		fileEdits, err := rewriteConfigDocs(file, from, to, !mvDryRun)
+		if err != nil {
+			return err
+		}
+		configEdits = append(configEdits, fileEdits...)
+	}
+
+	acks, err := loadAcks(rootDir)
+	if err != nil {
+		return fmt.Errorf("failed to load acks: %w", err)
+	}
+	movedAcks := renameAcks(acks, from, to)
+
+	if !mvDryRun {
+		if move {
+			full := filepath.Join(rootDir, filepath.FromSlash(to))
+			if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(filepath.Join(rootDir, filepath.FromSlash(from)), full); err != nil {
+				return err
+			}
+		}
+		if len(movedAcks) > 0 {
+			if err := saveAcks(rootDir, acks); err != nil {
+				return fmt.Errorf("failed to save acks: %w", err)
+			}
+		}
+	}
+
+	writeMvSummary(cmd.OutOrStdout(), from, to, move, edits, configEdits, movedAcks)
+	return nil
+}
+
+func writeMvSummary(out io.Writer, from, to string, move bool, edits, configEdits []mvEdit, movedAcks []string) {
+	verb := func(done, would string) string {
+		if mvDryRun {
+			return would
+		}
+		return done
+	}
+
+	if move {
+		fmt.Fprintf(out, "%s %s -> %s\n", verb("Moved", "Would move"), from, to)
+	}
+	files := make(map[string]bool)
+	for _, e := range edits {
+		files[e.file] = true
+	}
+	if len(edits) == 0 {
+		fmt.Fprintf(out, "No annotations link to %s.\n", from)
+	} else {
+		fmt.Fprintf(out, "%s %d annotation(s) in %d file(s):\n", verb("Rewrote", "Would rewrite"), len(edits), len(files))
+		for _, e := range edits {
+			fmt.Fprintf(out, "  %s:%d  %s -> %s\n", e.file, e.line, e.from, e.to)
+		}
+	}
+	if len(configEdits) > 0 {
+		fmt.Fprintf(out, "%s %d doc path(s) in config:\n", verb("Rewrote", "Would rewrite"), len(configEdits))
+		for _, e := range configEdits {
+			fmt.Fprintf(out, "  %s:%d  %s: %s -> %s\n", e.file, e.line, e.key, e.from, e.to)
+		}
+	}
+	for _, doc := range movedAcks {
+		fmt.Fprintf(out, "%s ack for %s\n", verb("Moved", "Would move"), doc)
+	}
+	if move && !mvDryRun {
+		fmt.Fprintln(out, "\nCommit the move without editing the doc so its review baseline carries over.")
+	}
+}
+
+// cleanDocArg turns a doc path from the command line into a repo-relative key.
+func cleanDocArg(arg string) string {
+	p := strings.Trim(filepath.ToSlash(arg), "/")
+	if p == "" {
+		return ""
+	}
+	return path.Clean(p)
+}
+
+// movedPath is p with from replaced by to, if p is from or lies under it.
+func movedPath(p, from, to string) (string, bool) {
+	if p == from {
+		return to, true
+	}
+	if strings.HasPrefix(p, from+"/") {
+		return to + p[len(from):], true
+	}
+	return "", false
+}
+
+// rewriteAnnotations points file's annotations for from (or anything under it)
+// at to, writing the file back if write is set. An annotation naming an old
+// path that an alias maps to from is pointed at to directly.
+func rewriteAnnotations(s *scanner.Scanner, file, from, to string, write bool) ([]mvEdit, error) {
+	full := filepath.Join(rootDir, filepath.FromSlash(file))
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return nil, err
+	}
+	_, details, ok := s.ExtractContent(file, content)
+	if !ok {
+		return nil, nil
+	}
+	sort.SliceStable(details, func(i, j int) bool { return details[i].Line < details[j].Line })
+
+	lines := strings.Split(string(content), "\n")
+	searchFrom := make(map[int]int) // byte offset on each line past the last rewrite
+	var edits []mvEdit
+	for _, d := range details {
+		resolved, anchor := docparse.SplitAnchor(scanner.ResolveAlias(cfg.Aliases, scanner.ResolveDocPath(file, d.Path)))
+		target, ok := movedPath(resolved, from, to)
+		if !ok || d.Line < 1 || d.Line > len(lines) {
+			continue
+		}
+		if anchor != "" {
+			target += "#" + anchor
+		}
+
+		line := lines[d.Line-1]
+		start, ok := searchFrom[d.Line]
+		if !ok {
+			if start = strings.Index(line, cfg.AnnotationTag); start < 0 {
+				continue
+			}
+		}
+		i := strings.Index(line[start:], d.Path)
+		if i < 0 {
+			continue
+		}
+		i += start
+		rewritten := restylePath(file, d.Path, target)
+		lines[d.Line-1] = line[:i] + rewritten + line[i+len(d.Path):]
+		searchFrom[d.Line] = i + len(rewritten)
+		edits = append(edits, mvEdit{file: file, line: d.Line, from: d.Path, to: rewritten})
+	}
+
+	if len(edits) == 0 || !write {
+		return edits, nil
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	return edits, os.WriteFile(full, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
+
+// mappingFiles lists the repo-relative files that can hold links: rules or
+// aliases: the config file, if any, and the mapping file.
+func mappingFiles() []string {
+	var files []string
+	if p := config.FilePath(rootDir); p != "" {
+		files = append(files, filepath.Base(p))
+	}
+	return append(files, config.MapFile)
+}
+
+// rewriteConfigDocs points the doc of each links: rule and the target of each
+// alias in file that names from (or a doc under it) at to, writing the file
+// back if write is set. A rule's doc goes through the aliases first, as the
+// scanner reads it. Only the values change, so comments and layout are kept.
+// A missing file has nothing to rewrite.
+func rewriteConfigDocs(file, from, to string, write bool) ([]mvEdit, error) {
+	full := filepath.Join(rootDir, filepath.FromSlash(file))
+	content, err := os.ReadFile(full)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	type docValue struct {
+		key      string
+		node     *yaml.Node
+		resolved string // the doc the value names
+	}
+	var values []docValue
+	root := documentRoot(&doc)
+	if rules := mappingValue(root, "links"); rules != nil && rules.Kind == yaml.SequenceNode {
+		for _, rule := range rules.Content {
+			if v := mappingValue(rule, "doc"); v != nil && v.Kind == yaml.ScalarNode {
+				values = append(values, docValue{"doc", v, scanner.ResolveAlias(cfg.Aliases, scanner.ResolveDocPath("", v.Value))})
+			}
+		}
+	}
+	if aliases := mappingValue(root, "aliases"); aliases != nil && aliases.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(aliases.Content); i += 2 {
+			if v := aliases.Content[i+1]; v.Kind == yaml.ScalarNode {
+				values = append(values, docValue{"aliases[" + aliases.Content[i].Value + "]", v, cleanDocArg(v.Value)})
+			}
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].node.Line < values[j].node.Line })
+
+	lines := strings.Split(string(content), "\n")
+	var edits []mvEdit
+	for _, dv := range values {
+		v := dv.node
+		resolved, anchor := docparse.SplitAnchor(dv.resolved)
+		target, ok := movedPath(resolved, from, to)
+		if !ok {
+			continue
+		}
+		if anchor != "" {
+			target += "#" + anchor
+		}
+		rewritten := restylePath("", v.Value, target)
+		line := lines[v.Line-1]
+		start := min(max(v.Column-1, 0), len(line))
+		i := strings.Index(line[start:], v.Value)
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: can't rewrite %q in place; update it to %s by hand", file, v.Line, v.Value, rewritten)
+		}
+		i += start
+		lines[v.Line-1] = line[:i] + rewritten + line[i+len(v.Value):]
+		edits = append(edits, mvEdit{file: file, line: v.Line, key: dv.key, from: v.Value, to: rewritten})
+	}
+
+	if len(edits) == 0 || !write {
+		return edits, nil
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	return edits, os.WriteFile(full, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
+
+// documentRoot is the top-level node of a parsed YAML (or JSON) document.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value under key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// restylePath writes target (repo-relative, maybe with an anchor) the way raw
+// was written in sourceFile: root-anchored, relative to the file, or plain,
+// with backslashes if raw used them.
+func restylePath(sourceFile, raw, target string) string {
+	p := strings.ReplaceAll(raw, "\\", "/")
+	out := target
+	switch {
+	case strings.HasPrefix(p, "/"):
+		out = "/" + target
+	case p == "." || p == ".." || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../"):
+		rel, err := filepath.Rel(filepath.FromSlash(path.Dir(sourceFile)), filepath.FromSlash(target))
+		if err != nil {
+			break
+		}
+		out = filepath.ToSlash(rel)
+		if !strings.HasPrefix(out, "../") {
+			out = "./" + out
+		}
+	}
+	if strings.Contains(raw, "\\") {
+		out = strings.ReplaceAll(out, "/", "\\")
+	}
+	return out
+}
+
+// renameAcks moves ack entries for from (and its sections, or docs under it)
+// to the new path, returning the old keys it moved.
+func renameAcks(acks map[string]string, from, to string) []string {
+	var moved []string
+	for doc, sha := range acks {
+		p, anchor := docparse.SplitAnchor(doc)
+		target, ok := movedPath(p, from, to)
+		if !ok {
+			continue
+		}
+		if anchor != "" {
+			target += "#" + anchor
+		}
+		delete(acks, doc)
+		acks[target] = sha
+		moved = append(moved, doc)
+	}
+	sort.Strings(moved)
+	return moved
+}
@@ -0,0 +1,443 @@
+package commands
+
+// @doc README.md
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+// The most each signal can add to a confidence. Signals combine as
+// independent evidence: confidence = 1 - Π(1 - weight·strength), so two
+// agreeing signals beat either alone and no signal reaches 1 by itself.
+const (
+	weightDirectory = 0.6
+	weightMention   = 0.5
+	weightImport    = 0.45
+	weightCoChange  = 0.5
+
+	// coChangeMaxFiles skips commits too big to say anything about coupling.
+	coChangeMaxFiles = 30
+)
+
+// ownerEvidence is one signal's support for a doc owning a file, strength in
+// [0,1].
+type ownerEvidence struct {
+	weight   float64
+	strength float64
+	reason   string
+}
+
+// ownerModel scores the docs that might own an orphaned file from four
+// signals: directory votes of annotated neighbors, docs that mention the file
+// or its symbols, annotated import-graph neighbors (Go, JS/TS, Python), and
+// annotated files or docs it is committed together with.
+type ownerModel struct {
+	scan       *scanner.Result
+	votes      map[string]map[string]int // dir -> doc -> annotated files linking it
+	dirFiles   map[string]int            // dir -> annotated files
+	pathRefs   map[string][]string       // file -> docs naming its path
+	spans      map[string][]string       // code span text -> docs using it
+	imports    map[string]map[string]bool
+	history    *git.History
+	candidates map[string]bool // docs a file may be suggested for
+	minConf    float64         // below this, a file is left unmapped
+}
+
+func newOwnerModel(scanResult *scanner.Result, history *git.History, minConfidence float64) *ownerModel {
+	m := &ownerModel{
+		scan:       scanResult,
+		minConf:    minConfidence,
+		votes:      make(map[string]map[string]int),
+		dirFiles:   make(map[string]int),
+		pathRefs:   make(map[string][]string),
+		spans:      make(map[string][]string),
+		history:    history,
+		candidates: make(map[string]bool),
+	}
+	for file, ann := range scanResult.Annotations {
+		dir := path.Dir(file)
+		m.dirFiles[dir]++
+		for _, doc := range ann.DocPaths {
+			if m.votes[dir] == nil {
+				m.votes[dir] = make(map[string]int)
+			}
+			m.votes[dir][doc]++
+			m.candidates[doc] = true
+		}
+	}
+	m.indexDocs()
+	m.imports = importGraph(scanResult.AllFiles)
+	return m
+}
+
+// indexDocs records which files each doc names by path, and the code spans it
+// uses, for the mention signal. Code blocks don't count.
+func (m *ownerModel) indexDocs() {
+	p := docparse.New(m.scan.AllFiles, registry.AllExtensions())
+	for _, doc := range m.scan.DocFiles {
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(doc)))
+		if err != nil {
+			continue
+		}
+		m.candidates[doc] = true
+		content = formats.Prose(doc, content)
+		for _, ref := range p.Parse(content) {
+			m.pathRefs[ref.Path] = appendUnique(m.pathRefs[ref.Path], doc)
+		}
+		for _, span := range docparse.CodeSpans(content) {
+			text := docparse.SpanIdentifier(span.Text)
+			m.spans[text] = appendUnique(m.spans[text], doc)
+		}
+	}
+}
+
+// fileSuggestion is the best owner found for one orphaned file.
+type fileSuggestion struct {
+	File       string   `json:"file"`
+	Doc        string   `json:"-"`
+	Confidence float64  `json:"confidence"`
+	Reasons    []string `json:"reasons"`
+}
+
+// guess returns the most likely owning doc for file, or ok=false when no
+// doc reaches the model's minimum confidence. A single faint signal, like a
+// doc mentioning a common file name, isn't enough.
+func (m *ownerModel) guess(file string) (fileSuggestion, bool) {
+	evidence := make(map[string][]ownerEvidence)
+	add := func(doc string, e ownerEvidence) {
+		if doc != file && e.strength > 0 {
+			evidence[doc] = append(evidence[doc], e)
+		}
+	}
+	m.directory(file, add)
+	m.mentions(file, add)
+	m.neighbors(file, add)
+	m.coChanges(file, add)
+
+	best := fileSuggestion{File: file}
+	for doc, es := range evidence {
+		doubt := 1.0
+		for _, e := range es {
+			doubt *= 1 - e.weight*min(e.strength, 1)
+		}
+		conf := math.Round((1-doubt)*100) / 100
+		if conf > best.Confidence || (conf == best.Confidence && best.Doc != "" && doc < best.Doc) {
+			best.Doc, best.Confidence = doc, conf
+		}
+	}
+	if best.Doc == "" || best.Confidence < m.minConf {
+		return best, false
+	}
+	es := evidence[best.Doc]
+	sort.SliceStable(es, func(i, j int) bool { return es[i].weight*es[i].strength > es[j].weight*es[j].strength })
+	for _, e := range es {
+		best.Reasons = append(best.Reasons, e.reason)
+	}
+	return best, true
+}
+
+// directory is the original heuristic: the nearest ancestor directory with
+// annotated files votes for the docs they link, weaker the further up it is.
+func (m *ownerModel) directory(file string, add func(string, ownerEvidence)) {
+	dir, levels, docs := directoryVotes(file, m.votes)
+	if docs == nil {
+		return
+	}
+	where := "in " + dir + "/"
+	if levels > 0 {
+		where = "in ancestor " + dir + "/"
+	}
+	if dir == "." {
+		where = "at the top level"
+	}
+	total := m.dirFiles[dir]
+	for doc, n := range docs {
+		add(doc, ownerEvidence{
+			weight:   weightDirectory,
+			strength: float64(n) / float64(total) * math.Pow(0.5, float64(levels)),
+			reason:   fmt.Sprintf("%d of %d annotated files %s link it", n, total, where),
+		})
+	}
+}
+
+// directoryVotes walks up file's directories to the nearest one with votes,
+// returning it, how many levels up it is, and its votes.
+func directoryVotes(file string, votes map[string]map[string]int) (string, int, map[string]int) {
+	dir := path.Dir(file)
+	for levels := 0; ; levels++ {
+		if docs := votes[dir]; docs != nil {
+			return dir, levels, docs
+		}
+		parent := path.Dir(dir)
+		if parent == dir || dir == "." {
+			return "", 0, nil
+		}
+		dir = parent
+	}
+}
+
+// mentions credits docs that name file's path, its base name, or symbols it
+// declares according to the scan's symbol index.
+func (m *ownerModel) mentions(file string, add func(string, ownerEvidence)) {
+	for _, doc := range m.pathRefs[file] {
+		add(doc, ownerEvidence{weightMention, 1, "mentioned by path in " + doc})
+	}
+	base := path.Base(file)
+	for _, doc := range m.spans[base] {
+		add(doc, ownerEvidence{weightMention, 0.6, fmt.Sprintf("%s mentions `%s`", doc, base)})
+	}
+
+	byDoc := make(map[string][]string)
+	for _, sym := range m.scan.Symbols.Declared(file) {
+		for _, doc := range m.spans[sym] {
+			byDoc[doc] = append(byDoc[doc], "`"+sym+"`")
+		}
+	}
+	for doc, syms := range byDoc {
+		add(doc, ownerEvidence{weightMention, min(0.8, 0.4*float64(len(syms))),
+			fmt.Sprintf("%s mentions %s", doc, strings.Join(limitList(syms, 3), ", "))})
+	}
+}
+
+// neighbors credits docs linked by annotated files that file imports or is
+// imported by.
+func (m *ownerModel) neighbors(file string, add func(string, ownerEvidence)) {
+	var annotated []string
+	for n := range m.imports[file] {
+		if m.scan.Annotations[n] != nil {
+			annotated = append(annotated, n)
+		}
+	}
+	sort.Strings(annotated)
+	byDoc := make(map[string][]string)
+	for _, n := range annotated {
+		for _, doc := range m.scan.Annotations[n].DocPaths {
+			byDoc[doc] = append(byDoc[doc], n)
+		}
+	}
+	for doc, files := range byDoc {
+		add(doc, ownerEvidence{
+			weight:   weightImport,
+			strength: float64(len(files)) / float64(len(annotated)),
+			reason:   fmt.Sprintf("%d of %d annotated import neighbors link it (%s)", len(files), len(annotated), strings.Join(limitList(files, 2), ", ")),
+		})
+	}
+}
+
+// coChanges credits docs committed together with file, directly or through
+// annotated files that link them. A short history counts for less, and a file
+// committed only once has none.
+func (m *ownerModel) coChanges(file string, add func(string, ownerEvidence)) {
+	if m.history == nil {
+		return
+	}
+	commits, with := m.history.CoChanges(file, coChangeMaxFiles)
+	if commits < 2 {
+		return // the commit that added it says nothing yet
+	}
+	type partner struct {
+		count int
+		via   string
+	}
+	best := make(map[string]partner)
+	note := func(doc string, count int, via string) {
+		if p, ok := best[doc]; !ok || count > p.count || (count == p.count && via < p.via) {
+			best[doc] = partner{count, via}
+		}
+	}
+	for other, count := range with {
+		if m.candidates[other] {
+			note(other, count, "")
+		}
+		if ann := m.scan.Annotations[other]; ann != nil {
+			for _, doc := range ann.DocPaths {
+				note(doc, count, other)
+			}
+		}
+	}
+	damp := min(1, float64(commits)/3)
+	for doc, p := range best {
+		reason := fmt.Sprintf("committed with it in %d of %d commits", p.count, commits)
+		if p.via != "" {
+			reason = fmt.Sprintf("committed with %s (links it) in %d of %d commits", p.via, p.count, commits)
+		}
+		add(doc, ownerEvidence{weightCoChange, float64(p.count) / float64(commits) * damp, reason})
+	}
+}
+
+var (
+	goImportModule = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	jsImport       = regexp.MustCompile(`(?:from\s+|require\(\s*|import\s*\(\s*|import\s+)['"](\.{1,2}/[^'"]+)['"]`)
+	pythonImport   = regexp.MustCompile(`(?m)^\s*(?:from\s+(\.*)([\w.]*)\s+import\s+([\w, ]+)|import\s+([\w.]+))`)
+	jsExtensions   = []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue"}
+)
+
+// importGraph links each Go, JS/TS and Python file to the repo files it
+// imports, in both directions. Imports that leave the repo are ignored.
+func importGraph(files []string) map[string]map[string]bool {
+	known := make(map[string]bool, len(files))
+	goDirs := make(map[string][]string)
+	for _, f := range files {
+		known[f] = true
+		if path.Ext(f) == ".go" {
+			goDirs[path.Dir(f)] = append(goDirs[path.Dir(f)], f)
+		}
+	}
+	module := ""
+	if data, err := os.ReadFile(filepath.Join(rootDir, "go.mod")); err == nil {
+		if m := goImportModule.FindSubmatch(data); m != nil {
+			module = string(m[1])
+		}
+	}
+
+	graph := make(map[string]map[string]bool)
+	link := func(a, b string) {
+		if a == b {
+			return
+		}
+		for _, pair := range [][2]string{{a, b}, {b, a}} {
+			if graph[pair[0]] == nil {
+				graph[pair[0]] = make(map[string]bool)
+			}
+			graph[pair[0]][pair[1]] = true
+		}
+	}
+
+	for _, f := range files {
+		ext := strings.ToLower(path.Ext(f))
+		if ext != ".go" && ext != ".py" && !containsExt(jsExtensions, ext) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(f)))
+		if err != nil {
+			continue
+		}
+		switch {
+		case ext == ".go":
+			if module == "" {
+				continue
+			}
+			parsed, err := parser.ParseFile(token.NewFileSet(), f, content, parser.ImportsOnly)
+			if err != nil {
+				continue
+			}
+			for _, imp := range parsed.Imports {
+				p, _ := strconv.Unquote(imp.Path.Value)
+				if dir, ok := strings.CutPrefix(p, module+"/"); ok {
+					for _, target := range goDirs[dir] {
+						link(f, target)
+					}
+				}
+			}
+		case ext == ".py":
+			for _, m := range pythonImport.FindAllStringSubmatch(string(content), -1) {
+				for _, target := range resolvePythonImport(f, m, known) {
+					link(f, target)
+				}
+			}
+		default:
+			for _, m := range jsImport.FindAllStringSubmatch(string(content), -1) {
+				if target, ok := resolveJSImport(f, m[1], known); ok {
+					link(f, target)
+				}
+			}
+		}
+	}
+	return graph
+}
+
+func resolveJSImport(from, spec string, known map[string]bool) (string, bool) {
+	base := path.Join(path.Dir(from), spec)
+	if known[base] {
+		return base, true
+	}
+	for _, ext := range jsExtensions {
+		for _, candidate := range []string{base + ext, base + "/index" + ext} {
+			if known[candidate] {
+				return candidate, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolvePythonImport maps `from .pkg import x` / `import a.b` to repo files,
+// trying the module itself and, for from-imports, each imported name as a
+// submodule.
+func resolvePythonImport(from string, m []string, known map[string]bool) []string {
+	dots, module, names := m[1], m[2], m[3]
+	if m[4] != "" {
+		module = m[4]
+	}
+	var bases []string
+	if dots != "" {
+		dir := path.Dir(from)
+		for range len(dots) - 1 {
+			dir = path.Dir(dir)
+		}
+		bases = []string{dir}
+	} else {
+		bases = []string{".", "src"}
+	}
+
+	var modules []string
+	if module != "" {
+		modules = append(modules, strings.ReplaceAll(module, ".", "/"))
+	}
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			modules = append(modules, path.Join(strings.ReplaceAll(module, ".", "/"), name))
+		}
+	}
+
+	var out []string
+	for _, base := range bases {
+		for _, mod := range modules {
+			for _, candidate := range []string{path.Join(base, mod) + ".py", path.Join(base, mod, "__init__.py")} {
+				if known[candidate] {
+					out = appendUnique(out, candidate)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func containsExt(exts []string, ext string) bool {
+	for _, e := range exts {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func limitList(list []string, n int) []string {
+	if len(list) <= n {
+		return list
+	}
+	return append(append([]string{}, list[:n]...), fmt.Sprintf("%d more", len(list)-n))
+}
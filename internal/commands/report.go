@@ -9,15 +9,15 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/report"
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 var (
-	ErrStaleDocsFound        = errors.New("stale documentation found")
-	ErrOrphanedFilesFound    = errors.New("orphaned files found")
-	ErrUndocumentedRefsFound = errors.New("undocumented references found")
+	ErrStaleDocsFound         = errors.New("stale documentation found")
+	ErrOrphanedFilesFound     = errors.New("orphaned files found")
+	ErrUndocumentedRefsFound  = errors.New("undocumented references found")
+	ErrBrokenAnnotationsFound = errors.New("annotations link to docs that do not exist")
 )
 
 var (
@@ -29,6 +29,7 @@ var (
 	reportCI           bool
 	reportDepth        int
 	reportNoBacklinks  bool
+	reportNoDeadRefs   bool
 )
 
 var reportCmd = &cobra.Command{
@@ -36,8 +37,8 @@ var reportCmd = &cobra.Command{
 	Short: "Show documentation coverage and staleness report",
 	Long: `Show a report of documentation coverage and staleness.
 
-By default, shows a full report including stale docs, coverage by doc file,
-orphaned files, and summary statistics.`,
+By default, shows a full report including stale docs, dead references in docs,
+coverage by doc file, orphaned files, and summary statistics.`,
 	RunE: runReport,
 }
 
@@ -49,34 +50,49 @@ func init() {
 	reportCmd.Flags().BoolVar(&reportSARIF, "sarif", false, "output as SARIF for CI integration")
 	reportCmd.Flags().BoolVar(&reportCI, "ci", false, "enable CI mode (exit 1 on stale docs)")
 	reportCmd.Flags().BoolVar(&reportNoBacklinks, "no-backlinks", false, "hide missing back-link suggestions")
+	reportCmd.Flags().BoolVar(&reportNoDeadRefs, "no-dead-refs", false, "skip checking docs for removed files and symbols")
 	reportCmd.Flags().IntVar(&reportDepth, "depth", 1, "directory depth for coverage breakdown (0 = disable)")
 	rootCmd.AddCommand(reportCmd)
 }
 
 func runReport(cmd *cobra.Command, args []string) error {
+	// Dead references need the symbol index and reads at each doc's baseline,
+	// so they're computed only when the output shows them.
+	deadRefs := !reportNoDeadRefs && (reportJSON || reportSARIF || !(reportStale || reportOrphaned || reportUndocumented))
 	s := scanner.New(cfg, registry)
+	if deadRefs {
+		s.IndexSymbols()
+	}
 	scanResult, err := s.Scan(rootDir)
 	if err != nil {
 		return fmt.Errorf("scan failed: %w", err)
 	}
 
-	g := git.New(rootDir)
-	staleDocs := computeStaleDocs(g, scanResult.FilesByDoc, cmd.ErrOrStderr())
+	g := openRepo()
+	staleDocs := computeStaleDocs(g, scanResult, cmd.ErrOrStderr())
 
 	rpt := report.NewReport()
 	rpt.StaleDocs = staleDocs
 	rpt.FilesByDoc = scanResult.FilesByDoc
-	rpt.OrphanedFiles = scanResult.OrphanedFiles()
+	orphaned := scanResult.OrphanedFiles()
+	rpt.OrphanedFiles = orphaned
 	if !reportNoBacklinks {
 		rpt.UndocumentedRefs = scanResult.UndocumentedRefs
 	}
-	rpt.CalculateSummary(len(scanResult.AllFiles), len(scanResult.Annotations))
+	rpt.BrokenAnnotations = scanResult.BrokenAnnotations
+	if deadRefs {
+		rpt.DeadRefs = computeDeadRefs(g, s, scanResult, cmd.ErrOrStderr())
+	}
+	rpt.CalculateSummary(len(scanResult.AllFiles), len(scanResult.AllFiles)-len(orphaned))
 
 	if reportDepth > 0 {
 		documentedFiles := make(map[string]bool)
-		for file := range scanResult.Annotations {
+		for _, file := range scanResult.AllFiles {
 			documentedFiles[file] = true
 		}
+		for _, file := range orphaned {
+			delete(documentedFiles, file)
+		}
 		rpt.CalculateDirectoryCoverage(scanResult.AllFiles, documentedFiles, reportDepth)
 	}
 
@@ -108,6 +124,9 @@ func runReport(cmd *cobra.Command, args []string) error {
 		if cfg.CI.FailOnStale && len(staleDocs) > 0 {
 			return ErrStaleDocsFound
 		}
+		if cfg.CI.FailOnBrokenAnnotations && len(rpt.BrokenAnnotations) > 0 {
+			return ErrBrokenAnnotationsFound
+		}
 		if cfg.CI.FailOnOrphaned && len(rpt.OrphanedFiles) > 0 {
 			return ErrOrphanedFilesFound
 		}
@@ -1,18 +1,23 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docformat"
+	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/language"
 )
 
 var (
-	rootDir  string
-	cfg      *config.Config
-	registry *language.Registry
+	rootDir    string
+	gitBackend string
+	cfg        *config.Config
+	registry   *language.Registry
+	formats    = docformat.DefaultRegistry() // doc formats aren't configurable
 )
 
 var rootCmd = &cobra.Command{
@@ -39,8 +44,17 @@ Example: // @doc docs/API.md`,
 		if err != nil {
 			return err
 		}
+		if gitBackend != "" {
+			if err := config.ValidateGitBackend(gitBackend); err != nil {
+				return fmt.Errorf("--git-backend: %w", err)
+			}
+			cfg.GitBackend = gitBackend
+		}
 
-		registry = language.DefaultRegistry()
+		registry, err = language.FromConfig(cfg.Languages)
+		if err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
 
 		return nil
 	},
@@ -48,6 +62,17 @@ Example: // @doc docs/API.md`,
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&rootDir, "dir", "", "project root directory (default: current directory)")
+	rootCmd.PersistentFlags().StringVar(&gitBackend, "git-backend", "", "git implementation: exec or go-git (default: git_backend from config, else exec)")
+}
+
+// openRepo opens rootDir with the configured git backend. The backend name was
+// validated when the config loaded.
+func openRepo() git.Repo {
+	g, err := git.Open(rootDir, cfg.GitBackend)
+	if err != nil {
+		return git.New(rootDir)
+	}
+	return g
 }
 
 func Execute() error {
@@ -10,7 +10,7 @@ import (
 
 // fileHitsDoc reports whether changed file (via ann) should flag doc, honoring
 // scoped annotations. An unscoped `@doc` = whole-file ownership: any change
-// hits. A scoped `@doc X #s` owns [its line, next annotation) and is hit only
+// hits. A scoped `@doc X #s` owns its region (see ownedRegion) and is hit only
 // when a changed hunk overlaps that region. When hunk info is unavailable — an
 // untracked/new file, or --files mode — it falls back to whole-file so a real
 // change is never missed.
@@ -36,7 +36,7 @@ func fileHitsDoc(ann *scanner.Annotation, doc string, hunks map[string][]git.Lin
 		return true // no hunk info; don't narrow
 	}
 	for _, s := range scoped {
-		start, end := ownedRegion(ann.Details, s.Line)
+		start, end := regionOf(ann.Details, s)
 		for _, h := range ranges {
 			if h.Start <= end && h.End >= start {
 				return true
@@ -49,14 +49,225 @@ func fileHitsDoc(ann *scanner.Annotation, doc string, hunks map[string][]git.Lin
 const eof = 1 << 30
 
 // ownedRegion is the inclusive [start,end] line region an annotation at `line`
-// owns: from its line to just before the next annotation in the file (any doc),
-// or EOF for the last annotation.
+// owns: from its line to just before the next annotation in the file (any doc;
+// regions declared by mapping links don't count), or EOF for the last one.
+// Under declaration ownership an annotation directly above a declaration owns
+// through the end of that declaration instead, even past a nested annotation.
 func ownedRegion(details []language.DocAnnotation, line int) (int, int) {
 	end := eof
 	for _, d := range details {
+		if d.Declared {
+			continue
+		}
+		if d.Line == line && d.End > 0 {
+			return line, d.End
+		}
 		if d.Line > line && d.Line-1 < end {
 			end = d.Line - 1
 		}
 	}
 	return line, end
 }
+
+// regionOf is the region d owns among details: exactly its lines for a
+// region a mapping link declares, otherwise its ownedRegion.
+func regionOf(details []language.DocAnnotation, d language.DocAnnotation) (int, int) {
+	if d.Declared {
+		return d.Line, d.End
+	}
+	return ownedRegion(details, d.Line)
+}
+
+// scopedFor reports whether every annotation ann has for doc is scoped, i.e.
+// the file only owes doc for part of its lines. Whole-file and unannotated
+// files are never narrowed, so callers can skip the hunk lookups for them.
+func scopedFor(ann *scanner.Annotation, doc string) bool {
+	if ann == nil {
+		return false
+	}
+	scoped := false
+	for _, d := range ann.Details {
+		if d.Path != doc {
+			continue
+		}
+		if d.Scope == "" {
+			return false
+		}
+		scoped = true
+	}
+	return scoped
+}
+
+// mapAnnotationBack returns a copy of ann with each annotation line translated
+// through hunks to the older side of that diff, so owned regions line up with
+// hunks recorded against an older revision.
+func mapAnnotationBack(ann *scanner.Annotation, hunks []git.Hunk) *scanner.Annotation {
+	if ann == nil || len(hunks) == 0 {
+		return ann
+	}
+	mapped := *ann
+	mapped.Details = make([]language.DocAnnotation, len(ann.Details))
+	for i, d := range ann.Details {
+		d.Line = git.MapToOld(hunks, d.Line)
+		if d.End > 0 {
+			d.End = git.MapEndToOld(hunks, d.End)
+		}
+		mapped.Details[i] = d
+	}
+	return &mapped
+}
+
+// docScope applies scoped ownership to committed history for one doc. Owned
+// regions are computed on the scanned (working-tree) files, so before comparing
+// them with a commit's hunks they are mapped back through every later change:
+// working tree -> HEAD -> the commit. Comparing raw line numbers would drift as
+// soon as lines were inserted or removed above a region.
+type docScope struct {
+	g    git.Repo
+	doc  string
+	anns map[string]*scanner.Annotation
+	head map[string]*scanner.Annotation // scoped annotations mapped to HEAD lines
+}
+
+func newDocScope(g git.Repo, doc string, anns map[string]*scanner.Annotation) *docScope {
+	return &docScope{g: g, doc: doc, anns: anns}
+}
+
+func (s *docScope) scopedFiles(files []string) []string {
+	var scoped []string
+	for _, f := range files {
+		if scopedFor(s.anns[f], s.doc) {
+			scoped = append(scoped, f)
+		}
+	}
+	return scoped
+}
+
+// headAnnotations maps the scoped files' annotations from the working tree to
+// HEAD, once per file.
+func (s *docScope) headAnnotations(files []string) map[string]*scanner.Annotation {
+	if s.head == nil {
+		s.head = make(map[string]*scanner.Annotation)
+	}
+	var missing []string
+	for _, f := range files {
+		if _, ok := s.head[f]; !ok {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		dirty, err := s.g.HunksSince("HEAD", false, missing)
+		if err != nil {
+			dirty = nil // best-effort: a failed lookup leaves lines unmapped
+		}
+		for _, f := range missing {
+			s.head[f] = mapAnnotationBack(s.anns[f], dirty[f])
+		}
+	}
+	return s.head
+}
+
+// committedFiles narrows files changed in fromHash..HEAD to those whose owned
+// region for the doc was touched. Lookup failures keep the file, so a real
+// change is never hidden.
+func (s *docScope) committedFiles(fromHash string, changed []string) []string {
+	scoped := s.scopedFiles(changed)
+	if len(scoped) == 0 {
+		return changed
+	}
+	hunks, err := s.g.ChangedHunksBetween(fromHash, "HEAD", scoped)
+	if err != nil {
+		return changed
+	}
+	head := s.headAnnotations(scoped)
+	return s.keep(changed, func(f string) bool { return fileHitsDoc(head[f], s.doc, hunks) })
+}
+
+// worktreeFiles narrows files changed between fromHash and the working tree
+// (or index when staged). The hunks are already in working-tree lines.
+func (s *docScope) worktreeFiles(fromHash string, staged bool, changed []string) []string {
+	scoped := s.scopedFiles(changed)
+	if len(scoped) == 0 {
+		return changed
+	}
+	hunks, err := s.g.ChangedHunksSince(fromHash, staged, scoped)
+	if err != nil {
+		return changed
+	}
+	return s.keep(changed, func(f string) bool { return fileHitsDoc(s.anns[f], s.doc, hunks) })
+}
+
+// commitAnnotations maps the scoped files' annotations to the line numbers of
+// commit hash, for matching that commit's own hunks.
+func (s *docScope) commitAnnotations(hash string, files []string) map[string]*scanner.Annotation {
+	head := s.headAnnotations(files)
+	toHead, err := s.g.HunksBetween(hash, "HEAD", files)
+	if err != nil {
+		toHead = nil
+	}
+	out := make(map[string]*scanner.Annotation, len(files))
+	for _, f := range files {
+		out[f] = mapAnnotationBack(head[f], toHead[f])
+	}
+	return out
+}
+
+// commitFiles narrows the linked files a single commit touched to those whose
+// owned region it changed.
+func (s *docScope) commitFiles(hash string, touched []string) []string {
+	scoped := s.scopedFiles(touched)
+	if len(scoped) == 0 {
+		return touched
+	}
+	hunks, err := s.g.CommitHunks(hash, scoped)
+	if err != nil {
+		return touched
+	}
+	ranges := make(map[string][]git.LineRange, len(hunks))
+	for f, hs := range hunks {
+		for _, h := range hs {
+			ranges[f] = append(ranges[f], h.NewRange())
+		}
+	}
+	anns := s.commitAnnotations(hash, scoped)
+	return s.keep(touched, func(f string) bool { return fileHitsDoc(anns[f], s.doc, ranges) })
+}
+
+// commits returns the commits in fromHash..HEAD (newest first) that changed an
+// owned region of any linked file.
+func (s *docScope) commits(fromHash string, files []string) []git.CommitDetail {
+	details, _ := s.g.CommitDetails(fromHash, "HEAD", files)
+	if len(s.scopedFiles(files)) == 0 {
+		return details
+	}
+	kept := make([]git.CommitDetail, 0, len(details))
+	for _, c := range details {
+		touched, err := s.g.FilesChangedInCommit(c.Hash, files)
+		if err != nil || len(touched) == 0 || len(s.commitFiles(c.Hash, touched)) > 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// hunkFilter returns a predicate for filterDiffHunks that drops hunks outside
+// the doc's owned regions. anns must be in the diff's new-side line numbers.
+func (s *docScope) hunkFilter(anns map[string]*scanner.Annotation) func(string, git.LineRange) bool {
+	return func(file string, r git.LineRange) bool {
+		ann, ok := anns[file]
+		if !ok {
+			return true
+		}
+		return fileHitsDoc(ann, s.doc, map[string][]git.LineRange{file: {r}})
+	}
+}
+
+func (s *docScope) keep(files []string, hit func(string) bool) []string {
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		if !scopedFor(s.anns[f], s.doc) || hit(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
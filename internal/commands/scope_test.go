@@ -49,4 +49,39 @@ func TestOwnedRegion(t *testing.T) {
 	if s, e := ownedRegion(details, 5); s != 5 || e != eof {
 		t.Errorf("region for last line 5 = [%d,%d], want [5,eof]", s, e)
 	}
+
+	// A declaration extent wins over the next annotation in either direction.
+	details = []language.DocAnnotation{{Line: 2, End: 3}, {Line: 5, End: 9}, {Line: 7}}
+	if s, e := ownedRegion(details, 2); s != 2 || e != 3 {
+		t.Errorf("declaration region for line 2 = [%d,%d], want [2,3]", s, e)
+	}
+	if s, e := ownedRegion(details, 5); s != 5 || e != 9 {
+		t.Errorf("declaration region for line 5 = [%d,%d], want [5,9]", s, e)
+	}
+}
+
+func TestMapAnnotationBack(t *testing.T) {
+	ann := &scanner.Annotation{
+		FilePath: "central.go",
+		Details: []language.DocAnnotation{
+			{Path: "docs/GENERAL.md", Scope: "general", Line: 5},
+			{Path: "docs/MOBILE.md", Scope: "mobile", Line: 7},
+		},
+	}
+	// Three lines were inserted after line 1 since the older revision.
+	mapped := mapAnnotationBack(ann, []git.Hunk{{OldStart: 1, OldCount: 0, NewStart: 2, NewCount: 3}})
+	if mapped.Details[0].Line != 2 || mapped.Details[1].Line != 4 {
+		t.Errorf("mapped lines = %d,%d, want 2,4", mapped.Details[0].Line, mapped.Details[1].Line)
+	}
+	// A declaration's last line maps to the end of the block it falls in:
+	// old line 6 became new lines 6-8.
+	withEnd := mapAnnotationBack(&scanner.Annotation{
+		Details: []language.DocAnnotation{{Path: "docs/GENERAL.md", Scope: "general", Line: 5, End: 7}},
+	}, []git.Hunk{{OldStart: 6, OldCount: 1, NewStart: 6, NewCount: 3}})
+	if d := withEnd.Details[0]; d.Line != 5 || d.End != 6 {
+		t.Errorf("mapped region = [%d,%d], want [5,6]", d.Line, d.End)
+	}
+	if ann.Details[0].Line != 5 {
+		t.Error("mapAnnotationBack must not modify the original annotation")
+	}
 }
@@ -0,0 +1,254 @@
+package commands
+
+// @doc README.md
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+// sectionFeed is one heading of a doc and the code that feeds it.
+type sectionFeed struct {
+	heading docparse.Heading
+	target  string // what `changes` takes: the anchor target, or the doc for scope-only feeds
+	links   []sectionLink
+}
+
+// sectionLink is one code region feeding a section: a file linked through the
+// heading's anchor (docs/API.md#auth), or a scoped annotation on the whole doc
+// whose scope names the heading (docs/API.md with scope #auth).
+type sectionLink struct {
+	file     string
+	via      string
+	line     int // annotation line for a scoped region; 0 = whole file
+	start    int // owned region in working-tree lines
+	end      int
+	baseline string // review anchor the region is compared against
+	changed  bool   // committed change to the region since baseline
+}
+
+func (f sectionFeed) stale() bool {
+	for _, l := range f.links {
+		if l.changed {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionFeeds collects the headings of doc that have linked code, and how
+// many headings have none. Section targets and anchorless docs that can't be
+// read yield nothing.
+func sectionFeeds(g git.Repo, scanResult *scanner.Result, doc string, acks map[string]string) ([]sectionFeed, int) {
+	if _, anchor := docparse.SplitAnchor(doc); anchor != "" {
+		return nil, 0
+	}
+	content, err := os.ReadFile(filepath.Join(rootDir, doc))
+	if err != nil {
+		return nil, 0
+	}
+
+	anchored := make(map[string]string) // lowercased anchor -> target
+	for target := range scanResult.FilesByDoc {
+		if path, anchor := docparse.SplitAnchor(target); path == doc && anchor != "" {
+			anchored[strings.ToLower(anchor)] = target
+		}
+	}
+
+	var docBaseline string
+	if len(scanResult.FilesByDoc[doc]) > 0 {
+		if b, err := baselineForDoc(g, doc, acks); err == nil {
+			docBaseline = b.Effective
+		}
+	}
+
+	var feeds []sectionFeed
+	unlinked := 0
+	for _, h := range formats.Headings(doc, content) {
+		key := strings.ToLower(h.Anchor)
+		feed := sectionFeed{heading: h, target: doc}
+		if target, ok := anchored[key]; ok {
+			feed.target = target
+			feed.links = append(feed.links, anchorLinks(g, scanResult, target, acks)...)
+		}
+		feed.links = append(feed.links, scopeLinks(g, scanResult, doc, key, docBaseline)...)
+		if len(feed.links) == 0 {
+			unlinked++
+			continue
+		}
+		feeds = append(feeds, feed)
+	}
+	return feeds, unlinked
+}
+
+// anchorLinks lists the files linked to a section target, compared against the
+// section's own baseline.
+func anchorLinks(g git.Repo, scanResult *scanner.Result, target string, acks map[string]string) []sectionLink {
+	files := append([]string(nil), scanResult.FilesByDoc[target]...)
+	sort.Strings(files)
+
+	var baseline string
+	if b, err := baselineForDoc(g, target, acks); err == nil {
+		baseline = b.Effective
+	}
+	changed := map[string]bool{}
+	if baseline != "" {
+		drift, _ := committedDrift(g, target, baseline, files, scanResult.Ownership())
+		for _, f := range drift {
+			changed[f] = true
+		}
+	}
+
+	var links []sectionLink
+	for _, f := range files {
+		ann := scanResult.Ownership()[f]
+		if !scopedFor(ann, target) {
+			links = append(links, sectionLink{file: f, via: "anchor", baseline: baseline, changed: changed[f]})
+			continue
+		}
+		for _, d := range ann.Details {
+			if d.Path != target {
+				continue
+			}
+			start, end := regionOf(ann.Details, d)
+			links = append(links, sectionLink{
+				file: f, via: "anchor, scoped #" + d.Scope, line: d.Line, start: start, end: end,
+				baseline: baseline, changed: changed[f],
+			})
+		}
+	}
+	return links
+}
+
+// scopeLinks lists scoped annotations on the whole doc whose scope names the
+// heading, each compared against the doc's baseline on its own region only.
+func scopeLinks(g git.Repo, scanResult *scanner.Result, doc, anchor, baseline string) []sectionLink {
+	files := append([]string(nil), scanResult.FilesByDoc[doc]...)
+	sort.Strings(files)
+
+	var links []sectionLink
+	for _, f := range files {
+		ann := scanResult.Ownership()[f]
+		if !scopedFor(ann, doc) {
+			continue // whole-file links feed the whole doc, not one section
+		}
+		for _, d := range ann.Details {
+			if d.Path != doc || (strings.ToLower(d.Scope) != anchor && docparse.Slug(d.Scope) != anchor) {
+				continue
+			}
+			changed := false
+			if baseline != "" {
+				only := map[string]*scanner.Annotation{f: onlyScope(ann, doc, d.Line)}
+				drift, _ := committedDrift(g, doc, baseline, []string{f}, only)
+				changed = len(drift) > 0
+			}
+			start, end := regionOf(ann.Details, d)
+			links = append(links, sectionLink{
+				file: f, via: "scoped #" + d.Scope, line: d.Line, start: start, end: end,
+				baseline: baseline, changed: changed,
+			})
+		}
+	}
+	return links
+}
+
+// onlyScope returns a copy of ann where only the annotation on `line` still
+// links to doc. The others keep their lines, so they still bound regions.
+func onlyScope(ann *scanner.Annotation, doc string, line int) *scanner.Annotation {
+	only := *ann
+	only.Details = append(only.Details[:0:0], ann.Details...)
+	for i, d := range only.Details {
+		if d.Path == doc && d.Line != line {
+			only.Details[i].Path = ""
+		}
+	}
+	return &only
+}
+
+func writeSectionBreakdown(out io.Writer, g git.Repo, scanResult *scanner.Result, feeds []sectionFeed, unlinked int) {
+	fmt.Fprintf(out, "\nSections (%d of %d headings have linked code):\n", len(feeds), len(feeds)+unlinked)
+	for _, feed := range feeds {
+		h := feed.heading
+		fmt.Fprintf(out, "\n  %s %s  (#%s, lines %d-%d)\n", strings.Repeat("#", h.Level), h.Title, h.Anchor, h.Line, h.End)
+		for _, l := range feed.links {
+			region := l.file
+			if l.line > 0 {
+				end := "EOF"
+				if l.end != eof {
+					end = fmt.Sprint(l.end)
+				}
+				region = fmt.Sprintf("%s:%d-%s", l.file, l.start, end)
+			}
+			fmt.Fprintf(out, "    %s (%s)\n", region, l.via)
+			fmt.Fprintf(out, "      last change: %s\n", lastRegionChange(g, scanResult, feed.target, l))
+			switch {
+			case l.baseline == "":
+				fmt.Fprintln(out, "      no baseline (section never committed, no ack)")
+			case l.changed:
+				fmt.Fprintf(out, "      changed since %s\n", l.baseline)
+			default:
+				fmt.Fprintf(out, "      unchanged since %s\n", l.baseline)
+			}
+		}
+		if feed.stale() {
+			fmt.Fprintf(out, "    Verdict: STALE — run 'docdiff changes %s' for the diff.\n", feed.target)
+		} else {
+			fmt.Fprintln(out, "    Verdict: up to date")
+		}
+	}
+}
+
+// lastRegionChange describes the newest commit that touched a link's region as
+// it stands at HEAD (the whole file for unscoped links).
+func lastRegionChange(g git.Repo, scanResult *scanner.Result, target string, l sectionLink) string {
+	hash, _ := g.LastCommit(l.file)
+	if l.line > 0 {
+		hash = ""
+		head := newDocScope(g, target, scanResult.Ownership()).headAnnotations([]string{l.file})[l.file]
+		if head != nil {
+			for i, d := range scanResult.Ownership()[l.file].Details {
+				if d.Line != l.line {
+					continue
+				}
+				start, end := regionOf(head.Details, head.Details[i])
+				hash = lastCommitInRegion(g, l.file, start, end)
+				break
+			}
+		}
+	}
+	if hash == "" {
+		return "none (uncommitted)"
+	}
+	info, _ := g.CommitInfo(hash)
+	subject, _ := g.CommitSubject(hash)
+	return info + " " + subject
+}
+
+// lastCommitInRegion resolves an open-ended region against the file at HEAD
+// before asking git for the region's history.
+func lastCommitInRegion(g git.Repo, file string, start, end int) string {
+	content, ok, err := g.FileAt("HEAD", file)
+	if err != nil || !ok {
+		return ""
+	}
+	lines := strings.Count(string(content), "\n")
+	if !strings.HasSuffix(string(content), "\n") {
+		lines++
+	}
+	if end > lines {
+		end = lines
+	}
+	if start > end {
+		return ""
+	}
+	hash, _ := g.LastCommitInRange(file, start, end)
+	return hash
+}
@@ -8,8 +8,10 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/StevenBock/docdiff/internal/docparse"
 	"github.com/StevenBock/docdiff/internal/git"
 	"github.com/StevenBock/docdiff/internal/report"
+	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 type reviewBaseline struct {
@@ -23,19 +25,27 @@ type reviewBaseline struct {
 // computeStaleDocs returns docs whose linked files changed (in committed
 // history) since the doc itself was last committed. Each doc's own last commit
 // is the "reviewed" anchor — editing code and doc together in one commit makes
-// them share that anchor, so nothing is stale. An `ack` floor (.docdiff-acks.json)
-// can move the anchor forward for docs reviewed without an edit. Warnings go to
-// errOut.
-func computeStaleDocs(g *git.Git, filesByDoc map[string][]string, errOut io.Writer) map[string]*report.StaleDoc {
+// them share that anchor, so nothing is stale. A section target
+// (`docs/API.md#auth`) is anchored by the last commit to its own lines. An
+// `ack` floor (.docdiff-acks.json) can move the anchor forward for docs
+// reviewed without an edit. A file linked only by scoped annotations counts
+// only when a hunk since the anchor touched its owned region. History is
+// walked once up front, so per-doc baselines and changed files don't each
+// cost a git process. Warnings go to errOut.
+func computeStaleDocs(g git.Repo, scanResult *scanner.Result, errOut io.Writer) map[string]*report.StaleDoc {
 	stale := make(map[string]*report.StaleDoc)
 
+	if err := g.LoadHistory(); err != nil {
+		fmt.Fprintf(errOut, "Warning: failed to read git history, querying per doc: %v\n", err)
+	}
+
 	acks, err := loadAcks(rootDir)
 	if err != nil {
 		fmt.Fprintf(errOut, "Warning: failed to load %s: %v\n", acksFile, err)
 		acks = map[string]string{}
 	}
 
-	for doc, files := range filesByDoc {
+	for doc, files := range scanResult.FilesByDoc {
 		if len(files) == 0 {
 			continue
 		}
@@ -51,7 +61,7 @@ func computeStaleDocs(g *git.Git, filesByDoc map[string][]string, errOut io.Writ
 			continue // doc not committed and not acked; nothing to compare against
 		}
 
-		changed, err := g.ChangedFilesBetween(lastHash, "HEAD", files)
+		changed, err := committedDrift(g, doc, lastHash, files, scanResult.Ownership())
 		if err != nil {
 			fmt.Fprintf(errOut, "Warning: failed to check changes for %s (%s..HEAD): %v\n", doc, lastHash, err)
 			continue
@@ -72,8 +82,58 @@ func computeStaleDocs(g *git.Git, filesByDoc map[string][]string, errOut io.Writ
 	return stale
 }
 
-func baselineForDoc(g *git.Git, doc string, acks map[string]string) (reviewBaseline, error) {
-	docCommit, err := g.LastCommit(doc)
+// committedDrift returns the linked files with committed changes to doc's owned
+// regions since baseline.
+func committedDrift(g git.Repo, doc, baseline string, files []string, anns map[string]*scanner.Annotation) ([]string, error) {
+	changed, err := g.ChangedFilesBetween(baseline, "HEAD", files)
+	if err != nil {
+		return nil, err
+	}
+	if path, _ := docparse.SplitAnchor(doc); len(changed) > 0 && renamedSinceContent(g, path) {
+		// The baseline predates the rename, so the commit that moved the doc
+		// and repointed its annotations would otherwise count as drift.
+		if changed, err = withoutAnnotationOnly(g, baseline, changed); err != nil {
+			return nil, err
+		}
+	}
+	return newDocScope(g, doc, anns).committedFiles(baseline, changed), nil
+}
+
+// renamedSinceContent reports whether path's last commit only renamed it.
+func renamedSinceContent(g git.Repo, path string) bool {
+	last, err := g.LastCommit(path)
+	if err != nil {
+		return false
+	}
+	content, err := g.LastContentCommit(path)
+	return err == nil && content != last
+}
+
+// withoutAnnotationOnly drops files whose changes since baseline all sit on
+// annotation lines.
+func withoutAnnotationOnly(g git.Repo, baseline string, files []string) ([]string, error) {
+	diff, err := g.Diff(baseline, "HEAD", files)
+	if err != nil {
+		return nil, err
+	}
+	real := make(map[string]bool)
+	filterDiff(diff, func(file string, hunk []string) bool {
+		if !annotationOnlyHunk(hunk, cfg.AnnotationTag) {
+			real[file] = true
+		}
+		return true
+	})
+	var kept []string
+	for _, f := range files {
+		if real[f] {
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+func baselineForDoc(g git.Repo, doc string, acks map[string]string) (reviewBaseline, error) {
+	docCommit, err := lastReviewCommit(g, doc)
 	if err != nil {
 		return reviewBaseline{}, err
 	}
@@ -89,7 +149,28 @@ func baselineForDoc(g *git.Git, doc string, acks map[string]string) (reviewBasel
 	}, nil
 }
 
-func resolveAckFloor(g *git.Git, doc, recorded string) (string, bool) {
+// lastReviewCommit is the doc's own review anchor. For a whole doc that's its
+// last commit that changed content, so a pure rename (`docdiff mv`) keeps the
+// baseline it had under the old name. For a section target (`docs/API.md#authentication`) it's the
+// last commit that touched the lines under that heading at HEAD, so editing
+// another section doesn't mark this one reviewed.
+func lastReviewCommit(g git.Repo, doc string) (string, error) {
+	path, anchor := docparse.SplitAnchor(doc)
+	if anchor == "" {
+		return g.LastContentCommit(path)
+	}
+	content, ok, err := g.FileAt("HEAD", path)
+	if err != nil || !ok {
+		return "", err // uncommitted doc: no anchor yet
+	}
+	h, ok := formats.Section(path, content, anchor)
+	if !ok {
+		return "", fmt.Errorf("%s has no heading with anchor #%s", path, anchor)
+	}
+	return g.LastCommitInRange(path, h.Line, h.End)
+}
+
+func resolveAckFloor(g git.Repo, doc, recorded string) (string, bool) {
 	if recorded == "" {
 		return "", false
 	}
@@ -113,7 +194,7 @@ func ackEntryRegex(doc string) string {
 // effectiveBaseline picks the review anchor: the newer of the doc's own last
 // commit and its ack floor. A missing or unresolvable ack falls back to the
 // doc's commit, so a stale/garbage-collected ack never hides real changes.
-func effectiveBaseline(g *git.Git, docCommit, ackedSha string) string {
+func effectiveBaseline(g git.Repo, docCommit, ackedSha string) string {
 	if ackedSha == "" {
 		return docCommit
 	}
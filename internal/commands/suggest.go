@@ -15,57 +15,80 @@ import (
 	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
-var suggestJSON bool
+var (
+	suggestJSON          bool
+	suggestMinConfidence float64
+)
 
 var suggestCmd = &cobra.Command{
 	Use:   "suggest",
 	Short: "Suggest @doc annotations for orphaned files, grouped by likely owning doc",
-	Long: `Group orphaned files (no @doc annotation) by the documentation file that
-their already-annotated neighbors point to, and emit ready-to-paste annotation
-lines in batches.
-
-The owner is inferred by directory: for each orphaned file, the nearest ancestor
-directory that contains annotated files votes for its most common doc. Files with
-no annotated neighbors are listed separately as unmapped.`,
+	Long: `Group orphaned files (no @doc annotation) by their most likely owning doc, and
+emit ready-to-paste annotation lines in batches, each with a confidence and
+the reasons behind it.
+
+The owner is scored from four signals: the docs annotated files in the nearest
+directory link; docs that mention the file's path, name or declared symbols;
+the docs annotated import neighbors link (Go, JS/TS, Python); and the docs the
+file is committed together with, directly or through annotated files. Files
+whose best doc scores below --min-confidence (suggest.min_confidence, default
+0.35) are listed separately as unmapped.`,
 	RunE: runSuggest,
 }
 
 func init() {
 	suggestCmd.Flags().BoolVar(&suggestJSON, "json", false, "output as JSON")
+	suggestCmd.Flags().Float64Var(&suggestMinConfidence, "min-confidence", 0, "lowest confidence to suggest an owner at (default: suggest.min_confidence, else 0.35)")
 	rootCmd.AddCommand(suggestCmd)
 }
 
 type docSuggestion struct {
-	Doc   string   `json:"doc"`
-	Files []string `json:"files"`
+	Doc     string           `json:"doc"`
+	Files   []string         `json:"files"`
+	Details []fileSuggestion `json:"details"` // per file, in Files order
 }
 
 func runSuggest(cmd *cobra.Command, args []string) error {
 	s := scanner.New(cfg, registry)
+	s.IndexSymbols() // for the mention signal
 	scanResult, err := s.Scan(rootDir)
 	if err != nil {
 		return fmt.Errorf("scan failed: %w", err)
 	}
+	suggestions, unmapped := suggestOwners(scanResult, minConfidence(cmd, suggestMinConfidence))
 
-	// Tally, per directory, which docs the annotated files in it point to.
-	// ponytail: directory-vote heuristic, no content analysis. Upgrade to
-	// import-graph ownership only if path proximity proves too coarse.
-	votes := make(map[string]map[string]int) // dir -> doc -> count
-	for file, ann := range scanResult.Annotations {
-		dir := filepath.ToSlash(filepath.Dir(file))
-		for _, doc := range ann.DocPaths {
-			if votes[dir] == nil {
-				votes[dir] = make(map[string]int)
-			}
-			votes[dir][doc]++
-		}
+	out := cmd.OutOrStdout()
+	if suggestJSON {
+		return writeSuggestJSON(out, suggestions, unmapped)
+	}
+	writeSuggestHuman(out, suggestions, unmapped)
+	return nil
+}
+
+// minConfidence is suggest.min_confidence, or flag when --min-confidence was
+// given on the command line.
+func minConfidence(cmd *cobra.Command, flag float64) float64 {
+	if cmd.Flags().Changed("min-confidence") {
+		return flag
+	}
+	return cfg.Suggest.MinConfidence
+}
+
+// suggestOwners groups the orphaned files by their most likely owning doc
+// (see ownerModel). Files with no doc at minConfidence or above come back
+// unmapped.
+func suggestOwners(scanResult *scanner.Result, minConfidence float64) ([]docSuggestion, []string) {
+	history, err := openRepo().History()
+	if err != nil {
+		history = nil // not a git checkout: no co-change signal
 	}
+	model := newOwnerModel(scanResult, history, minConfidence)
 
-	byDoc := make(map[string][]string)
+	byDoc := make(map[string][]fileSuggestion)
 	var unmapped []string
 	for _, file := range scanResult.OrphanedFiles() {
-		if doc := suggestDoc(file, votes); doc != "" {
-			byDoc[doc] = append(byDoc[doc], file)
+		if guess, ok := model.guess(file); ok {
+			byDoc[guess.Doc] = append(byDoc[guess.Doc], guess)
 		} else {
 			unmapped = append(unmapped, file)
 		}
@@ -79,40 +102,16 @@ func runSuggest(cmd *cobra.Command, args []string) error {
 
 	suggestions := make([]docSuggestion, 0, len(byDoc))
 	for _, doc := range docs {
-		files := byDoc[doc]
-		sort.Strings(files)
-		suggestions = append(suggestions, docSuggestion{Doc: doc, Files: files})
-	}
-	sort.Strings(unmapped)
-
-	out := cmd.OutOrStdout()
-	if suggestJSON {
-		return writeSuggestJSON(out, suggestions, unmapped)
-	}
-	writeSuggestHuman(out, suggestions, unmapped)
-	return nil
-}
-
-// suggestDoc walks up file's directory ancestry and returns the top-voted doc
-// from the nearest ancestor directory that has any votes.
-func suggestDoc(file string, votes map[string]map[string]int) string {
-	dir := filepath.ToSlash(filepath.Dir(file))
-	for {
-		if docs := votes[dir]; docs != nil {
-			best, bestN := "", 0
-			for doc, n := range docs {
-				if n > bestN || (n == bestN && doc < best) {
-					best, bestN = doc, n
-				}
-			}
-			return best
-		}
-		parent := filepath.ToSlash(filepath.Dir(dir))
-		if parent == dir || dir == "." {
-			return ""
+		details := byDoc[doc]
+		sort.Slice(details, func(i, j int) bool { return details[i].File < details[j].File })
+		files := make([]string, len(details))
+		for i, d := range details {
+			files[i] = d.File
 		}
-		dir = parent
+		suggestions = append(suggestions, docSuggestion{Doc: doc, Files: files, Details: details})
 	}
+	sort.Strings(unmapped)
+	return suggestions, unmapped
 }
 
 func writeSuggestHuman(out io.Writer, suggestions []docSuggestion, unmapped []string) {
@@ -128,21 +127,22 @@ func writeSuggestHuman(out io.Writer, suggestions []docSuggestion, unmapped []st
 	fmt.Fprintf(out, "Suggested %s annotations for %d orphaned file(s):\n\n", cfg.AnnotationTag, total)
 	for _, s := range suggestions {
 		fmt.Fprintf(out, "%s (%d files):\n", s.Doc, len(s.Files))
-		for _, f := range s.Files {
-			fmt.Fprintf(out, "  %s %s %s   ->   %s\n", commentToken(f), cfg.AnnotationTag, s.Doc, f)
+		for _, d := range s.Details {
+			fmt.Fprintf(out, "  %s %s %s   ->   %s   (%.0f%%: %s)\n", commentToken(d.File), cfg.AnnotationTag, s.Doc, d.File,
+				d.Confidence*100, strings.Join(d.Reasons, "; "))
 		}
 		fmt.Fprintln(out)
 	}
 
 	if len(unmapped) > 0 {
-		fmt.Fprintf(out, "Unmapped (no annotated neighbor) — %d file(s):\n", len(unmapped))
+		fmt.Fprintf(out, "Unmapped (no confident candidate) — %d file(s):\n", len(unmapped))
 		for _, f := range unmapped {
 			fmt.Fprintf(out, "  %s\n", f)
 		}
 		fmt.Fprintln(out)
 	}
 
-	fmt.Fprintln(out, "Add each annotation to the file's top comment, then commit the source and its doc together.")
+	fmt.Fprintln(out, "Add each annotation to the file's top comment (or run 'docdiff annotate'), then commit the source and its doc together.")
 }
 
 func writeSuggestJSON(out io.Writer, suggestions []docSuggestion, unmapped []string) error {
@@ -1,24 +1,44 @@
 package commands
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
 
-func TestSuggestDoc(t *testing.T) {
-	votes := map[string]map[string]int{
-		"internal/api": {"docs/API.md": 3, "docs/Other.md": 1},
-		"internal/db":  {"docs/DB.md": 2},
+func TestDirectorySignal(t *testing.T) {
+	m := &ownerModel{
+		votes: map[string]map[string]int{
+			"internal/api": {"docs/API.md": 3, "docs/Other.md": 1},
+			"internal/db":  {"docs/DB.md": 2},
+		},
+		dirFiles: map[string]int{"internal/api": 4, "internal/db": 2},
 	}
 
-	cases := map[string]string{
-		"internal/api/new.go":      "docs/API.md", // direct dir, majority wins
-		"internal/api/sub/deep.go": "docs/API.md", // ancestor dir votes
-		"internal/db/conn.go":      "docs/DB.md",
-		"cmd/tool/main.go":         "", // no annotated neighbor
-		"toplevel.go":              "", // no votes at "."
+	cases := map[string]struct {
+		doc      string
+		strength float64
+	}{
+		"internal/api/new.go":      {"docs/API.md", 0.75},  // direct dir, majority wins
+		"internal/api/sub/deep.go": {"docs/API.md", 0.375}, // ancestor dir votes, weaker
+		"internal/db/conn.go":      {"docs/DB.md", 1},
+		"cmd/tool/main.go":         {"", 0}, // no annotated neighbor
+		"toplevel.go":              {"", 0}, // no votes at "."
 	}
 
 	for file, want := range cases {
-		if got := suggestDoc(file, votes); got != want {
-			t.Errorf("suggestDoc(%q) = %q, want %q", file, got, want)
+		best, strength := "", 0.0
+		m.directory(file, func(doc string, e ownerEvidence) {
+			if e.strength > strength {
+				best, strength = doc, e.strength
+			}
+		})
+		if best != want.doc || strength != want.strength {
+			t.Errorf("directory(%q) = %q (%v), want %q (%v)", file, best, strength, want.doc, want.strength)
 		}
 	}
 }
@@ -32,3 +52,191 @@ func TestCommentToken(t *testing.T) {
 		}
 	}
 }
+
+func TestAnnotate(t *testing.T) {
+	dir := setupTestProject(t)
+	os.WriteFile(filepath.Join(dir, "src", "tool.py"), []byte("#!/usr/bin/env python\r\nprint(1)\r\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+	os.WriteFile(filepath.Join(dir, "lib", "guide.go"), []byte("package lib\n\n// @doc docs/GUIDE.md\nfunc G() {}\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "lib", "more.go"), []byte("package lib\n\nfunc M() {}\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "tools"), 0755)
+	os.WriteFile(filepath.Join(dir, "tools", "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "docs", "GUIDE.md"), []byte("# Guide\n\nRun `main.go`.\n"), 0644)
+	initTestEnv(t, dir)
+	read := func(rel string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		return string(data)
+	}
+	run := func(dryRun bool, docs ...string) string {
+		t.Helper()
+		annotateDryRun, annotateDocs = dryRun, docs
+		defer func() { annotateDryRun, annotateDocs = false, nil }()
+		var stdout bytes.Buffer
+		annotateCmd.SetOut(&stdout)
+		if err := annotateCmd.RunE(annotateCmd, nil); err != nil {
+			t.Fatalf("annotate failed: %v", err)
+		}
+		return stdout.String()
+	}
+
+	out := run(true)
+	for _, want := range []string{
+		"--- a/src/util.go\n+++ b/src/util.go\n@@ -1,3 +1,5 @@\n package main\n+\n+// @doc docs/API.md\n \n func Util() {}\n",
+		"--- a/lib/more.go",
+		"Would annotate 3 file(s).",
+		"1 file(s) have no confident doc candidate",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry run output missing %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(read("src/util.go"), "@doc") {
+		t.Fatal("dry run must not change files")
+	}
+	if strings.Contains(out, "tools/main.go") {
+		t.Errorf("a bare file name mention shouldn't be enough to annotate, got:\n%s", out)
+	}
+
+	out = run(false, "./docs/API.md")
+	if !strings.Contains(out, "Annotated 2 file(s):") || !strings.Contains(out, "src/util.go:3  @doc docs/API.md") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+	if got, want := read("src/util.go"), "package main\n\n// @doc docs/API.md\n\nfunc Util() {}\n"; got != want {
+		t.Errorf("src/util.go =\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := read("src/tool.py"), "#!/usr/bin/env python\r\n# @doc docs/API.md\r\nprint(1)\r\n"; got != want {
+		t.Errorf("src/tool.py = %q, want %q", got, want)
+	}
+	if strings.Contains(read("lib/more.go"), "@doc") {
+		t.Error("--doc should limit which suggestions are applied")
+	}
+	if out := run(false, "docs/API.md"); !strings.Contains(out, "No annotations to add.") {
+		t.Errorf("a second run should have nothing left, got:\n%s", out)
+	}
+}
+
+func TestSuggestSignals(t *testing.T) {
+	dir := setupTestProject(t)
+	write := func(rel, content string) {
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(content), 0644)
+	}
+	write("go.mod", "module example.com/app\n\ngo 1.22\n")
+	write("docs/BILLING.md", "# Billing\n\n`InvoiceTotal` sums the `InvoiceLines`.\n")
+	write("docs/DB.md", "# DB\n\nStart from `main.go`.\n")
+	write("billing/invoice.go", "package billing\n\nfunc InvoiceTotal() int { return 0 }\n\nfunc InvoiceLines() {}\n")
+	write("cmd/run/main.go", "package main\n")
+	write("lib/db/conn.go", "package db\n\n// @doc docs/DB.md\nfunc Open() {}\n")
+	write("app/store.go", "package app\n\nimport \"example.com/app/lib/db\"\n\nfunc Store() { db.Open() }\n")
+	write("web/api.js", "// @doc docs/API.md\nexport function call() {}\n")
+	write("web/pages/view.js", "import { call } from '../api'\n")
+	write("misc/alone.go", "package misc\n")
+	commitAll(t, dir, "Add packages")
+	for i := range 3 {
+		write("tools/job.go", "package tools\n\nvar N = "+string(rune('0'+i))+"\n")
+		write("docs/GUIDE.md", "# Guide\n\nRevision "+string(rune('0'+i))+"\n")
+		commitAll(t, dir, "Tune the job")
+	}
+	initTestEnv(t, dir)
+
+	suggestJSON = true
+	defer func() { suggestJSON = false }()
+	var stdout bytes.Buffer
+	suggestCmd.SetOut(&stdout)
+	if err := suggestCmd.RunE(suggestCmd, nil); err != nil {
+		t.Fatalf("suggest failed: %v", err)
+	}
+	var got struct {
+		Suggestions []docSuggestion `json:"suggestions"`
+		Unmapped    []string        `json:"unmapped"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("bad JSON: %v\n%s", err, stdout.String())
+	}
+	owners := map[string]fileSuggestion{}
+	for _, s := range got.Suggestions {
+		for _, d := range s.Details {
+			d.Doc = s.Doc
+			owners[d.File] = d
+		}
+	}
+
+	for file, want := range map[string]struct{ doc, reason string }{
+		"billing/invoice.go": {"docs/BILLING.md", "docs/BILLING.md mentions `InvoiceTotal`, `InvoiceLines`"},
+		"app/store.go":       {"docs/DB.md", "1 of 1 annotated import neighbors link it (lib/db/conn.go)"},
+		"web/pages/view.js":  {"docs/API.md", "1 of 1 annotated import neighbors link it (web/api.js)"},
+		"tools/job.go":       {"docs/GUIDE.md", "committed with it in 3 of 3 commits"},
+		"src/util.go":        {"docs/API.md", "1 of 1 annotated files in src/ link it"},
+	} {
+		o := owners[file]
+		if o.Doc != want.doc || o.Confidence <= 0 || o.Confidence >= 1 || !containsString(o.Reasons, want.reason) {
+			t.Errorf("%s: got %+v, want %s because %q", file, o, want.doc, want.reason)
+		}
+	}
+	if !containsString(got.Unmapped, "misc/alone.go") {
+		t.Errorf("a file with no signal should be unmapped, got %v", got.Unmapped)
+	}
+	if !containsString(got.Unmapped, "cmd/run/main.go") {
+		t.Errorf("a bare file name mention is below min_confidence, so it should be unmapped, got %v", got.Unmapped)
+	}
+
+	suggestCmd.Flags().Set("min-confidence", "0.3")
+	defer func() {
+		suggestMinConfidence = 0
+		suggestCmd.Flags().Lookup("min-confidence").Changed = false
+	}()
+	stdout.Reset()
+	if err := suggestCmd.RunE(suggestCmd, nil); err != nil {
+		t.Fatalf("suggest --min-confidence failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), `"file": "cmd/run/main.go"`) {
+		t.Errorf("--min-confidence 0.3 should let the file name mention through, got:\n%s", stdout.String())
+	}
+
+	suggestJSON = false
+	stdout.Reset()
+	if err := suggestCmd.RunE(suggestCmd, nil); err != nil {
+		t.Fatalf("suggest failed: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Unmapped (no confident candidate) — ") {
+		t.Errorf("human output should head the unmapped files, got:\n%s", stdout.String())
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestResolveImports(t *testing.T) {
+	known := map[string]bool{
+		"app/models/user.py": true, "app/models/__init__.py": true, "app/util.py": true, "src/lib/core.py": true,
+		"web/api.ts": true, "web/lib/index.js": true,
+	}
+	for _, tt := range []struct {
+		from, line string
+		want       []string
+	}{
+		{"app/views.py", "from .models import user", []string{"app/models/__init__.py", "app/models/user.py"}},
+		{"app/models/user.py", "from ..util import helper", []string{"app/util.py"}},
+		{"app/views.py", "import app.util", []string{"app/util.py"}},
+		{"tests/test_core.py", "from lib.core import run", []string{"src/lib/core.py"}},
+		{"app/views.py", "import os", nil},
+	} {
+		m := pythonImport.FindStringSubmatch(tt.line)
+		if got := resolvePythonImport(tt.from, m, known); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %q resolved to %v, want %v", tt.from, tt.line, got, tt.want)
+		}
+	}
+
+	for spec, want := range map[string]string{"./api": "web/api.ts", "./lib": "web/lib/index.js", "./missing": ""} {
+		if got, _ := resolveJSImport("web/main.js", spec, known); got != want {
+			t.Errorf("resolveJSImport(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package commands
+
+// @doc README.md
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+
+	"github.com/StevenBock/docdiff/internal/scanner"
+)
+
+var watchDebounce time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Re-run check as you save and show the docs you owe",
+	Long: `Watch the working tree and keep a live "docs you now owe" panel: the result
+of 'docdiff check', re-evaluated whenever a file is saved, staged, committed or
+checked out.
+
+The scan stays in memory between runs, so only files that changed are read and
+re-extracted. Bursts of saves are coalesced (see --debounce). On a terminal
+the panel is redrawn in place; otherwise a new panel is printed each time the
+result changes. Stop with Ctrl-C.`,
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().BoolVar(&checkStaged, "staged", false, "only consider staged (index) changes")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 200*time.Millisecond, "quiet period after the last change before re-checking")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// gitTriggers are the files in the git directory whose changes can change the
+// changeset: checkouts and commits (HEAD, refs, and the HEAD reflog every
+// commit appends to) and staging (index).
+var gitTriggers = map[string]bool{"HEAD": true, "index": true, "packed-refs": true, "logs/HEAD": true}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	g := openRepo()
+	gitDir, err := g.Dir()
+	if err != nil {
+		return fmt.Errorf("watch needs a git checkout: %w", err)
+	}
+	checkFiles = nil // watch always follows git, never an explicit file list
+
+	s := scanner.New(cfg, registry)
+	s.Retain()
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := watchTree(w, s); err != nil {
+		return err
+	}
+	if err := watchGitDir(w, gitDir); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	p := &owedPanel{out: cmd.OutOrStdout(), clear: isTerminal(cmd.OutOrStdout())}
+	errOut := cmd.ErrOrStderr()
+	evaluate := func(changed []string) {
+		s.Forget(changed...)
+		o, err := evaluateCheck(g, s, errOut, false)
+		p.render(o, err)
+	}
+	evaluate(nil)
+
+	pending := make(map[string]bool)
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(errOut, "Warning: watch: %v\n", err)
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			rel, relevant := watchEventPath(ev, gitDir)
+			if !relevant {
+				continue
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := watchTree(w, s); err != nil {
+						fmt.Fprintf(errOut, "Warning: watch: %v\n", err)
+					}
+					if err := watchGitDir(w, gitDir); err != nil {
+						fmt.Fprintf(errOut, "Warning: watch: %v\n", err)
+					}
+				}
+			}
+			if rel != "" {
+				pending[rel] = true
+			}
+			fire = time.After(watchDebounce)
+		case <-fire:
+			changed := make([]string, 0, len(pending))
+			for rel := range pending {
+				changed = append(changed, rel)
+			}
+			pending = make(map[string]bool)
+			fire = nil
+			evaluate(changed)
+		}
+	}
+}
+
+// watchTree adds every directory a scan walks. Adding a watched directory
+// again is a no-op, so it also picks up newly created directories.
+func watchTree(w *fsnotify.Watcher, s *scanner.Scanner) error {
+	dirs, err := s.Dirs(rootDir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := w.Add(filepath.Join(rootDir, filepath.FromSlash(dir))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchGitDir adds the git directory, its logs directory, and every directory
+// under refs/heads: watches aren't recursive, and a branch like feature/x
+// lives in refs/heads/feature. Like watchTree, it is safe to call again to
+// pick up new directories.
+func watchGitDir(w *fsnotify.Watcher, gitDir string) error {
+	dirs := []string{gitDir, filepath.Join(gitDir, "logs")}
+	heads := filepath.Join(gitDir, "refs", "heads")
+	err := filepath.WalkDir(heads, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := w.Add(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchEventPath classifies an event: inside the git directory only the
+// trigger files matter (and name no working-tree file); elsewhere the path is
+// returned relative to the root. Pure chmods are ignored.
+func watchEventPath(ev fsnotify.Event, gitDir string) (string, bool) {
+	if ev.Op == fsnotify.Chmod {
+		return "", false
+	}
+	if rel, err := filepath.Rel(gitDir, ev.Name); err == nil && !strings.HasPrefix(rel, "..") {
+		rel = filepath.ToSlash(rel)
+		return "", gitTriggers[rel] || (strings.HasPrefix(rel, "refs/heads/") && !strings.HasSuffix(rel, ".lock"))
+	}
+	rel, err := filepath.Rel(rootDir, ev.Name)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// owedPanel renders check outcomes as the watch panel, skipping redraws that
+// wouldn't change anything.
+type owedPanel struct {
+	out   io.Writer
+	clear bool
+	last  string
+}
+
+func (p *owedPanel) render(o *checkOutcome, err error) {
+	var b strings.Builder
+	if err != nil {
+		fmt.Fprintf(&b, "check failed: %v\n", err)
+	} else {
+		writeOwed(&b, o)
+	}
+	body := b.String()
+	if body == p.last {
+		return
+	}
+	p.last = body
+
+	if p.clear {
+		fmt.Fprint(p.out, "\033[H\033[2J")
+	} else {
+		fmt.Fprintln(p.out)
+	}
+	fmt.Fprintf(p.out, "docdiff watch — %s\n\n%s\nWatching for changes (Ctrl-C to stop).\n", time.Now().Format("15:04:05"), body)
+}
+
+func writeOwed(out io.Writer, o *checkOutcome) {
+	var owed, updated []checkResult
+	for _, r := range o.results {
+		if r.Status == "needs update" {
+			owed = append(owed, r)
+		} else {
+			updated = append(updated, r)
+		}
+	}
+
+	changes := strings.TrimSuffix(o.source, " changes") + " changes" // "staged changes" is already plural
+	if len(owed) == 0 {
+		fmt.Fprintf(out, "Docs you now owe: none for your %s.\n", changes)
+	} else {
+		fmt.Fprintf(out, "Docs you now owe (%d) for your %s:\n", len(owed), changes)
+		for _, r := range owed {
+			fmt.Fprintf(out, "  %s%s\n", r.Doc, broadHint(r))
+			writeProvenance(out, r)
+		}
+	}
+	if len(updated) > 0 {
+		fmt.Fprintf(out, "\nAlready updated (%d):\n", len(updated))
+		for _, r := range updated {
+			fmt.Fprintf(out, "  %s\n", r.Doc)
+		}
+	}
+	writeBrokenAnnotations(out, o.broken)
+	for _, warning := range o.warnings {
+		fmt.Fprintf(out, "\nWarning: %s\n", warning)
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer lets the test read output while runWatch is still writing it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchReportsOwedDocs(t *testing.T) {
+	dir := setupTestProject(t)
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkFiles = nil
+	watchDebounce = 20 * time.Millisecond
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchCmd.SetOut(&out)
+	watchCmd.SetErr(&out)
+	watchCmd.SetContext(ctx)
+	done := make(chan error, 1)
+	go func() { done <- runWatch(watchCmd, nil) }()
+
+	// waitFor returns the latest panel once it contains want.
+	waitFor := func(want string) string {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			s := out.String()
+			if i := strings.LastIndex(s, "docdiff watch — "); i >= 0 && strings.Contains(s[i:], want) {
+				return s[i:]
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %q, got:\n%s", want, out.String())
+		return ""
+	}
+
+	waitFor("Docs you now owe: none for your working tree changes.")
+
+	os.WriteFile(filepath.Join(dir, "src", "handler.go"), []byte("package main\n\n// @doc docs/API.md\nfunc Handler(name string) {}\n"), 0644)
+	panel := waitFor("Docs you now owe (1) for your working tree changes:")
+	if !strings.Contains(panel, "  docs/API.md") || !strings.Contains(panel, "src/handler.go") {
+		t.Errorf("panel should name the doc and the code behind it, got:\n%s", panel)
+	}
+
+	os.WriteFile(filepath.Join(dir, "docs", "API.md"), []byte("# API Docs\n\nHandler takes a name.\n"), 0644)
+	panel = waitFor("Already updated (1):")
+	if !strings.Contains(panel, "Docs you now owe: none") {
+		t.Errorf("updating the doc should settle what's owed, got:\n%s", panel)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("watch returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch didn't stop when its context was cancelled")
+	}
+}
+
+func TestWatchFollowsCommitsOnNestedBranch(t *testing.T) {
+	dir := setupTestProject(t)
+	runGit(t, dir, "checkout", "-q", "-b", "feature/x")
+	os.WriteFile(filepath.Join(dir, "src", "handler.go"), []byte("package main\n\n// @doc docs/API.md\nfunc Handler(name string) {}\n"), 0644)
+	commitAll(t, dir, "Change the handler")
+	commit := runGit(t, dir, "rev-parse", "HEAD")
+	runGit(t, dir, "reset", "-q", "--soft", "HEAD~1")
+	initTestEnv(t, dir)
+	checkStaged = false
+	checkFiles = nil
+	watchDebounce = 20 * time.Millisecond
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watchCmd.SetOut(&out)
+	watchCmd.SetErr(&out)
+	watchCmd.SetContext(ctx)
+	done := make(chan error, 1)
+	go func() { done <- runWatch(watchCmd, nil) }()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			s := out.String()
+			if i := strings.LastIndex(s, "docdiff watch — "); i >= 0 && strings.Contains(s[i:], want) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %q, got:\n%s", want, out.String())
+	}
+
+	waitFor("Docs you now owe (1) for your working tree changes:")
+	// Committing moves refs/heads/feature/x and appends to logs/HEAD, but
+	// leaves HEAD (still naming the branch) and the index alone.
+	runGit(t, dir, "update-ref", "-m", "commit: Change the handler", "HEAD", commit)
+	waitFor("Docs you now owe: none for your working tree changes.")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watch returned %v", err)
+	}
+}
+
+func TestWriteOwedSourceLabel(t *testing.T) {
+	for source, want := range map[string]string{
+		"working tree":   "Docs you now owe: none for your working tree changes.",
+		"staged changes": "Docs you now owe: none for your staged changes.",
+	} {
+		var b bytes.Buffer
+		writeOwed(&b, &checkOutcome{source: source})
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("writeOwed(%q) = %q, want %q", source, b.String(), want)
+		}
+	}
+}
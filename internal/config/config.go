@@ -4,8 +4,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,13 +17,46 @@ import (
 type Config struct {
 	AnnotationTag    string                    `yaml:"annotation_tag" json:"annotation_tag"`
 	DocsDirectory    string                    `yaml:"docs_directory" json:"docs_directory"`
+	DocGlobs         []string                  `yaml:"doc_globs" json:"doc_globs"`
 	Include          []string                  `yaml:"include" json:"include"`
 	Exclude          []string                  `yaml:"exclude" json:"exclude"`
 	RespectGitignore *bool                     `yaml:"respect_gitignore" json:"respect_gitignore"`
 	Languages        map[string]LanguageConfig `yaml:"languages" json:"languages"`
+	ScopeOwnership   string                    `yaml:"scope_ownership" json:"scope_ownership"`
+	ScanCache        *bool                     `yaml:"scan_cache" json:"scan_cache"`
+	ScanWorkers      int                       `yaml:"scan_workers" json:"scan_workers"`
+	GitBackend       string                    `yaml:"git_backend" json:"git_backend"`
+	Aliases          map[string]string         `yaml:"aliases" json:"aliases"`
+	SymbolRefs       bool                      `yaml:"symbol_refs" json:"symbol_refs"`
+	InferLinks       InferLinksConfig          `yaml:"infer_links" json:"infer_links"`
+	Suggest          SuggestConfig             `yaml:"suggest" json:"suggest"`
+	Links            []LinkRule                `yaml:"links" json:"links"`
 	CI               CIConfig                  `yaml:"ci" json:"ci"`
 }
 
+// Scope ownership modes. With next-annotation (the default) a scoped
+// annotation owns its line through the line before the next annotation. With
+// declaration, an annotation directly above a declaration owns only that
+// declaration; elsewhere, or in a language without a block finder, it falls
+// back to next-annotation.
+const (
+	ScopeOwnershipNextAnnotation = "next-annotation"
+	ScopeOwnershipDeclaration    = "declaration"
+)
+
+// Git backends. exec (the default) runs the git binary; go-git reads the
+// repository in-process and needs no git install.
+const (
+	GitBackendExec  = "exec"
+	GitBackendGoGit = "go-git"
+)
+
+// DeclarationScopes reports whether scoped annotations own the declaration
+// below them rather than everything up to the next annotation.
+func (c *Config) DeclarationScopes() bool {
+	return c.ScopeOwnership == ScopeOwnershipDeclaration
+}
+
 // GitignoreRespected reports whether gitignored files should be skipped during
 // scanning. Defaults to true when unset.
 func (c *Config) GitignoreRespected() bool {
@@ -29,16 +66,161 @@ func (c *Config) GitignoreRespected() bool {
 	return *c.RespectGitignore
 }
 
+// ScanCacheEnabled reports whether extracted annotations are cached under the
+// git directory between runs. Defaults to true when unset.
+func (c *Config) ScanCacheEnabled() bool {
+	if c.ScanCache == nil {
+		return true
+	}
+	return *c.ScanCache
+}
+
+// Workers is how many files the scanner reads and extracts in parallel.
+// Defaults to GOMAXPROCS when unset or not positive.
+func (c *Config) Workers() int {
+	if c.ScanWorkers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return c.ScanWorkers
+}
+
+// LanguageConfig tweaks a built-in language or, under a new name, declares a
+// whole new one. A declared language needs some comment syntax (line_comments,
+// block_comments or comment_patterns) and some way to be detected (extensions,
+// interpreters or modelines). Exclude scopes a language out of part of the
+// tree: files matching one of its globs aren't detected as that language.
 type LanguageConfig struct {
-	Enabled         *bool    `yaml:"enabled" json:"enabled"`
-	Extensions      []string `yaml:"extensions" json:"extensions"`
-	CommentPatterns []string `yaml:"comment_patterns" json:"comment_patterns"`
+	Enabled         *bool          `yaml:"enabled" json:"enabled"`
+	Extensions      []string       `yaml:"extensions" json:"extensions"`
+	CommentPatterns []string       `yaml:"comment_patterns" json:"comment_patterns"`
+	Interpreters    []string       `yaml:"interpreters" json:"interpreters"`
+	Modelines       []string       `yaml:"modelines" json:"modelines"`
+	LineComments    []string       `yaml:"line_comments" json:"line_comments"`
+	BlockComments   []BlockComment `yaml:"block_comments" json:"block_comments"`
+	Exclude         []string       `yaml:"exclude" json:"exclude"`
+}
+
+// BlockComment is a start/end delimiter pair such as `/*` and `*/`.
+type BlockComment struct {
+	Start string `yaml:"start" json:"start"`
+	End   string `yaml:"end" json:"end"`
 }
 
 type CIConfig struct {
 	FailOnStale            bool `yaml:"fail_on_stale" json:"fail_on_stale"`
 	FailOnOrphaned         bool `yaml:"fail_on_orphaned" json:"fail_on_orphaned"`
 	FailOnUndocumentedRefs bool `yaml:"fail_on_undocumented_refs" json:"fail_on_undocumented_refs"`
+	// FailOnBrokenAnnotations fails CI when an annotation names a doc that
+	// doesn't exist. Defaults to false so upgrading doesn't break builds that
+	// already carry such typos; broken annotations are still reported.
+	FailOnBrokenAnnotations bool `yaml:"fail_on_broken_annotations" json:"fail_on_broken_annotations"`
+}
+
+// InferLinksConfig holds the thresholds `infer-links` reports pairs at.
+type InferLinksConfig struct {
+	// MinCommits is how many commits a doc and an unlinked source must share.
+	MinCommits int `yaml:"min_commits" json:"min_commits"`
+	// MinRatio is the share of the source's commits that must touch the doc.
+	MinRatio float64 `yaml:"min_ratio" json:"min_ratio"`
+	// DeadAfter is how many commits a linked source may change without its
+	// doc before the link is reported as likely dead.
+	DeadAfter int `yaml:"dead_after" json:"dead_after"`
+	// MaxFiles skips commits touching more paths than this (mass reformats,
+	// vendoring); 0 keeps every commit.
+	MaxFiles int `yaml:"max_files" json:"max_files"`
+}
+
+// Validate rejects negative counts and a ratio outside 0..1.
+func (c InferLinksConfig) Validate() error {
+	if c.MinCommits < 0 || c.DeadAfter < 0 || c.MaxFiles < 0 {
+		return fmt.Errorf("min_commits, dead_after and max_files can't be negative")
+	}
+	if c.MinRatio < 0 || c.MinRatio > 1 {
+		return fmt.Errorf("min_ratio %v is outside 0..1", c.MinRatio)
+	}
+	return nil
+}
+
+// SuggestConfig holds how sure `suggest` and `annotate` must be of an owner.
+type SuggestConfig struct {
+	// MinConfidence is the lowest confidence an owner is suggested at; files
+	// whose best doc scores below it are listed as unmapped.
+	MinConfidence float64 `yaml:"min_confidence" json:"min_confidence"`
+}
+
+// Validate rejects a confidence outside 0..1.
+func (c SuggestConfig) Validate() error {
+	if c.MinConfidence < 0 || c.MinConfidence > 1 {
+		return fmt.Errorf("min_confidence %v is outside 0..1", c.MinConfidence)
+	}
+	return nil
+}
+
+// MapFile is the standalone mapping file: a `links:` list like the config's,
+// kept apart when the mapping grows long or is generated.
+const MapFile = "docdiff.map.yaml"
+
+// LinkRule links every file Files matches to Doc, for code that can't carry
+// an annotation or is easier to map centrally. Without scopes the doc owns the
+// whole file; each scope, a line range (`40-80`, `L40-L80`) or a declared
+// symbol name, narrows it to that region.
+type LinkRule struct {
+	Files  string   `yaml:"files" json:"files"`
+	Doc    string   `yaml:"doc" json:"doc"`
+	Scopes []string `yaml:"scopes" json:"scopes"`
+}
+
+var lineRange = regexp.MustCompile(`^L?(\d+)(?:-L?(\d+))?$`)
+
+// ParseLineRange reads a line-range scope such as `40-80`, `L40-L80` or `L12`
+// into its inclusive 1-based bounds. ok is false for anything else, such as a
+// symbol name.
+func ParseLineRange(scope string) (start, end int, ok bool) {
+	m := lineRange.FindStringSubmatch(scope)
+	if m == nil {
+		return 0, 0, false
+	}
+	start, _ = strconv.Atoi(m[1])
+	end = start
+	if m[2] != "" {
+		end, _ = strconv.Atoi(m[2])
+	}
+	return start, end, true
+}
+
+// Validate requires files and doc, and scopes that are either a valid line
+// range or a name.
+func (r LinkRule) Validate() error {
+	if r.Files == "" || r.Doc == "" {
+		return fmt.Errorf("files and doc are both required")
+	}
+	for _, scope := range r.Scopes {
+		if scope == "" {
+			return fmt.Errorf("%s: empty scope", r.Files)
+		}
+		if start, end, ok := ParseLineRange(scope); ok && (start < 1 || end < start) {
+			return fmt.Errorf("%s: line range %q is empty", r.Files, scope)
+		}
+	}
+	return nil
+}
+
+// loadMapFile reads the links listed in dir's MapFile, if there is one.
+func loadMapFile(dir string) ([]LinkRule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MapFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m struct {
+		Links []LinkRule `yaml:"links"`
+	}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", MapFile, err)
+	}
+	return m.Links, nil
 }
 
 func (lc *LanguageConfig) IsEnabled() bool {
@@ -48,43 +230,83 @@ func (lc *LanguageConfig) IsEnabled() bool {
 	return *lc.Enabled
 }
 
+// FilePath returns the config file Load reads in dir: .docdiff.yaml, else
+// .docdiff.yml, else .docdiff.json. It is "" when there is none.
+func FilePath(dir string) string {
+	for _, name := range []string{".docdiff.yaml", ".docdiff.yml", ".docdiff.json"} {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
 func Load(dir string) (*Config, error) {
 	cfg := DefaultConfig()
 
-	yamlPath := filepath.Join(dir, ".docdiff.yaml")
-	ymlPath := filepath.Join(dir, ".docdiff.yml")
-	jsonPath := filepath.Join(dir, ".docdiff.json")
+	if configPath := FilePath(dir); configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
 
-	var configPath string
-	if _, err := os.Stat(yamlPath); err == nil {
-		configPath = yamlPath
-	} else if _, err := os.Stat(ymlPath); err == nil {
-		configPath = ymlPath
-	} else if _, err := os.Stat(jsonPath); err == nil {
-		configPath = jsonPath
-	} else {
-		return cfg, nil
+		ext := filepath.Ext(configPath)
+		if ext == ".json" {
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, err
+			}
+		} else {
+			if err := yaml.Unmarshal(data, cfg); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	data, err := os.ReadFile(configPath)
+	mapped, err := loadMapFile(dir)
 	if err != nil {
 		return nil, err
 	}
+	cfg.Links = append(cfg.Links, mapped...)
 
-	ext := filepath.Ext(configPath)
-	if ext == ".json" {
-		if err := json.Unmarshal(data, cfg); err != nil {
-			return nil, err
+	switch cfg.ScopeOwnership {
+	case "", ScopeOwnershipNextAnnotation, ScopeOwnershipDeclaration:
+	default:
+		return nil, fmt.Errorf("scope_ownership: unknown mode %q (want %q or %q)", cfg.ScopeOwnership, ScopeOwnershipNextAnnotation, ScopeOwnershipDeclaration)
+	}
+	if err := ValidateGitBackend(cfg.GitBackend); err != nil {
+		return nil, fmt.Errorf("git_backend: %w", err)
+	}
+	if err := cfg.InferLinks.Validate(); err != nil {
+		return nil, fmt.Errorf("infer_links: %w", err)
+	}
+	if err := cfg.Suggest.Validate(); err != nil {
+		return nil, fmt.Errorf("suggest: %w", err)
+	}
+	for from, to := range cfg.Aliases {
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("aliases: %q -> %q: both paths are required", from, to)
 		}
-	} else {
-		if err := yaml.Unmarshal(data, cfg); err != nil {
-			return nil, err
+	}
+	for i, rule := range cfg.Links {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("links[%d]: %w", i, err)
 		}
 	}
 
 	return cfg, nil
 }
 
+// ValidateGitBackend rejects backend names other than exec and go-git ("" is
+// the default, exec).
+func ValidateGitBackend(name string) error {
+	switch name {
+	case "", GitBackendExec, GitBackendGoGit:
+		return nil
+	}
+	return fmt.Errorf("unknown git backend %q (want %q or %q)", name, GitBackendExec, GitBackendGoGit)
+}
+
 func (c *Config) DocsPath(rootDir string) string {
 	return filepath.Join(rootDir, c.DocsDirectory)
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -55,6 +57,12 @@ func TestDefaultConfig(t *testing.T) {
 			t.Error("CI.FailOnOrphaned should be false by default")
 		}
 	})
+
+	t.Run("default CI fail on broken annotations", func(t *testing.T) {
+		if cfg.CI.FailOnBrokenAnnotations {
+			t.Error("CI.FailOnBrokenAnnotations should be false by default")
+		}
+	})
 }
 
 func TestLanguageConfig_IsEnabled(t *testing.T) {
@@ -101,6 +109,8 @@ func TestLoad(t *testing.T) {
 		configContent := `
 annotation_tag: "@track"
 docs_directory: documentation
+doc_globs:
+  - "adr/**/*.md"
 include:
   - "src/**"
 exclude:
@@ -108,6 +118,7 @@ exclude:
 ci:
   fail_on_stale: false
   fail_on_orphaned: true
+  fail_on_broken_annotations: true
 `
 		err := os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(configContent), 0644)
 		if err != nil {
@@ -125,6 +136,9 @@ ci:
 		if cfg.DocsDirectory != "documentation" {
 			t.Errorf("DocsDirectory = %s, want documentation", cfg.DocsDirectory)
 		}
+		if len(cfg.DocGlobs) != 1 || cfg.DocGlobs[0] != "adr/**/*.md" {
+			t.Errorf("DocGlobs = %v, want [adr/**/*.md]", cfg.DocGlobs)
+		}
 		if len(cfg.Include) != 1 || cfg.Include[0] != "src/**" {
 			t.Errorf("Include = %v, want [src/**]", cfg.Include)
 		}
@@ -137,6 +151,9 @@ ci:
 		if !cfg.CI.FailOnOrphaned {
 			t.Error("CI.FailOnOrphaned should be true")
 		}
+		if !cfg.CI.FailOnBrokenAnnotations {
+			t.Error("CI.FailOnBrokenAnnotations should be true")
+		}
 	})
 
 	t.Run("load yml config", func(t *testing.T) {
@@ -212,6 +229,103 @@ ci:
 			t.Error("Load() should return error for invalid JSON")
 		}
 	})
+
+	t.Run("scope ownership mode", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(`scope_ownership: declaration`), 0644)
+
+		cfg, err := Load(tmpDir)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if !cfg.DeclarationScopes() {
+			t.Error("DeclarationScopes() should be true")
+		}
+		if DefaultConfig().DeclarationScopes() {
+			t.Error("declaration ownership should be opt-in")
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(`scope_ownership: function`), 0644)
+		if _, err := Load(tmpDir); err == nil || !strings.Contains(err.Error(), "scope_ownership") {
+			t.Errorf("Load() error = %v, want unknown scope_ownership mode", err)
+		}
+	})
+
+	t.Run("git backend", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(`git_backend: go-git`), 0644)
+
+		cfg, err := Load(tmpDir)
+		if err != nil || cfg.GitBackend != GitBackendGoGit {
+			t.Fatalf("Load() = %q, %v; want go-git", cfg.GitBackend, err)
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(`git_backend: libgit2`), 0644)
+		if _, err := Load(tmpDir); err == nil || !strings.Contains(err.Error(), "git_backend") {
+			t.Errorf("Load() error = %v, want unknown git_backend", err)
+		}
+	})
+
+	t.Run("infer links thresholds", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte("infer_links:\n  min_ratio: 0.8\n"), 0644)
+
+		cfg, err := Load(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (InferLinksConfig{MinCommits: 3, MinRatio: 0.8, DeadAfter: 20, MaxFiles: 30}); cfg.InferLinks != want {
+			t.Errorf("InferLinks = %+v, want %+v (unset keys keep their defaults)", cfg.InferLinks, want)
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte("infer_links:\n  min_ratio: 2\n"), 0644)
+		if _, err := Load(tmpDir); err == nil || !strings.Contains(err.Error(), "infer_links") {
+			t.Errorf("Load() error = %v, want a min_ratio range error", err)
+		}
+	})
+
+	t.Run("suggest min confidence", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		cfg, err := Load(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Suggest.MinConfidence != 0.35 {
+			t.Errorf("Suggest.MinConfidence = %v, want 0.35 by default", cfg.Suggest.MinConfidence)
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte("suggest:\n  min_confidence: 1.5\n"), 0644)
+		if _, err := Load(tmpDir); err == nil || !strings.Contains(err.Error(), "suggest") {
+			t.Errorf("Load() error = %v, want a min_confidence range error", err)
+		}
+	})
+
+	t.Run("links from config and map file", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		os.WriteFile(filepath.Join(tmpDir, MapFile), []byte("links:\n  - files: schemas/*.json\n    doc: docs/SCHEMAS.md\n"), 0644)
+
+		cfg, err := Load(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []LinkRule{{Files: "schemas/*.json", Doc: "docs/SCHEMAS.md"}}; !reflect.DeepEqual(cfg.Links, want) {
+			t.Errorf("Links = %+v, want %+v (the map file loads without a config)", cfg.Links, want)
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte("links:\n  - files: src/settings.go\n    doc: docs/MOBILE.md\n    scopes: [mobileSettings, L40-L80]\n"), 0644)
+		cfg, err = Load(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.Links) != 2 || cfg.Links[0].Doc != "docs/MOBILE.md" || len(cfg.Links[0].Scopes) != 2 {
+			t.Errorf("Links = %+v, want the config's links, then the map file's", cfg.Links)
+		}
+
+		os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte("links:\n  - files: src/settings.go\n    doc: docs/MOBILE.md\n    scopes: [80-40]\n"), 0644)
+		if _, err := Load(tmpDir); err == nil || !strings.Contains(err.Error(), "links[0]") {
+			t.Errorf("Load() error = %v, want an empty line range error", err)
+		}
+	})
 }
 
 func TestConfig_Paths(t *testing.T) {
@@ -238,6 +352,13 @@ languages:
       - ".py"
       - ".pyw"
       - ".pyi"
+  lua:
+    extensions: [".lua"]
+    interpreters: ["lua"]
+    line_comments: ["--"]
+    block_comments:
+      - start: "--[["
+        end: "]]"
 `
 	os.WriteFile(filepath.Join(tmpDir, ".docdiff.yaml"), []byte(configContent), 0644)
 
@@ -268,4 +389,40 @@ languages:
 			t.Errorf("Python extensions = %v, want 3 items", pyCfg.Extensions)
 		}
 	})
+
+	t.Run("declared language fields", func(t *testing.T) {
+		luaCfg, ok := cfg.Languages["lua"]
+		if !ok {
+			t.Fatal("lua language config not found")
+		}
+		if len(luaCfg.Interpreters) != 1 || luaCfg.Interpreters[0] != "lua" {
+			t.Errorf("Interpreters = %v, want [lua]", luaCfg.Interpreters)
+		}
+		if len(luaCfg.LineComments) != 1 || luaCfg.LineComments[0] != "--" {
+			t.Errorf("LineComments = %v, want [--]", luaCfg.LineComments)
+		}
+		if len(luaCfg.BlockComments) != 1 || luaCfg.BlockComments[0] != (BlockComment{Start: "--[[", End: "]]"}) {
+			t.Errorf("BlockComments = %v, want [{--[[ ]]}]", luaCfg.BlockComments)
+		}
+	})
+}
+
+func TestParseLineRange(t *testing.T) {
+	tests := []struct {
+		scope      string
+		start, end int
+		ok         bool
+	}{
+		{"40-80", 40, 80, true},
+		{"L40-L80", 40, 80, true},
+		{"L12", 12, 12, true},
+		{"mobileSettings", 0, 0, false},
+		{"40-", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := ParseLineRange(tt.scope)
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Errorf("ParseLineRange(%q) = %d, %d, %v, want %d, %d, %v", tt.scope, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
 }
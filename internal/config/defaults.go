@@ -20,9 +20,19 @@ func DefaultConfig() *Config {
 			"venv/**",
 		},
 		Languages: make(map[string]LanguageConfig),
+		InferLinks: InferLinksConfig{
+			MinCommits: 3,
+			MinRatio:   0.5,
+			DeadAfter:  20,
+			MaxFiles:   30,
+		},
+		Suggest: SuggestConfig{
+			MinConfidence: 0.35,
+		},
 		CI: CIConfig{
-			FailOnStale:    true,
-			FailOnOrphaned: false,
+			FailOnStale:             true,
+			FailOnOrphaned:          false,
+			FailOnBrokenAnnotations: false,
 		},
 	}
 }
@@ -0,0 +1,122 @@
+package docformat
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+)
+
+// asciiDoc is AsciiDoc, as Asciidoctor reads it.
+type asciiDoc struct{}
+
+func NewAsciiDocFormat() Format {
+	return &asciiDoc{}
+}
+
+func (a *asciiDoc) Name() string {
+	return "asciidoc"
+}
+
+func (a *asciiDoc) Extensions() []string {
+	return []string{".adoc", ".asciidoc", ".asc"}
+}
+
+// adocCodeDelimiter opens and closes listing (----), literal (....),
+// passthrough (++++) and comment (////) blocks. Fenced ``` blocks count too.
+var adocCodeDelimiter = regexp.MustCompile("^(?:-{4,}|\\.{4,}|\\+{4,}|/{4,}|```.*)$")
+
+// Prose blanks listing, literal, passthrough and comment blocks, and line
+// comments. A block ends at the same delimiter that opened it.
+func (a *asciiDoc) Prose(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	code := make([]bool, len(lines))
+	delimiter := ""
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if delimiter != "" {
+			code[i] = true
+			if trimmed == delimiter || (strings.HasPrefix(delimiter, "```") && trimmed == "```") {
+				delimiter = ""
+			}
+			continue
+		}
+		switch {
+		case adocCodeDelimiter.MatchString(trimmed):
+			delimiter = trimmed
+			code[i] = true
+		case strings.HasPrefix(trimmed, "//") && !strings.HasPrefix(trimmed, "///"):
+			code[i] = true
+		}
+	}
+	return blankLines(lines, code)
+}
+
+var (
+	adocMacro    = regexp.MustCompile(`\b(?:link|xref):([^\[\s]+)\[`)
+	adocInclude  = regexp.MustCompile(`^include::([^\[\s]+)\[`)
+	adocCrossRef = regexp.MustCompile(`<<([^,>\s]+)(?:,[^>]*)?>>`)
+)
+
+// Links finds link: and xref: macros, include:: directives, and <<target>>
+// cross references that name a file.
+func (a *asciiDoc) Links(prose []byte) []docparse.Link {
+	var links []docparse.Link
+	for i, line := range strings.Split(string(prose), "\n") {
+		for _, m := range adocMacro.FindAllStringSubmatch(line, -1) {
+			links = append(links, docparse.Link{Target: m[1], Line: i + 1})
+		}
+		if m := adocInclude.FindStringSubmatch(line); m != nil {
+			links = append(links, docparse.Link{Target: m[1], Line: i + 1})
+		}
+		for _, m := range adocCrossRef.FindAllStringSubmatch(line, -1) {
+			if strings.Contains(m[1], ".") {
+				links = append(links, docparse.Link{Target: m[1], Line: i + 1})
+			}
+		}
+	}
+	return links
+}
+
+var (
+	adocHeading  = regexp.MustCompile(`^(={1,6}|#{1,6})[ \t]+(.+?)(?:[ \t]+=+)?[ \t]*$`)
+	adocAnchor   = regexp.MustCompile(`^\[(?:\[([^\],]+)(?:,[^\]]*)?\]|#([^\].,%]+)[^\]]*)\]$`)
+	adocIDStrip  = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	adocAttrLine = regexp.MustCompile(`^\[[^\]]*\]$`)
+)
+
+// Headings reads `= Title` through `====== Title` (or Markdown-style `#`)
+// section titles; the number of markers is the level. Anchors are
+// Asciidoctor's default ids (`_` then the lowercased title with punctuation
+// runs as `_`), or an explicit `[[id]]` or `[#id]` on the line above.
+func (a *asciiDoc) Headings(content []byte) []docparse.Heading {
+	lines := strings.Split(string(content), "\n")
+	prose := strings.Split(string(a.Prose(content)), "\n")
+	var headings []docparse.Heading
+	ids := newAnchors("_", 2)
+	explicit := ""
+	for i, line := range prose {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if m := adocAnchor.FindStringSubmatch(trimmed); m != nil {
+			explicit = m[1] + m[2]
+			continue
+		}
+		if adocAttrLine.MatchString(trimmed) {
+			continue // other block attributes keep a pending anchor
+		}
+		m := adocHeading.FindStringSubmatch(trimmed)
+		if m == nil {
+			explicit = ""
+			continue
+		}
+		title := strings.TrimSpace(m[2])
+		anchor := explicit
+		if anchor == "" {
+			anchor = ids.unique("_" + strings.Trim(adocIDStrip.ReplaceAllString(strings.ToLower(title), "_"), "_"))
+		}
+		headings = append(headings, docparse.Heading{Level: len(m[1]), Title: title, Anchor: anchor, Line: i + 1})
+		explicit = ""
+	}
+	docparse.CloseSections(headings, lines)
+	return headings
+}
@@ -0,0 +1,157 @@
+// Package docformat knows how to read documentation in each supported markup:
+// which lines are code rather than prose, which links a doc makes, and where
+// its sections start and end. It is the docs-side counterpart of the language
+// package's strategies.
+package docformat
+
+// @doc CLAUDE.md
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+)
+
+// Format is one documentation markup.
+type Format interface {
+	Name() string
+	Extensions() []string
+	// Prose returns content with every line that isn't prose, such as code
+	// blocks, blanked in place, so examples aren't taken as references and
+	// line numbers still match the doc.
+	Prose(content []byte) []byte
+	// Links returns the explicit link targets in prose, as written.
+	Links(prose []byte) []docparse.Link
+	// Headings returns the doc's sections, with their anchors as the markup's
+	// usual toolchain renders them.
+	Headings(content []byte) []docparse.Heading
+}
+
+// LocationBound is implemented by a format whose extension is too common
+// outside documentation to claim files on its own: .txt also names
+// requirements.txt, CMakeLists.txt and LICENSE.txt. Its files are docs only
+// where docs are configured to live.
+type LocationBound interface {
+	LocationBound() bool
+}
+
+type Registry struct {
+	formats map[string]Format
+	extMap  map[string]Format
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		formats: make(map[string]Format),
+		extMap:  make(map[string]Format),
+	}
+}
+
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(NewMarkdownFormat())
+	r.Register(NewMDXFormat())
+	r.Register(NewRSTFormat())
+	r.Register(NewAsciiDocFormat())
+	r.Register(NewTextFormat())
+	return r
+}
+
+func (r *Registry) Register(f Format) {
+	r.formats[f.Name()] = f
+	for _, ext := range f.Extensions() {
+		r.extMap[ext] = f
+	}
+}
+
+// ForPath returns the format of the doc at path, by extension
+// (case-insensitively).
+func (r *Registry) ForPath(path string) (Format, bool) {
+	f, ok := r.extMap[strings.ToLower(filepath.Ext(path))]
+	return f, ok
+}
+
+// IsDoc reports whether path is in a registered doc format.
+func (r *Registry) IsDoc(path string) bool {
+	_, ok := r.ForPath(path)
+	return ok
+}
+
+// LocationBound reports whether path's format claims it only where docs are
+// configured to live (see the LocationBound interface).
+func (r *Registry) LocationBound(path string) bool {
+	f, ok := r.ForPath(path)
+	if !ok {
+		return false
+	}
+	lb, ok := f.(LocationBound)
+	return ok && lb.LocationBound()
+}
+
+func (r *Registry) AllExtensions() []string {
+	exts := make([]string, 0, len(r.extMap))
+	for ext := range r.extMap {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Prose is path's prose under its format, or content unchanged when no format
+// claims path.
+func (r *Registry) Prose(path string, content []byte) []byte {
+	if f, ok := r.ForPath(path); ok {
+		return f.Prose(content)
+	}
+	return content
+}
+
+// Headings parses path's headings with its format, falling back to Markdown
+// when no format claims path.
+func (r *Registry) Headings(path string, content []byte) []docparse.Heading {
+	if f, ok := r.ForPath(path); ok {
+		return f.Headings(content)
+	}
+	return docparse.Headings(content)
+}
+
+// Section returns the heading of path's section with the given anchor.
+func (r *Registry) Section(path string, content []byte, anchor string) (docparse.Heading, bool) {
+	return docparse.FindSection(r.Headings(path, content), anchor)
+}
+
+// blankLines returns content with the lines code marks replaced by empty
+// lines.
+func blankLines(lines []string, code []bool) []byte {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if !code[i] {
+			out[i] = line
+		}
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// anchors hands out unique anchors, suffixing repeats with sep and a count.
+type anchors struct {
+	seen  map[string]int
+	sep   string
+	first int // the count the first repeat gets
+}
+
+func newAnchors(sep string, first int) *anchors {
+	return &anchors{seen: make(map[string]int), sep: sep, first: first}
+}
+
+func (a *anchors) unique(anchor string) string {
+	n, dup := a.seen[anchor]
+	if !dup {
+		a.seen[anchor] = a.first
+		return anchor
+	}
+	a.seen[anchor] = n + 1
+	return anchor + a.sep + strconv.Itoa(n)
+}
@@ -0,0 +1,202 @@
+package docformat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+)
+
+func TestRegistry_ForPath(t *testing.T) {
+	r := DefaultRegistry()
+	tests := map[string]string{
+		"docs/API.md":       "markdown",
+		"README.MARKDOWN":   "markdown",
+		"docs/intro.mdx":    "mdx",
+		"docs/index.rst":    "rst",
+		"docs/guide.adoc":   "asciidoc",
+		"NOTES.txt":         "text",
+		"src/handler.go":    "",
+		"docs/diagram.html": "",
+	}
+	for path, want := range tests {
+		f, ok := r.ForPath(path)
+		got := ""
+		if ok {
+			got = f.Name()
+		}
+		if got != want {
+			t.Errorf("ForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestProse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		want    string
+	}{
+		{
+			name:    "markdown fences",
+			format:  NewMarkdownFormat(),
+			content: "See a.go\n```go\nb.go\n```\nc.go",
+			want:    "See a.go\n\n\n\nc.go",
+		},
+		{
+			name:    "rst literal block and code directive",
+			format:  NewRSTFormat(),
+			content: "Example::\n\n    a.go\n\nSee b.go\n\n.. code-block:: go\n   :linenos:\n\n   c.go\n\nd.go",
+			want:    "Example::\n\n\n\nSee b.go\n\n.. code-block:: go\n\n\n\n\nd.go",
+		},
+		{
+			name:    "rst comment",
+			format:  NewRSTFormat(),
+			content: ".. old notes\n   a.go\n\n.. _label:\n\nb.go",
+			want:    "\n\n\n.. _label:\n\nb.go",
+		},
+		{
+			name:    "asciidoc listing and comments",
+			format:  NewAsciiDocFormat(),
+			content: "See a.go\n[source,go]\n----\nb.go\n----\n// c.go\n////\nd.go\n////\ne.go",
+			want:    "See a.go\n[source,go]\n\n\n\n\n\n\n\ne.go",
+		},
+		{
+			name:    "text is all prose",
+			format:  NewTextFormat(),
+			content: "    a.go\n```\nb.go",
+			want:    "    a.go\n```\nb.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.format.Prose([]byte(tt.content))); got != tt.want {
+				t.Errorf("Prose() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		want    []docparse.Link
+	}{
+		{
+			name:    "markdown inline links and images",
+			format:  NewMarkdownFormat(),
+			content: "See [the handler](../src/handler.go#L3) and ![chart](<img/a b.png> \"Chart\").",
+			want:    []docparse.Link{{Target: "../src/handler.go#L3", Line: 1}, {Target: "img/a b.png", Line: 1}},
+		},
+		{
+			name:    "rst references, targets and includes",
+			format:  NewRSTFormat(),
+			content: "See `the handler <../src/handler.go>`_.\n\n.. _util: ../src/util.go\n.. literalinclude:: ../src/main.py",
+			want: []docparse.Link{
+				{Target: "../src/handler.go", Line: 1},
+				{Target: "../src/util.go", Line: 3},
+				{Target: "../src/main.py", Line: 4},
+			},
+		},
+		{
+			name:    "asciidoc macros, includes and cross references",
+			format:  NewAsciiDocFormat(),
+			content: "See link:../src/handler.go[handler] and <<api.adoc#auth,Auth>>, not <<auth>>.\ninclude::../src/util.go[]",
+			want: []docparse.Link{
+				{Target: "../src/handler.go", Line: 1},
+				{Target: "api.adoc#auth", Line: 1},
+				{Target: "../src/util.go", Line: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.format.Links(tt.format.Prose([]byte(tt.content)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Links() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		want    []docparse.Heading
+	}{
+		{
+			name:   "rst adornment levels and labels",
+			format: NewRSTFormat(),
+			content: strings.Join([]string{
+				"=====",
+				"Guide",
+				"=====",
+				"",
+				"Rate Limits & Quotas",
+				"--------------------",
+				"",
+				"::",
+				"",
+				"    Not a title",
+				"    -----------",
+				"",
+				".. _auth:",
+				"",
+				"Authentication",
+				"--------------",
+				"",
+			}, "\n"),
+			want: []docparse.Heading{
+				{Level: 1, Title: "Guide", Anchor: "guide", Line: 2, End: 16},
+				{Level: 2, Title: "Rate Limits & Quotas", Anchor: "rate-limits-quotas", Line: 5, End: 14},
+				{Level: 2, Title: "Authentication", Anchor: "auth", Line: 15, End: 16},
+			},
+		},
+		{
+			name:   "asciidoc levels and anchors",
+			format: NewAsciiDocFormat(),
+			content: strings.Join([]string{
+				"= Guide",
+				"",
+				"== Rate Limits & Quotas",
+				"",
+				"----",
+				"== not a title",
+				"----",
+				"",
+				"[[auth]]",
+				"== Authentication",
+				"",
+				"=== Overview",
+				"",
+				"== Overview",
+			}, "\n"),
+			want: []docparse.Heading{
+				{Level: 1, Title: "Guide", Anchor: "_guide", Line: 1, End: 14},
+				{Level: 2, Title: "Rate Limits & Quotas", Anchor: "_rate_limits_quotas", Line: 3, End: 9},
+				{Level: 2, Title: "Authentication", Anchor: "auth", Line: 10, End: 13},
+				{Level: 3, Title: "Overview", Anchor: "_overview", Line: 12, End: 13},
+				{Level: 2, Title: "Overview", Anchor: "_overview_2", Line: 14, End: 14},
+			},
+		},
+		{
+			name:    "text has no sections",
+			format:  NewTextFormat(),
+			content: "Title\n=====\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.format.Headings([]byte(tt.content))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Headings() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package docformat
+
+import "github.com/StevenBock/docdiff/internal/docparse"
+
+// markdown is CommonMark/GitHub Markdown, and MDX under its own name: MDX's
+// JSX and imports read as prose, where a path mention in an import counts.
+type markdown struct {
+	name       string
+	extensions []string
+}
+
+func NewMarkdownFormat() Format {
+	return &markdown{name: "markdown", extensions: []string{".md", ".markdown"}}
+}
+
+func NewMDXFormat() Format {
+	return &markdown{name: "mdx", extensions: []string{".mdx"}}
+}
+
+func (m *markdown) Name() string {
+	return m.name
+}
+
+func (m *markdown) Extensions() []string {
+	return m.extensions
+}
+
+// Prose blanks fenced and indented code blocks and HTML comments.
+func (m *markdown) Prose(content []byte) []byte {
+	return docparse.MarkdownProse(content)
+}
+
+// Links finds inline and reference-style links and images.
+func (m *markdown) Links(prose []byte) []docparse.Link {
+	return docparse.MarkdownLinks(prose)
+}
+
+func (m *markdown) Headings(content []byte) []docparse.Heading {
+	return docparse.Headings(content)
+}
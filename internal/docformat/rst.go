@@ -0,0 +1,142 @@
+package docformat
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+)
+
+// rst is reStructuredText, as Sphinx and docutils read it.
+type rst struct{}
+
+func NewRSTFormat() Format {
+	return &rst{}
+}
+
+func (r *rst) Name() string {
+	return "rst"
+}
+
+func (r *rst) Extensions() []string {
+	return []string{".rst", ".rest"}
+}
+
+var (
+	rstCodeDirective = regexp.MustCompile(`^\s*\.\.\s+(?:code|code-block|sourcecode|literalinclude|highlight)::`)
+	rstComment       = regexp.MustCompile(`^\s*\.\.(?:\s|$)`)
+)
+
+// Prose blanks literal blocks, introduced by a paragraph ending in `::`, the
+// bodies of code directives, and comments: each runs over the following lines
+// indented deeper than the line that opened it.
+func (r *rst) Prose(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	code := make([]bool, len(lines))
+	open := -1 // indentation of the line opening a block, or -1 outside one
+	for i, line := range lines {
+		if open >= 0 {
+			if strings.TrimSpace(line) == "" || indentation(line) > open {
+				code[i] = true
+				continue
+			}
+			open = -1
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case rstCodeDirective.MatchString(line):
+			open = indentation(line)
+		case rstComment.MatchString(line) && !strings.Contains(trimmed, "::") && !strings.HasPrefix(trimmed, ".. _"):
+			code[i] = true
+			open = indentation(line)
+		case strings.HasSuffix(trimmed, "::") && !strings.HasPrefix(trimmed, ".."):
+			open = indentation(line)
+		}
+	}
+	return blankLines(lines, code)
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+var (
+	rstEmbeddedTarget = regexp.MustCompile("`[^`<]*<([^>`]+)>`__?")
+	rstTargetDef      = regexp.MustCompile(`^\s*\.\.\s+_[^:]+:\s+(\S+)\s*$`)
+	rstInclude        = regexp.MustCompile(`^\s*\.\.\s+(?:include|literalinclude)::\s+(\S+)`)
+)
+
+// Links finds hyperlink references with an embedded target
+// (`text <target>`_), hyperlink target definitions (.. _name: target), and
+// included files.
+func (r *rst) Links(prose []byte) []docparse.Link {
+	var links []docparse.Link
+	for i, line := range strings.Split(string(prose), "\n") {
+		for _, m := range rstEmbeddedTarget.FindAllStringSubmatch(line, -1) {
+			links = append(links, docparse.Link{Target: m[1], Line: i + 1})
+		}
+		for _, re := range []*regexp.Regexp{rstTargetDef, rstInclude} {
+			if m := re.FindStringSubmatch(line); m != nil {
+				links = append(links, docparse.Link{Target: m[1], Line: i + 1})
+			}
+		}
+	}
+	return links
+}
+
+var (
+	rstAdornment = regexp.MustCompile(`^([!-/:-@\[-` + "`" + `{-~])+$`)
+	rstLabel     = regexp.MustCompile(`^\.\.\s+_([^:]+):\s*$`)
+	rstIDStrip   = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// Headings reads section titles underlined, and optionally overlined, with a
+// repeated punctuation character. Levels follow the order adornment styles
+// first appear in, as in docutils. Anchors are docutils ids (lowercase,
+// punctuation runs to `-`), or the label of a `.. _label:` line just above.
+func (r *rst) Headings(content []byte) []docparse.Heading {
+	lines := strings.Split(string(content), "\n")
+	prose := strings.Split(string(r.Prose(content)), "\n")
+	var headings []docparse.Heading
+	levels := make(map[string]int) // adornment style -> level
+	ids := newAnchors("-", 1)
+	label := ""
+	for i := 0; i+1 < len(lines); i++ {
+		title := strings.TrimSpace(prose[i])
+		if m := rstLabel.FindStringSubmatch(title); m != nil {
+			label = strings.ToLower(strings.TrimSpace(m[1]))
+			continue
+		}
+		under := strings.TrimRight(prose[i+1], " \t\r")
+		if title == "" || isAdornment(title) || !isAdornment(under) || len([]rune(under)) < len([]rune(title)) || indentation(prose[i]) > 0 {
+			if title != "" && !isAdornment(title) {
+				label = "" // a label only names the section right below it
+			}
+			continue
+		}
+		style := under[:1]
+		if i > 0 && strings.TrimRight(prose[i-1], " \t\r") == under {
+			style += "/" // overlined
+		}
+		level, ok := levels[style]
+		if !ok {
+			level = len(levels) + 1
+			levels[style] = level
+		}
+		anchor := label
+		if anchor == "" {
+			anchor = ids.unique(strings.Trim(rstIDStrip.ReplaceAllString(strings.ToLower(title), "-"), "-"))
+		}
+		headings = append(headings, docparse.Heading{Level: level, Title: title, Anchor: anchor, Line: i + 1})
+		label = ""
+		i++ // the underline
+	}
+	docparse.CloseSections(headings, lines)
+	return headings
+}
+
+// isAdornment reports whether line is a run of one repeated punctuation
+// character, at least two long.
+func isAdornment(line string) bool {
+	return len(line) >= 2 && rstAdornment.MatchString(line) && strings.Count(line, line[:1]) == len(line)
+}
@@ -0,0 +1,36 @@
+package docformat
+
+import "github.com/StevenBock/docdiff/internal/docparse"
+
+// text is plain text: all prose, with no links or sections to find. Path
+// mentions in it still count. It is location-bound: a .txt file is a doc only
+// under docs_directory or matching doc_globs.
+type text struct{}
+
+func NewTextFormat() Format {
+	return &text{}
+}
+
+func (t *text) Name() string {
+	return "text"
+}
+
+func (t *text) Extensions() []string {
+	return []string{".txt"}
+}
+
+func (t *text) LocationBound() bool {
+	return true
+}
+
+func (t *text) Prose(content []byte) []byte {
+	return content
+}
+
+func (t *text) Links(prose []byte) []docparse.Link {
+	return nil
+}
+
+func (t *text) Headings(content []byte) []docparse.Heading {
+	return nil
+}
@@ -0,0 +1,52 @@
+package docparse
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontMatter is the `docdiff:` key of a doc's YAML front matter:
+//
+//	---
+//	docdiff:
+//	  sources: ["src/billing/**", "schemas/invoice.json"]
+//	---
+type FrontMatter struct {
+	Sources []string `yaml:"sources"` // globs naming the files the doc describes
+}
+
+// ParseFrontMatter reads the docdiff key of the front matter opening content.
+// A doc without front matter, or whose front matter has no docdiff key, gives
+// the zero FrontMatter.
+func ParseFrontMatter(content []byte) (FrontMatter, error) {
+	lines := strings.Split(string(content), "\n")
+	end := frontMatterEnd(lines)
+	if end == 0 {
+		return FrontMatter{}, nil
+	}
+	var doc struct {
+		Docdiff FrontMatter `yaml:"docdiff"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end-1], "\n")), &doc); err != nil {
+		return FrontMatter{}, fmt.Errorf("front matter: %w", err)
+	}
+	return doc.Docdiff, nil
+}
+
+// frontMatterEnd returns the number of lines the front matter block takes,
+// delimiters included, or 0 when lines don't open with one. The block starts
+// with a `---` line and ends with the next `---` or `...` line.
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(strings.TrimPrefix(lines[0], "\ufeff"), " \t\r") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		switch strings.TrimRight(lines[i], " \t\r") {
+		case "---", "...":
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package docparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "flow mapping",
+			content: "---\ntitle: Billing\ndocdiff: { sources: [\"src/billing/**\", \"schemas/invoice.json\"] }\n---\n# Billing\n",
+			want:    []string{"src/billing/**", "schemas/invoice.json"},
+		},
+		{
+			name:    "block list, dots close",
+			content: "---\ndocdiff:\n  sources:\n    - src/api/*.go\n...\n",
+			want:    []string{"src/api/*.go"},
+		},
+		{name: "no docdiff key", content: "---\ntitle: Guide\n---\n# Guide\n"},
+		{name: "no front matter", content: "# Guide\n---\ndocdiff: {sources: [x]}\n---\n"},
+		{name: "unterminated", content: "---\ndocdiff: {sources: [x]}\n"},
+		{name: "invalid yaml", content: "---\ndocdiff: [\n---\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFrontMatter([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFrontMatter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Sources, tt.want) {
+				t.Errorf("Sources = %v, want %v", got.Sources, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadings_SkipsFrontMatter(t *testing.T) {
+	got := Headings([]byte("---\ntitle: Billing\n---\n# Billing\n"))
+	want := []Heading{{Level: 1, Title: "Billing", Anchor: "billing", Line: 4, End: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headings() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package docparse
+
+import (
+	"path"
+	"strings"
+)
+
+// Link is an explicit link in a doc, such as [text](../src/app.go), with its
+// target as written.
+type Link struct {
+	Target string
+	Line   int
+}
+
+// ResolveLink turns a link target in doc into the repository-relative path it
+// points at. Targets are relative to doc's directory, or to the repository
+// root with a leading slash; a #fragment or ?query is dropped. URLs, mailto:
+// and other schemes, bare fragments, and paths that climb out of the
+// repository don't resolve.
+func ResolveLink(doc, target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	if i := strings.IndexAny(target, "#?"); i >= 0 {
+		target = target[:i]
+	}
+	if target == "" || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") {
+		return "", false
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimLeft(target, "/")
+	} else {
+		target = path.Join(path.Dir(doc), target)
+	}
+	target = path.Clean(target)
+	if target == "." || target == ".." || strings.HasPrefix(target, "../") {
+		return "", false
+	}
+	return target, true
+}
+
+// AppendLinks adds a reference for each link in doc whose target resolves
+// (see ResolveLink) to a known file not already among refs. A line anchor
+// (see LineAnchor) sets the reference's Start and End, including on an
+// earlier unanchored reference to the same file.
+func (p *Parser) AppendLinks(refs []FileReference, doc string, links []Link) []FileReference {
+	seen := make(map[string]int, len(refs))
+	for i, r := range refs {
+		seen[r.Path] = i
+	}
+	for _, l := range links {
+		file, ok := ResolveLink(doc, l.Target)
+		if !ok || !p.knownFiles[file] {
+			continue
+		}
+		start, end, _ := LineAnchor(l.Target)
+		if i, ok := seen[file]; ok {
+			if refs[i].Start == 0 {
+				refs[i].Start, refs[i].End = start, end
+			}
+			continue
+		}
+		seen[file] = len(refs)
+		refs = append(refs, FileReference{Path: file, Line: l.Line, Start: start, End: end})
+	}
+	return refs
+}
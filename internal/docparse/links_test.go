@@ -0,0 +1,39 @@
+package docparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveLink(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"../src/handler.go", "src/handler.go", true},
+		{"./guide.md#setup", "docs/guide.md", true},
+		{"/src/util.go?plain=1", "src/util.go", true},
+		{"api.md", "docs/api.md", true},
+		{"https://example.com/src/a.go", "", false},
+		{"mailto:team@example.com", "", false},
+		{"#section", "", false},
+		{"../../outside.go", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ResolveLink("docs/README.md", tt.target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ResolveLink(docs/README.md, %q) = %q, %v, want %q, %v", tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParser_AppendLinks(t *testing.T) {
+	p := New([]string{"src/a.go", "src/b.go"}, []string{".go"})
+	refs := []FileReference{{Path: "src/a.go", Line: 1}}
+	links := []Link{{Target: "../src/a.go#L5-L9", Line: 2}, {Target: "../src/b.go", Line: 3}, {Target: "../src/c.go", Line: 4}}
+	want := []FileReference{{Path: "src/a.go", Line: 1, Start: 5, End: 9}, {Path: "src/b.go", Line: 3}}
+	if got := p.AppendLinks(refs, "docs/API.md", links); !reflect.DeepEqual(got, want) {
+		t.Errorf("AppendLinks() = %+v, want %+v", got, want)
+	}
+}
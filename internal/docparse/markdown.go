@@ -0,0 +1,223 @@
+package docparse
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	fenceOpen  = regexp.MustCompile("^([ \t]*)(`{3,}|~{3,})(.*)$")
+	listMarker = regexp.MustCompile(`^([ \t]*)(?:[-+*]|\d{1,9}[.)])([ \t]+|$)`)
+)
+
+// MarkdownProse returns content with its code blanked in place: fenced blocks
+// (``` or ~~~, closed by a fence of the same character at least as long),
+// indented code blocks and HTML comments. Line numbers still match the doc.
+func MarkdownProse(content []byte) []byte {
+	lines := strings.Split(string(content), "\n")
+	out := make([]string, len(lines))
+	fence := ""        // the open fence's marker, or "" outside a fence
+	listIndent := 0    // content column of the enclosing list item, or 0
+	comment := false   // inside a multi-line HTML comment
+	afterBreak := true // previous line can't continue a paragraph
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+				fence = ""
+				afterBreak = true
+			}
+			continue
+		}
+		if comment {
+			end := strings.Index(line, "-->")
+			if end < 0 {
+				continue
+			}
+			comment = false
+			line = strings.Repeat(" ", end+3) + line[end+3:]
+			trimmed = strings.TrimSpace(line)
+		}
+		line, comment = stripComments(line)
+		if strings.TrimSpace(line) == "" {
+			afterBreak = trimmed == "" || afterBreak
+			out[i] = line
+			continue
+		}
+
+		indent := columns(line)
+		if m := fenceOpen.FindStringSubmatch(line); m != nil && columns(m[1]) <= listIndent+3 &&
+			!(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+			fence = m[2]
+			continue
+		}
+		if indent >= listIndent+4 && afterBreak {
+			continue // indented code; stays open until a less indented line
+		}
+		if m := listMarker.FindStringSubmatch(line); m != nil {
+			listIndent = len(m[0])
+			if m[2] == "" {
+				listIndent++
+			}
+		} else if indent == 0 && (afterBreak || listIndent == 0) {
+			listIndent = 0
+		}
+		afterBreak = atxHeading.MatchString(line)
+		out[i] = line
+	}
+	return []byte(strings.Join(out, "\n"))
+}
+
+// stripComments blanks the HTML comments on line, reporting whether the last
+// one is still open at the end of the line.
+func stripComments(line string) (string, bool) {
+	for {
+		start := strings.Index(line, "<!--")
+		if start < 0 {
+			return line, false
+		}
+		end := strings.Index(line[start+4:], "-->")
+		if end < 0 {
+			return line[:start], true
+		}
+		end += start + 4 + 3
+		line = line[:start] + strings.Repeat(" ", end-start) + line[end:]
+	}
+}
+
+// columns is the width of line's leading whitespace, with tabs to the next
+// multiple of four.
+func columns(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+var (
+	// inlineLink matches the target of an inline link or image:
+	// [text](target) or [text](<target> "title").
+	inlineLink = regexp.MustCompile(`\]\(\s*(?:<([^>]*)>|([^)\s]+))(?:\s+(?:"[^"]*"|'[^']*'))?\s*\)`)
+	// linkDefinition matches a reference definition: [label]: target "title".
+	linkDefinition = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*(?:<([^>]*)>|(\S+))`)
+	// linkReference matches [text][label], [label][] and [label] shortcuts.
+	linkReference = regexp.MustCompile(`\[([^\[\]]+)\](?:\[([^\[\]]*)\])?`)
+)
+
+// MarkdownLinks returns the links in Markdown prose (see MarkdownProse):
+// inline links and images, and reference-style links whose label has a
+// definition. A reference counts on the line that uses it, or on its
+// definition's line when nothing does. Link syntax inside code spans is text.
+func MarkdownLinks(prose []byte) []Link {
+	lines := strings.Split(string(prose), "\n")
+	definitions := make(map[string]Link)
+	for i, line := range lines {
+		if m := linkDefinition.FindStringSubmatch(stripCodeSpans(line)); m != nil {
+			label := linkLabel(m[1])
+			if _, ok := definitions[label]; !ok {
+				definitions[label] = Link{Target: m[2] + m[3], Line: i + 1}
+			}
+		}
+	}
+
+	var links []Link
+	used := make(map[string]bool)
+	for i, line := range lines {
+		line = stripCodeSpans(line)
+		if linkDefinition.MatchString(line) {
+			continue
+		}
+		for _, m := range inlineLink.FindAllStringSubmatch(line, -1) {
+			if target := m[1] + m[2]; target != "" {
+				links = append(links, Link{Target: target, Line: i + 1})
+			}
+		}
+		for _, loc := range linkReference.FindAllStringSubmatchIndex(line, -1) {
+			if loc[1] < len(line) && line[loc[1]] == '(' {
+				continue // an inline link's text
+			}
+			label := line[loc[2]:loc[3]]
+			if loc[4] >= 0 && loc[5] > loc[4] {
+				label = line[loc[4]:loc[5]]
+			}
+			def, ok := definitions[linkLabel(label)]
+			if !ok {
+				continue
+			}
+			used[linkLabel(label)] = true
+			links = append(links, Link{Target: def.Target, Line: i + 1})
+		}
+	}
+	for label, def := range definitions {
+		if !used[label] {
+			links = append(links, def)
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Line < links[j].Line })
+	return links
+}
+
+// linkLabel normalizes a reference label: case-insensitive, with runs of
+// whitespace as one space.
+func linkLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// stripCodeSpans blanks the code spans on line: text between two runs of
+// backticks of the same length.
+func stripCodeSpans(line string) string {
+	b := []byte(line)
+	open, openLen := -1, 0
+	for i := 0; i < len(b); {
+		if b[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for i < len(b) && b[i] == '`' {
+			i++
+		}
+		switch {
+		case open < 0:
+			open, openLen = run, i-run
+		case i-run == openLen:
+			for j := open; j < i; j++ {
+				b[j] = ' '
+			}
+			open = -1
+		}
+	}
+	return string(b)
+}
+
+var lineAnchor = regexp.MustCompile(`^L(\d+)(?:C\d+)?(?:-L?(\d+)(?:C\d+)?)?$`)
+
+// LineAnchor returns the source lines a link target's GitHub-style fragment
+// points at: #L40 is line 40, #L10-L20 lines 10 through 20. ok is false for
+// any other fragment, or none.
+func LineAnchor(target string) (start, end int, ok bool) {
+	_, fragment := SplitAnchor(target)
+	m := lineAnchor.FindStringSubmatch(fragment)
+	if m == nil {
+		return 0, 0, false
+	}
+	start, _ = strconv.Atoi(m[1])
+	end = start
+	if m[2] != "" {
+		end, _ = strconv.Atoi(m[2])
+	}
+	if start == 0 || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
@@ -0,0 +1,89 @@
+package docparse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownProse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "tilde fence closed only by a long enough tilde fence",
+			content: "a.go\n~~~~\nb.go\n```\n~~~\nc.go\n~~~~\nd.go",
+			want:    "a.go\n\n\n\n\n\n\nd.go",
+		},
+		{
+			name:    "indented code after a blank line",
+			content: "See a.go\n\n    b.go\n\tc.go\n\nd.go",
+			want:    "See a.go\n\n\n\n\nd.go",
+		},
+		{
+			name:    "indented paragraph continuation is prose",
+			content: "See a.go and\n    b.go",
+			want:    "See a.go and\n    b.go",
+		},
+		{
+			name:    "list item paragraphs are prose",
+			content: "- first a.go\n\n    more b.go\n\n        c.go",
+			want:    "- first a.go\n\n    more b.go\n\n",
+		},
+		{
+			name:    "html comments",
+			content: "a.go <!-- b.go --> c.go\n<!--\nd.go\n-->e.go",
+			want:    "a.go" + strings.Repeat(" ", 15) + "c.go\n\n\n   e.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(MarkdownProse([]byte(tt.content))); got != tt.want {
+				t.Errorf("MarkdownProse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	content := strings.Join([]string{
+		"See [the handler](../src/handler.go#L40-L60) and [util][u].",
+		"Also [Config] and `[not](a/link.go)`.",
+		"",
+		"[u]: ../src/util.go",
+		"[config]: <../src/config.go> \"Config\"",
+		"[unused]: ../src/unused.go",
+	}, "\n")
+	want := []Link{
+		{Target: "../src/handler.go#L40-L60", Line: 1},
+		{Target: "../src/util.go", Line: 1},
+		{Target: "../src/config.go", Line: 2},
+		{Target: "../src/unused.go", Line: 6},
+	}
+	if got := MarkdownLinks(MarkdownProse([]byte(content))); !reflect.DeepEqual(got, want) {
+		t.Errorf("MarkdownLinks() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLineAnchor(t *testing.T) {
+	tests := []struct {
+		target     string
+		start, end int
+		ok         bool
+	}{
+		{"../src/handler.go#L40-L60", 40, 60, true},
+		{"../src/handler.go#L12", 12, 12, true},
+		{"../src/handler.go#L3C5-L7C2", 3, 7, true},
+		{"../src/handler.go#L9-L2", 0, 0, false},
+		{"guide.md#setup", 0, 0, false},
+		{"../src/handler.go", 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, ok := LineAnchor(tt.target)
+		if start != tt.start || end != tt.end || ok != tt.ok {
+			t.Errorf("LineAnchor(%q) = %d, %d, %v, want %d, %d, %v", tt.target, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
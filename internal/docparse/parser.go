@@ -8,14 +8,21 @@ import (
 )
 
 type FileReference struct {
-	Path string
-	Line int
+	Path   string
+	Line   int
+	Symbol string // the code span naming a symbol Path declares; "" for a path mention
+
+	// Start and End are the lines of Path a link's #L10-L20 anchor points
+	// at; 0 when the reference isn't a line-anchored link.
+	Start int
+	End   int
 }
 
 type Parser struct {
 	knownFiles map[string]bool
 	extensions []string
 	pattern    *regexp.Regexp
+	symbols    *SymbolIndex
 }
 
 func New(knownFiles []string, extensions []string) *Parser {
@@ -33,6 +40,14 @@ func New(knownFiles []string, extensions []string) *Parser {
 	}
 }
 
+// WithSymbols makes Parse also resolve code spans naming a declared symbol,
+// such as `InvoiceTotal`, to the one known file declaring it. A nil index
+// leaves Parse matching paths only.
+func (p *Parser) WithSymbols(index *SymbolIndex) *Parser {
+	p.symbols = index
+	return p
+}
+
 func buildExtensionPattern(extensions []string) *regexp.Regexp {
 	if len(extensions) == 0 {
 		return nil
@@ -57,7 +72,20 @@ func buildExtensionPattern(extensions []string) *regexp.Regexp {
 }
 
 func (p *Parser) Parse(content []byte) []FileReference {
-	if p.pattern == nil {
+	return p.parse(content, true)
+}
+
+// Mentions returns every path-like mention in content with one of the
+// parser's extensions, whether or not the file is known, deduplicated in
+// order. Code spans aren't resolved as symbols.
+func (p *Parser) Mentions(content []byte) []FileReference {
+	return p.parse(content, false)
+}
+
+// parse walks content's lines outside code fences. With knownOnly, a path
+// must be a known file and code spans resolve through the symbol index.
+func (p *Parser) parse(content []byte, knownOnly bool) []FileReference {
+	if p.pattern == nil && (p.symbols == nil || !knownOnly) {
 		return nil
 	}
 
@@ -81,7 +109,10 @@ func (p *Parser) Parse(content []byte) []FileReference {
 			continue
 		}
 
-		matches := p.pattern.FindAllStringSubmatch(line, -1)
+		var matches [][]string
+		if p.pattern != nil {
+			matches = p.pattern.FindAllStringSubmatch(line, -1)
+		}
 		for _, match := range matches {
 			if len(match) < 3 {
 				continue
@@ -97,7 +128,7 @@ func (p *Parser) Parse(content []byte) []FileReference {
 				continue
 			}
 
-			if !p.knownFiles[fullPath] {
+			if knownOnly && !p.knownFiles[fullPath] {
 				continue
 			}
 
@@ -111,11 +142,30 @@ func (p *Parser) Parse(content []byte) []FileReference {
 				Line: lineNum,
 			})
 		}
+
+		if knownOnly && p.symbols != nil {
+			refs = p.appendSymbolRefs(refs, seen, line, lineNum)
+		}
 	}
 
 	return refs
 }
 
+// appendSymbolRefs adds a reference for each code span on line that names a
+// symbol exactly one known file declares.
+func (p *Parser) appendSymbolRefs(refs []FileReference, seen map[string]bool, line string, lineNum int) []FileReference {
+	for _, m := range codeSpan.FindAllStringSubmatch(line, -1) {
+		name := SpanIdentifier(strings.TrimSpace(m[1]))
+		file, ok := p.symbols.Resolve(name)
+		if !ok || !p.knownFiles[file] || seen[file] {
+			continue
+		}
+		seen[file] = true
+		refs = append(refs, FileReference{Path: file, Line: lineNum, Symbol: name})
+	}
+	return refs
+}
+
 func normalizePath(path string) string {
 	path = strings.ReplaceAll(path, "\\", "/")
 	path = strings.TrimPrefix(path, "./")
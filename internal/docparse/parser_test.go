@@ -1,6 +1,7 @@
 package docparse
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -202,3 +203,16 @@ func TestNormalizePath(t *testing.T) {
 		}
 	}
 }
+
+func TestParser_Mentions(t *testing.T) {
+	p := New([]string{"src/a.go"}, []string{".go"})
+	content := "See src/a.go and src/gone.go here.\n```\nsrc/fenced.go\n```\nAgain src/gone.go, `src/old.go`\n"
+	want := []FileReference{
+		{Path: "src/a.go", Line: 1},
+		{Path: "src/gone.go", Line: 1},
+		{Path: "src/old.go", Line: 5},
+	}
+	if got := p.Mentions([]byte(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %+v, want %+v", got, want)
+	}
+}
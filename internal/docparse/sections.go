@@ -0,0 +1,131 @@
+package docparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Heading is a Markdown heading and the section it opens: from its own line
+// through the line before the next heading of the same or a higher level, or
+// the end of the document.
+type Heading struct {
+	Level  int
+	Title  string
+	Anchor string // GitHub-style slug, or an explicit `{#id}`
+	Line   int    // 1-based line of the heading (the text line for setext)
+	End    int    // 1-based last line of the section
+}
+
+var (
+	atxHeading     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextUnder    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	explicitAnchor = regexp.MustCompile(`[ \t]*\{#([^}\s]+)\}$`)
+	slugStrip      = regexp.MustCompile(`[^\p{L}\p{N}\s_-]`)
+)
+
+// Headings parses ATX (`## Title`) and setext (`Title` over `===`/`---`)
+// headings, skipping front matter, code blocks and HTML comments. Anchors
+// follow GitHub's scheme, with `-1`, `-2`, ... suffixes for repeated titles.
+func Headings(content []byte) []Heading {
+	lines := strings.Split(string(content), "\n")
+	var headings []Heading
+	seen := make(map[string]int)
+	add := func(level int, title string, line int) {
+		anchor := ""
+		if m := explicitAnchor.FindStringSubmatch(title); m != nil {
+			title = strings.TrimSpace(title[:len(title)-len(m[0])])
+			anchor = m[1]
+		} else {
+			anchor = Slug(title)
+			if n := seen[anchor]; n > 0 {
+				seen[anchor] = n + 1
+				anchor = fmt.Sprintf("%s-%d", anchor, n)
+			} else {
+				seen[anchor] = 1
+			}
+		}
+		headings = append(headings, Heading{Level: level, Title: title, Anchor: anchor, Line: line})
+	}
+
+	prose := strings.Split(string(MarkdownProse(content)), "\n")
+	skip := frontMatterEnd(lines)
+	for i, line := range prose {
+		if i < skip {
+			continue
+		}
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			add(len(m[1]), strings.TrimSpace(m[2]), i+1)
+			continue
+		}
+		if m := setextUnder.FindStringSubmatch(line); m != nil && i > 0 && isParagraphLine(prose[i-1]) {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			add(level, strings.TrimSpace(prose[i-1]), i)
+		}
+	}
+
+	CloseSections(headings, lines)
+	return headings
+}
+
+// CloseSections sets each heading's End: the line before the next heading of
+// the same or a higher level, or the last line of the document.
+func CloseSections(headings []Heading, lines []string) {
+	last := len(lines)
+	if last > 0 && lines[last-1] == "" {
+		last-- // trailing newline
+	}
+	for i := range headings {
+		headings[i].End = last
+		for _, next := range headings[i+1:] {
+			if next.Level <= headings[i].Level {
+				headings[i].End = next.Line - 1
+				break
+			}
+		}
+	}
+}
+
+// isParagraphLine reports whether line can be the text of a setext heading.
+func isParagraphLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" && !atxHeading.MatchString(line) && !setextUnder.MatchString(line) &&
+		!strings.HasPrefix(trimmed, ">") && !strings.HasPrefix(trimmed, "- ") && !strings.HasPrefix(trimmed, "* ")
+}
+
+// Section returns the heading whose anchor matches (case-insensitively, with
+// or without a leading '#').
+func Section(content []byte, anchor string) (Heading, bool) {
+	return FindSection(Headings(content), anchor)
+}
+
+// FindSection is Section over already parsed headings, in any doc format.
+func FindSection(headings []Heading, anchor string) (Heading, bool) {
+	anchor = strings.ToLower(strings.TrimPrefix(anchor, "#"))
+	for _, h := range headings {
+		if strings.ToLower(h.Anchor) == anchor {
+			return h, true
+		}
+	}
+	return Heading{}, false
+}
+
+// Slug turns a heading title into its GitHub anchor: lowercase, punctuation
+// dropped, spaces to hyphens.
+func Slug(title string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+	title = slugStrip.ReplaceAllString(title, "")
+	return strings.ReplaceAll(title, " ", "-")
+}
+
+// SplitAnchor splits a doc target such as `docs/API.md#authentication` into
+// the file path and the heading anchor ("" when the target is the whole doc).
+func SplitAnchor(target string) (string, string) {
+	if i := strings.IndexByte(target, '#'); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
@@ -0,0 +1,74 @@
+package docparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHeadings(t *testing.T) {
+	content := `# API
+
+Intro.
+
+## Authentication
+
+Tokens.
+
+### Token refresh
+
+` + "```sh\n# not a heading\n```" + `
+
+## Rate Limits & Quotas {#limits}
+
+Setext Title
+------------
+
+## Authentication
+`
+	got := Headings([]byte(content))
+	want := []Heading{
+		{Level: 1, Title: "API", Anchor: "api", Line: 1, End: 20},
+		{Level: 2, Title: "Authentication", Anchor: "authentication", Line: 5, End: 14},
+		{Level: 3, Title: "Token refresh", Anchor: "token-refresh", Line: 9, End: 14},
+		{Level: 2, Title: "Rate Limits & Quotas", Anchor: "limits", Line: 15, End: 16},
+		{Level: 2, Title: "Setext Title", Anchor: "setext-title", Line: 17, End: 19},
+		{Level: 2, Title: "Authentication", Anchor: "authentication-1", Line: 20, End: 20},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headings() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestSection(t *testing.T) {
+	content := []byte("# Doc\n\n## Getting Started\ntext\n## Next\n")
+	h, ok := Section(content, "#Getting-Started")
+	if !ok || h.Line != 3 || h.End != 4 {
+		t.Errorf("Section() = %+v, %v; want lines 3-4", h, ok)
+	}
+	if _, ok := Section(content, "missing"); ok {
+		t.Error("Section() should not find a missing anchor")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Authentication":         "authentication",
+		"Rate Limits & Quotas":   "rate-limits--quotas",
+		"`docdiff check` output": "docdiff-check-output",
+		"snake_case and-hyphens": "snake_case-and-hyphens",
+	}
+	for in, want := range tests {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSplitAnchor(t *testing.T) {
+	if p, a := SplitAnchor("docs/API.md#authentication"); p != "docs/API.md" || a != "authentication" {
+		t.Errorf("SplitAnchor() = %q, %q", p, a)
+	}
+	if p, a := SplitAnchor("docs/API.md"); p != "docs/API.md" || a != "" {
+		t.Errorf("SplitAnchor() = %q, %q", p, a)
+	}
+}
@@ -0,0 +1,36 @@
+package docparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CodeSpan is the text of an inline code span (`like this`) in a doc.
+type CodeSpan struct {
+	Text string
+	Line int
+}
+
+var codeSpan = regexp.MustCompile("`([^`\n]+)`")
+
+// CodeSpans returns the inline code spans outside fenced code blocks, in
+// order. Docs name identifiers this way: "call `HandleRequest`".
+func CodeSpans(content []byte) []CodeSpan {
+	var spans []CodeSpan
+	inFence := false
+	for i, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, m := range codeSpan.FindAllStringSubmatch(line, -1) {
+			if text := strings.TrimSpace(m[1]); text != "" {
+				spans = append(spans, CodeSpan{Text: text, Line: i + 1})
+			}
+		}
+	}
+	return spans
+}
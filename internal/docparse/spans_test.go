@@ -0,0 +1,18 @@
+package docparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeSpans(t *testing.T) {
+	content := "Call `HandleRequest` or ` Invoice.Total() `.\n\n```go\nx := `raw`\n```\n\nSee `util.go`.\n"
+	want := []CodeSpan{
+		{Text: "HandleRequest", Line: 1},
+		{Text: "Invoice.Total()", Line: 1},
+		{Text: "util.go", Line: 7},
+	}
+	if got := CodeSpans([]byte(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("CodeSpans() = %+v, want %+v", got, want)
+	}
+}
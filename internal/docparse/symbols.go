@@ -0,0 +1,69 @@
+package docparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SymbolIndex maps the names source files declare to the files declaring
+// them, so a doc's `InvoiceTotal` can be traced to billing/invoice.go.
+type SymbolIndex struct {
+	files    map[string][]string // name -> declaring files
+	declared map[string][]string // file -> names it declares
+}
+
+func NewSymbolIndex() *SymbolIndex {
+	return &SymbolIndex{
+		files:    make(map[string][]string),
+		declared: make(map[string][]string),
+	}
+}
+
+// Add records the names file declares.
+func (x *SymbolIndex) Add(file string, names []string) {
+	for _, name := range names {
+		x.files[name] = append(x.files[name], file)
+	}
+	x.declared[file] = append(x.declared[file], names...)
+}
+
+// Resolve returns the file that declares name. ok is false when no file does,
+// or when several do and a mention can't say which one it means.
+func (x *SymbolIndex) Resolve(name string) (file string, ok bool) {
+	files := x.Files(name)
+	if len(files) != 1 {
+		return "", false
+	}
+	return files[0], true
+}
+
+// Files lists the files declaring name.
+func (x *SymbolIndex) Files(name string) []string {
+	if x == nil {
+		return nil
+	}
+	return x.files[name]
+}
+
+// Declared lists the names file declares, in declaration order.
+func (x *SymbolIndex) Declared(file string) []string {
+	if x == nil {
+		return nil
+	}
+	return x.declared[file]
+}
+
+// SpanIdentifier reduces a code span to the name it refers to:
+// `pkg.Handler()` and `Handler` both give Handler; a file name stays whole.
+func SpanIdentifier(text string) string {
+	text = strings.TrimSuffix(text, "()")
+	if fileLike.MatchString(text) || strings.ContainsAny(text, " /") {
+		return text
+	}
+	if i := strings.LastIndexAny(text, ".:"); i >= 0 {
+		text = text[i+1:]
+	}
+	return text
+}
+
+var fileLike = regexp.MustCompile(`^[\w-]+\.[a-z]{1,5}$`)
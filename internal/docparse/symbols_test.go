@@ -0,0 +1,48 @@
+package docparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParser_ParseSymbols(t *testing.T) {
+	index := NewSymbolIndex()
+	index.Add("billing/invoice.go", []string{"InvoiceTotal", "Render"})
+	index.Add("web/render.js", []string{"Render"})
+	index.Add("gone/old.go", []string{"OldThing"})
+
+	content := "# Billing\n\n" +
+		"`billing.InvoiceTotal()` sums the lines; see billing/invoice.go for more.\n" +
+		"`Render` is declared twice, `OldThing` isn't a known file.\n" +
+		"```go\n`InvoiceTotal`\n```\n"
+	p := New([]string{"billing/invoice.go", "web/render.js"}, []string{".go", ".js"}).WithSymbols(index)
+
+	want := []FileReference{{Path: "billing/invoice.go", Line: 3}}
+	if got := p.Parse([]byte(content)); !reflect.DeepEqual(got, want) {
+		t.Errorf("a path and a symbol naming the same file should dedupe: got %+v, want %+v", got, want)
+	}
+
+	want = []FileReference{{Path: "billing/invoice.go", Line: 1, Symbol: "InvoiceTotal"}}
+	if got := p.Parse([]byte("`InvoiceTotal` and `Render`\n")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+
+	if got := New([]string{"billing/invoice.go"}, nil).Parse([]byte("`InvoiceTotal`\n")); got != nil {
+		t.Errorf("without an index, code spans aren't references: %+v", got)
+	}
+}
+
+func TestSpanIdentifier(t *testing.T) {
+	for text, want := range map[string]string{
+		"Handler":        "Handler",
+		"pkg.Handler()":  "Handler",
+		"Foo::bar":       "bar",
+		"handler.go":     "handler.go",
+		"src/handler.go": "src/handler.go",
+		"go test ./...":  "go test ./...",
+	} {
+		if got := SpanIdentifier(text); got != want {
+			t.Errorf("SpanIdentifier(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
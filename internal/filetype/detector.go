@@ -19,7 +19,18 @@ func NewDetector(registry *language.Registry) *Detector {
 	return &Detector{registry: registry}
 }
 
+// Detect picks the language for the file at path, a repo-relative path, with
+// the given content. A file inside a language's configured excludes gets no
+// language at all rather than falling through to the next match.
 func (d *Detector) Detect(path string, content []byte) (language.Strategy, bool) {
+	strategy, ok := d.detect(path, content)
+	if ok && d.registry.Excludes(strategy.Name(), filepath.ToSlash(path)) {
+		return nil, false
+	}
+	return strategy, ok
+}
+
+func (d *Detector) detect(path string, content []byte) (language.Strategy, bool) {
 	if strategy := d.detectShebang(content); strategy != nil {
 		return strategy, true
 	}
@@ -69,7 +80,7 @@ func (d *Detector) detectShebang(content []byte) language.Strategy {
 	}
 
 	interpreter := string(matches[1])
-	if langName, ok := shebangMap[interpreter]; ok {
+	if langName, ok := d.languageForInterpreter(interpreter); ok {
 		if strategy, ok := d.registry.GetByName(langName); ok {
 			return strategy
 		}
@@ -78,6 +89,16 @@ func (d *Detector) detectShebang(content []byte) language.Strategy {
 	return nil
 }
 
+// languageForInterpreter consults interpreters declared in config before the
+// built-in shebang map, so a user-defined language can claim a name.
+func (d *Detector) languageForInterpreter(interpreter string) (string, bool) {
+	if langName, ok := d.registry.LanguageForInterpreter(interpreter); ok {
+		return langName, true
+	}
+	langName, ok := shebangMap[interpreter]
+	return langName, ok
+}
+
 var vimModelinePattern = regexp.MustCompile(`(?:vim?|ex):\s*(?:set\s+)?(?:.*\s)?(?:ft|filetype)=(\w+)`)
 var emacsModelinePattern = regexp.MustCompile(`-\*-\s*(?:mode:\s*)?(\w+).*-\*-`)
 
@@ -112,7 +133,7 @@ func (d *Detector) detectModeline(content []byte) language.Strategy {
 
 	if matches := vimModelinePattern.FindSubmatch(searchArea); len(matches) > 1 {
 		ft := strings.ToLower(string(matches[1]))
-		if langName, ok := modelineMap[ft]; ok {
+		if langName, ok := d.languageForModeline(ft); ok {
 			if strategy, ok := d.registry.GetByName(langName); ok {
 				return strategy
 			}
@@ -121,7 +142,7 @@ func (d *Detector) detectModeline(content []byte) language.Strategy {
 
 	if matches := emacsModelinePattern.FindSubmatch(searchArea); len(matches) > 1 {
 		mode := strings.ToLower(string(matches[1]))
-		if langName, ok := modelineMap[mode]; ok {
+		if langName, ok := d.languageForModeline(mode); ok {
 			if strategy, ok := d.registry.GetByName(langName); ok {
 				return strategy
 			}
@@ -131,6 +152,16 @@ func (d *Detector) detectModeline(content []byte) language.Strategy {
 	return nil
 }
 
+// languageForModeline consults modeline names declared in config before the
+// built-in map.
+func (d *Detector) languageForModeline(mode string) (string, bool) {
+	if langName, ok := d.registry.LanguageForModeline(mode); ok {
+		return langName, true
+	}
+	langName, ok := modelineMap[mode]
+	return langName, ok
+}
+
 func (d *Detector) detectExtension(path string) language.Strategy {
 	ext := strings.ToLower(filepath.Ext(path))
 	if ext == "" {
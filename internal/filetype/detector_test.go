@@ -3,6 +3,7 @@ package filetype
 import (
 	"testing"
 
+	"github.com/StevenBock/docdiff/internal/config"
 	"github.com/StevenBock/docdiff/internal/language"
 )
 
@@ -588,3 +589,61 @@ func TestDetector_PriorityCascade(t *testing.T) {
 		}
 	})
 }
+
+func TestDetector_ConfiguredLanguages(t *testing.T) {
+	registry, err := language.FromConfig(map[string]config.LanguageConfig{
+		"elixir": {
+			Extensions:   []string{".ex", ".exs"},
+			Interpreters: []string{"elixir"},
+			Modelines:    []string{"elixir"},
+			LineComments: []string{"#"},
+		},
+		"terraform": {
+			Extensions:   []string{".tf"},
+			LineComments: []string{"#", "//"},
+		},
+		// Declared interpreters take precedence over the built-in shebang map.
+		"zsh": {
+			Interpreters: []string{"sh"},
+			LineComments: []string{"#"},
+		},
+		"php": {Exclude: []string{"legacy/**"}},
+	})
+	if err != nil {
+		t.Fatalf("FromConfig() error = %v", err)
+	}
+	detector := NewDetector(registry)
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		wantLang string
+	}{
+		{"declared extension", "main.tf", "resource \"x\" \"y\" {}", "terraform"},
+		{"declared shebang", "script", "#!/usr/bin/env elixir\nIO.puts 1", "elixir"},
+		{"declared vim modeline", "script", "# vim: ft=elixir\nIO.puts 1", "elixir"},
+		{"declared emacs modeline", "script", "# -*- mode: elixir -*-\nIO.puts 1", "elixir"},
+		{"declared interpreter overrides built-in", "script", "#!/bin/sh\necho hi", "zsh"},
+		{"outside a language's excludes", "src/index.php", "<?php echo 1;", "php"},
+		{"inside a language's excludes", "legacy/index.php", "<?php echo 1;", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, ok := detector.Detect(tt.path, []byte(tt.content))
+			if tt.wantLang == "" {
+				if ok {
+					t.Errorf("Detect() lang = %s, want none", strategy.Name())
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Detect() found nothing, want %s", tt.wantLang)
+			}
+			if strategy.Name() != tt.wantLang {
+				t.Errorf("Detect() lang = %s, want %s", strategy.Name(), tt.wantLang)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/config"
+)
+
+// fixtureRepo builds a repository with a side branch merged back, a
+// .gitignore, and staged, unstaged, untracked and ignored changes on top.
+// Commit dates are fixed and increasing so history order is unambiguous. It
+// returns the short hashes of the commits by name.
+func fixtureRepo(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	dir := setupGitRepo(t)
+	day := 0
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		date := fmt.Sprintf("2024-01-%02dT12:00:00Z", day+1)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(path, content string) {
+		t.Helper()
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(message string) string {
+		t.Helper()
+		day++
+		git("add", "-A")
+		git("commit", "-q", "-m", message)
+		return git("rev-parse", "--short", "HEAD")
+	}
+
+	code := "package a\n\nfunc One() {}\n\nfunc Two() {}\n\nfunc Three() {}\n\nfunc Four() {}\n"
+	revs := map[string]string{}
+	write("README.md", "# Doc\n\n## Alpha\n\nalpha text\n\n## Beta\n\nbeta text\n")
+	write("src/a.go", code)
+	write(".gitignore", "build/\n*.log\n")
+	revs["root"] = commit("Initial import\n\nWith a body.")
+
+	write("src/a.go", strings.Replace(code, "func Two() {}", "func Two() { return }", 1))
+	write("src/b.go", "package a\n\nvar B = 1\n")
+	revs["second"] = commit("Change Two, add b")
+
+	git("checkout", "-q", "-b", "side")
+	write("src/b.go", "package a\n\nvar B = 2\n")
+	write("docs/side.md", "side\n")
+	revs["side"] = commit("Side: bump B")
+
+	git("checkout", "-q", "-")
+	write("README.md", "# Doc\n\n## Alpha\n\nalpha text\n\n## Beta\n\nbeta text, revised\nmore\n")
+	os.Remove(filepath.Join(dir, "src", "a.go"))
+	write("src/a.go", strings.Replace(code, "func Four() {}", "func Four() {}\n\nfunc Five() {}", 1))
+	revs["main"] = commit("Revise Beta, add Five")
+
+	day++
+	git("merge", "-q", "--no-ff", "-m", "Merge side", "side")
+	revs["merge"] = git("rev-parse", "--short", "HEAD")
+
+	write("src/c.go", "package a\n")
+	revs["head"] = commit("Add c")
+
+	write("src/a.go", strings.Replace(code, "func One() {}", "func One() { _ = 1 }", 1))
+	write("src/b.go", "package a\n\nvar B = 3\n")
+	git("add", "src/b.go")
+	write("src/b.go", "package a\n\nvar B = 4\n")
+	write("notes.txt", "untracked\n")
+	write("build/out.go", "package build\n")
+	write("debug.log", "log\n")
+	return dir, revs
+}
+
+// backends opens every Repo implementation on dir. The exec backend is the
+// reference the others must match.
+func backends(dir string) map[string]Repo {
+	return map[string]Repo{
+		config.GitBackendExec:  New(dir),
+		config.GitBackendGoGit: NewGoGit(dir),
+	}
+}
+
+func TestBackendConformance(t *testing.T) {
+	dir, revs := fixtureRepo(t)
+	files := []string{"README.md", "src/a.go", "src/b.go", "src/c.go", "docs/side.md"}
+
+	queries := map[string]func(r Repo) (any, error){
+		"IsRepo": func(r Repo) (any, error) { return r.IsRepo(), nil },
+		"Dir":    func(r Repo) (any, error) { return r.Dir() },
+		"CheckIgnore": func(r Repo) (any, error) {
+			return r.CheckIgnore([]string{"build/", "build/out.go", "debug.log", "src/a.go", "notes.txt", "src/"})
+		},
+		"HeadShort":    func(r Repo) (any, error) { return r.HeadShort() },
+		"HeadFull":     func(r Repo) (any, error) { return r.HeadFull() },
+		"ResolveShort": func(r Repo) (any, error) { return r.ResolveShort(revs["side"]) },
+		"IsAncestor": func(r Repo) (any, error) {
+			var got []bool
+			for _, pair := range [][2]string{{revs["root"], "HEAD"}, {revs["side"], revs["main"]}, {revs["side"], revs["merge"]}, {"HEAD", "HEAD"}} {
+				anc, err := r.IsAncestor(pair[0], pair[1])
+				if err != nil {
+					return nil, err
+				}
+				got = append(got, anc)
+			}
+			return got, nil
+		},
+		"LastCommit": func(r Repo) (any, error) { return eachFile(files, r.LastCommit) },
+		"LastCommitInRange": func(r Repo) (any, error) {
+			alpha, err := r.LastCommitInRange("README.md", 3, 6)
+			if err != nil {
+				return nil, err
+			}
+			beta, err := r.LastCommitInRange("README.md", 7, 10)
+			return []string{alpha, beta}, err
+		},
+		"LastCommitMatching": func(r Repo) (any, error) { return r.LastCommitMatching("src/a.go", `func Two`) },
+		"CommitInfo":         func(r Repo) (any, error) { return r.CommitInfo(revs["second"]) },
+		"CommitDate":         func(r Repo) (any, error) { return r.CommitDate(revs["second"]) },
+		"CommitSubject":      func(r Repo) (any, error) { return r.CommitSubject(revs["root"]) },
+		"CommitsBetween":     func(r Repo) (any, error) { return r.CommitsBetween(revs["second"], "HEAD", nil) },
+		"CommitsBetween files": func(r Repo) (any, error) {
+			return r.CommitsBetween(revs["root"], "HEAD", []string{"src/b.go"})
+		},
+		"CommitDetails": func(r Repo) (any, error) { return r.CommitDetails(revs["root"], "HEAD", []string{"src"}) },
+		"FileAt": func(r Repo) (any, error) {
+			content, ok, err := r.FileAt(revs["root"], "README.md")
+			_, missing, _ := r.FileAt(revs["root"], "src/c.go")
+			return fmt.Sprintf("%q %v %v", content, ok, missing), err
+		},
+		"ChangedFilesBetween": func(r Repo) (any, error) { return r.ChangedFilesBetween(revs["second"], "HEAD", nil) },
+		"ChangedFilesBetween files": func(r Repo) (any, error) {
+			return r.ChangedFilesBetween(revs["root"], revs["main"], []string{"src/b.go", "README.md"})
+		},
+		"ChangedFilesSince":        func(r Repo) (any, error) { return r.ChangedFilesSince(revs["second"], false, nil) },
+		"ChangedFilesSince staged": func(r Repo) (any, error) { return r.ChangedFilesSince("HEAD", true, nil) },
+		"FilesChangedInCommit": func(r Repo) (any, error) {
+			var got []any
+			for _, rev := range []string{revs["root"], revs["second"], revs["merge"]} {
+				files, err := r.FilesChangedInCommit(rev, nil)
+				if err != nil {
+					return nil, err
+				}
+				got = append(got, files)
+			}
+			filtered, err := r.FilesChangedInCommit(revs["second"], []string{"src/b.go"})
+			return append(got, filtered), err
+		},
+		"WorkingTreeFiles": func(r Repo) (any, error) { return r.WorkingTreeFiles() },
+		"StagedFiles":      func(r Repo) (any, error) { return r.StagedFiles() },
+		"UntrackedFiles":   func(r Repo) (any, error) { return r.UntrackedFiles(nil) },
+		"Diff": func(r Repo) (any, error) {
+			diff, err := r.Diff(revs["root"], "HEAD", []string{"src", "README.md"})
+			return parseDiffHunks(diff), err
+		},
+		"DiffSince": func(r Repo) (any, error) {
+			diff, err := r.DiffSince("HEAD", false, nil)
+			return parseDiffHunks(diff), err
+		},
+		"ShowCommitDiff": func(r Repo) (any, error) {
+			diff, err := r.ShowCommitDiff(revs["main"], nil)
+			return parseDiffHunks(diff), err
+		},
+		"HunksSince":          func(r Repo) (any, error) { return r.HunksSince(revs["root"], false, nil) },
+		"HunksSince staged":   func(r Repo) (any, error) { return r.HunksSince("HEAD", true, nil) },
+		"ChangedHunksSince":   func(r Repo) (any, error) { return r.ChangedHunksSince("HEAD", false, []string{"src/a.go"}) },
+		"HunksBetween":        func(r Repo) (any, error) { return r.HunksBetween(revs["root"], "HEAD", nil) },
+		"ChangedHunksBetween": func(r Repo) (any, error) { return r.ChangedHunksBetween(revs["second"], revs["main"], nil) },
+		"CommitHunks": func(r Repo) (any, error) {
+			var got []any
+			for _, rev := range []string{revs["root"], revs["main"], revs["merge"]} {
+				hunks, err := r.CommitHunks(rev, nil)
+				if err != nil {
+					return nil, err
+				}
+				got = append(got, hunks)
+			}
+			return got, nil
+		},
+		"History": func(r Repo) (any, error) {
+			h, err := r.History()
+			if err != nil {
+				return nil, err
+			}
+			var got []any
+			for _, f := range files {
+				got = append(got, h.LastCommit(f))
+			}
+			for _, rev := range []string{revs["root"], revs["side"], revs["main"]} {
+				changed, _ := h.TouchedSince(rev, files)
+				got = append(got, changed)
+			}
+			return got, nil
+		},
+	}
+
+	repos := backends(dir)
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			want, wantErr := query(repos[config.GitBackendExec])
+			if wantErr != nil {
+				t.Fatalf("exec backend error = %v", wantErr)
+			}
+			for backend, r := range repos {
+				if backend == config.GitBackendExec {
+					continue
+				}
+				got, err := query(r)
+				if err != nil {
+					t.Errorf("%s error = %v", backend, err)
+					continue
+				}
+				if g, w := fmt.Sprintf("%v", got), fmt.Sprintf("%v", want); g != w {
+					t.Errorf("%s =\n%s\nexec =\n%s", backend, g, w)
+				}
+			}
+		})
+	}
+}
+
+func eachFile(files []string, fn func(string) (string, error)) ([]string, error) {
+	var got []string
+	for _, f := range files {
+		v, err := fn(f)
+		if err != nil {
+			return nil, err
+		}
+		got = append(got, v)
+	}
+	return got, nil
+}
+
+func TestBackendConformance_EmptyRepo(t *testing.T) {
+	for backend, r := range backends(setupGitRepo(t)) {
+		t.Run(backend, func(t *testing.T) {
+			if !r.IsRepo() {
+				t.Error("IsRepo() = false")
+			}
+			h, err := r.History()
+			if err != nil || h.LastCommit("a.go") != "" {
+				t.Errorf("History() = %v, %v; want empty", h, err)
+			}
+			if _, err := r.HeadShort(); err == nil {
+				t.Error("HeadShort() should fail without commits")
+			}
+		})
+	}
+}
+
+func TestBackendConformance_NotARepo(t *testing.T) {
+	for backend, r := range backends(t.TempDir()) {
+		if r.IsRepo() {
+			t.Errorf("%s: IsRepo() = true outside a repository", backend)
+		}
+	}
+}
+
+func TestBackendConformance_StageAndAmend(t *testing.T) {
+	for backend := range backends("") {
+		t.Run(backend, func(t *testing.T) {
+			dir, revs := fixtureRepo(t)
+			r := backends(dir)[backend]
+			if err := r.StageAndAmend("notes.txt"); err != nil {
+				t.Fatalf("StageAndAmend() error = %v", err)
+			}
+			ref := New(dir)
+			if subject, _ := ref.CommitSubject("HEAD"); subject != "Add c" {
+				t.Errorf("amended subject = %q, want %q", subject, "Add c")
+			}
+			if files, _ := ref.FilesChangedInCommit("HEAD", nil); fmt.Sprint(files) != "[notes.txt src/b.go src/c.go]" {
+				t.Errorf("amended commit files = %v", files)
+			}
+			if parent, _ := ref.ResolveShort("HEAD^"); parent != revs["merge"] {
+				t.Errorf("amended parent = %s, want %s", parent, revs["merge"])
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if r, err := Open(".", ""); err != nil || r.(*Git) == nil {
+		t.Errorf(`Open("") = %T, %v; want exec backend`, r, err)
+	}
+	if r, err := Open(".", config.GitBackendGoGit); err != nil || r.(*GoGit) == nil {
+		t.Errorf("Open(go-git) = %T, %v", r, err)
+	}
+	if _, err := Open(".", "svn"); err == nil {
+		t.Error("Open() should reject an unknown backend")
+	}
+}
+
+func TestBackendConformance_LastContentCommit(t *testing.T) {
+	dir := setupGitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	commitFile(t, dir, "API.md", "# API\n\nFirst draft of the API reference.\n", "Add API doc")
+	edited := commitFile(t, dir, "API.md", "# API\n\nThe API reference, reviewed.\n", "Review API doc")
+	os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	git("mv", "API.md", "docs/API.md")
+	git("commit", "-q", "-m", "Move API doc")
+	git("mv", "docs/API.md", "docs/reference.md")
+	git("commit", "-q", "-m", "Rename API doc")
+	commitFile(t, dir, "GUIDE.md", "# Guide\n\nHow to use it.\n", "Add guide")
+	git("mv", "GUIDE.md", "docs/GUIDE.md")
+	os.WriteFile(filepath.Join(dir, "docs", "GUIDE.md"), []byte("# Guide\n\nHow to use it, moved and revised.\n"), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "Move and revise guide")
+	revised, _ := New(dir).HeadShort()
+
+	want := map[string]string{"docs/reference.md": edited, "docs/GUIDE.md": revised, "docs/missing.md": ""}
+	for backend := range backends(dir) {
+		for _, loaded := range []bool{false, true} {
+			r := backends(dir)[backend]
+			if loaded {
+				if err := r.LoadHistory(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for path, hash := range want {
+				if got, err := r.LastContentCommit(path); err != nil || got != hash {
+					t.Errorf("%s (history %v): LastContentCommit(%s) = %q, %v; want %q", backend, loaded, path, got, err, hash)
+				}
+			}
+		}
+	}
+}
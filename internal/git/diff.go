@@ -0,0 +1,216 @@
+package git
+
+// @doc README.md
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines around each hunk, as in `git diff`.
+const diffContext = 3
+
+// lineHunks is the pure-Go `git diff --unified=0`: the hunks that turn old into
+// new, with git's numbering (a zero count names the line the change sits after).
+func lineHunks(old, new string) []Hunk {
+	var hunks []Hunk
+	var cur *Hunk
+	oldLine, newLine := 1, 1
+	for _, d := range diff.Do(old, new) {
+		n := countLines(d.Text)
+		if d.Type == diffmatchpatch.DiffEqual {
+			if cur != nil {
+				hunks = append(hunks, *cur)
+				cur = nil
+			}
+			oldLine += n
+			newLine += n
+			continue
+		}
+		if cur == nil {
+			cur = &Hunk{OldStart: oldLine, NewStart: newLine}
+		}
+		if d.Type == diffmatchpatch.DiffDelete {
+			cur.OldCount += n
+			oldLine += n
+		} else {
+			cur.NewCount += n
+			newLine += n
+		}
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	for i := range hunks {
+		if hunks[i].OldCount == 0 {
+			hunks[i].OldStart--
+		}
+		if hunks[i].NewCount == 0 {
+			hunks[i].NewStart--
+		}
+	}
+	return hunks
+}
+
+func countLines(text string) int {
+	n := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		n++
+	}
+	return n
+}
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	eol  bool
+}
+
+// writeUnified appends a git-style unified diff of one file to b. A missing
+// side (exists false) is /dev/null. Binary content is reported, not diffed.
+func writeUnified(b *strings.Builder, path, old, new string, oldExists, newExists bool) {
+	fmt.Fprintf(b, "diff --git a/%s b/%s\n", path, path)
+	switch {
+	case !oldExists:
+		b.WriteString("new file mode 100644\n")
+	case !newExists:
+		b.WriteString("deleted file mode 100644\n")
+	}
+	from, to := "a/"+path, "b/"+path
+	if !oldExists {
+		from = "/dev/null"
+	}
+	if !newExists {
+		to = "/dev/null"
+	}
+	if strings.ContainsRune(old, 0) || strings.ContainsRune(new, 0) {
+		fmt.Fprintf(b, "Binary files %s and %s differ\n", from, to)
+		return
+	}
+	fmt.Fprintf(b, "--- %s\n+++ %s\n", from, to)
+
+	var lines []diffLine
+	for _, d := range diff.Do(old, new) {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		text := d.Text
+		for text != "" {
+			line, rest, eol := strings.Cut(text, "\n")
+			lines = append(lines, diffLine{op: op, text: line, eol: eol})
+			text = rest
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start := max(0, i-diffContext)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op == ' ' {
+				continue
+			}
+			if j-end-1 > 2*diffContext {
+				break
+			}
+			end = j
+		}
+		end = min(len(lines), end+diffContext+1)
+		writeHunk(b, lines, start, end)
+		i = end
+	}
+}
+
+func writeHunk(b *strings.Builder, lines []diffLine, start, end int) {
+	oldStart, newStart := 1, 1
+	for _, l := range lines[:start] {
+		if l.op != '+' {
+			oldStart++
+		}
+		if l.op != '-' {
+			newStart++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, l := range lines[start:end] {
+		if l.op != '+' {
+			oldCount++
+		}
+		if l.op != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, l := range lines[start:end] {
+		fmt.Fprintf(b, "%c%s\n", l.op, l.text)
+		if !l.eol {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// relativeDate formats t like git's `%ar` ("3 days ago", "1 year, 2 months ago").
+func relativeDate(t, now time.Time) string {
+	diff := int64(now.Sub(t) / time.Second)
+	if diff < 0 {
+		return "in the future"
+	}
+	if diff < 90 {
+		return plural(diff, "second") + " ago"
+	}
+	diff = (diff + 30) / 60
+	if diff < 90 {
+		return plural(diff, "minute") + " ago"
+	}
+	diff = (diff + 30) / 60
+	if diff < 36 {
+		return plural(diff, "hour") + " ago"
+	}
+	diff = (diff + 12) / 24
+	if diff < 14 {
+		return plural(diff, "day") + " ago"
+	}
+	if diff < 70 {
+		return plural((diff+3)/7, "week") + " ago"
+	}
+	if diff < 365 {
+		return plural((diff+15)/30, "month") + " ago"
+	}
+	if diff < 1825 {
+		totalMonths := (diff*12*2 + 365) / (365 * 2)
+		years, months := totalMonths/12, totalMonths%12
+		if months > 0 {
+			return plural(years, "year") + ", " + plural(months, "month") + " ago"
+		}
+		return plural(years, "year") + " ago"
+	}
+	return plural((diff+183)/365, "year") + " ago"
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -13,6 +13,7 @@ import (
 
 type Git struct {
 	workDir string
+	history *History // set by LoadHistory; answers per-path queries in memory
 }
 
 func New(workDir string) *Git {
@@ -20,6 +21,12 @@ func New(workDir string) *Git {
 }
 
 func (g *Git) run(args ...string) (string, error) {
+	out, err := g.runRaw(args...)
+	return strings.TrimSpace(out), err
+}
+
+// runRaw is run without trimming, for output that is file content.
+func (g *Git) runRaw(args ...string) (string, error) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = g.workDir
 
@@ -31,7 +38,7 @@ func (g *Git) run(args ...string) (string, error) {
 		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, stderr.String())
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	return stdout.String(), nil
 }
 
 func (g *Git) IsRepo() bool {
@@ -39,6 +46,12 @@ func (g *Git) IsRepo() bool {
 	return err == nil
 }
 
+// Dir returns the absolute path of the repository's git directory (`.git`, or
+// the per-worktree directory for a linked worktree).
+func (g *Git) Dir() (string, error) {
+	return g.run("rev-parse", "--absolute-git-dir")
+}
+
 // CheckIgnore returns the subset of paths that git would ignore (respecting
 // .gitignore, .git/info/exclude, and global excludes). Paths must be relative
 // to the repo root. A clean exit with no matches is not an error.
@@ -151,13 +164,77 @@ func (g *Git) StageAndAmend(paths ...string) error {
 // path, or "" if the path has no commits yet (new/untracked). This is the
 // "last reviewed" anchor for a doc: code committed after it is unreviewed.
 func (g *Git) LastCommit(path string) (string, error) {
+	if g.history != nil {
+		return g.history.LastCommit(path), nil
+	}
 	return g.run("log", "-1", "--format=%h", "--", path)
 }
 
+// LastContentCommit is LastCommit, except that a commit which only renamed
+// path (a `git mv` with the content unchanged) doesn't count: the search goes
+// on under the old name, as `git log --follow` does. It is a doc's review
+// anchor, so moving a doc doesn't reset its baseline.
+func (g *Git) LastContentCommit(path string) (string, error) {
+	if g.history != nil {
+		if hash, ok := g.history.LastContentCommit(path); ok {
+			return hash, nil
+		}
+	}
+	out, err := g.run("-c", "core.quotePath=false", "log", "--follow", "--name-status",
+		"--format="+historyRecord+"%h", "--", path)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range strings.Split(out, historyRecord) {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if len(lines) < 2 {
+			continue // no commits yet, or a merge with no changes of its own
+		}
+		status := strings.TrimSpace(lines[len(lines)-1])
+		if strings.HasPrefix(status, "R100\t") {
+			continue
+		}
+		return lines[0], nil
+	}
+	return "", nil
+}
+
+// LastCommitInRange returns the short hash of the most recent commit that
+// touched lines [start,end] of path as they stand at HEAD, following the lines
+// back through history (`git log -L`). Used as the "last reviewed" anchor for
+// a single section of a doc.
+func (g *Git) LastCommitInRange(path string, start, end int) (string, error) {
+	out, err := g.run("log", "-1", "--format=%h", "--no-patch", fmt.Sprintf("-L%d,%d:%s", start, end, path))
+	if err != nil {
+		return "", err
+	}
+	// Older gits ignore --no-patch with -L; the hash is still the first line.
+	first, _, _ := strings.Cut(out, "\n")
+	return strings.TrimSpace(first), nil
+}
+
+// FileAt returns path's content at rev, or ok=false when it doesn't exist
+// there (e.g. a doc that was never committed).
+func (g *Git) FileAt(rev, path string) ([]byte, bool, error) {
+	if _, err := g.run("cat-file", "-e", rev+":"+path); err != nil {
+		return nil, false, nil
+	}
+	out, err := g.runRaw("show", rev+":"+path)
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(out), true, nil
+}
+
 // IsAncestor reports whether commit a is an ancestor of commit b (a is older).
 // Used to pick the newer of a doc's last commit and an ack floor. A clean
 // "not an ancestor" (exit 1) is not an error; unknown commits are.
 func (g *Git) IsAncestor(a, b string) (bool, error) {
+	if g.history != nil {
+		if anc, ok := g.history.IsAncestor(a, b); ok {
+			return anc, nil
+		}
+	}
 	cmd := exec.Command("git", "merge-base", "--is-ancestor", a, b)
 	cmd.Dir = g.workDir
 
@@ -174,6 +251,11 @@ func (g *Git) IsAncestor(a, b string) (bool, error) {
 }
 
 func (g *Git) CommitInfo(hash string) (string, error) {
+	if g.history != nil {
+		if c, ok := g.history.Lookup(hash); ok {
+			return c.Info(), nil
+		}
+	}
 	return g.run("log", "-1", "--format=%h (%ar)", hash)
 }
 
@@ -185,7 +267,25 @@ func (g *Git) CommitSubject(hash string) (string, error) {
 	return g.run("log", "-1", "--format=%s", hash)
 }
 
+// ChangedFilesBetween returns files (optionally filtered) that differ between
+// two commits. With a loaded History and toHash "HEAD", files no commit in
+// fromHash..HEAD touched are ruled out in memory, and one unfiltered diff per
+// fromHash confirms the rest.
 func (g *Git) ChangedFilesBetween(fromHash, toHash string, files []string) ([]string, error) {
+	if g.history != nil && toHash == "HEAD" && len(files) > 0 {
+		changed, ok, err := g.history.ChangedFilesSince(fromHash, files, func(from string) ([]string, error) {
+			return g.ChangedFilesBetween(from, "HEAD", nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if len(changed) == 0 {
+				return nil, nil
+			}
+			return changed, nil
+		}
+	}
 	args := []string{"diff", "--name-only", fromHash + ".." + toHash}
 	if len(files) > 0 {
 		args = append(args, "--")
@@ -356,18 +456,74 @@ type LineRange struct {
 	End   int
 }
 
-var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+// Hunk is one --unified=0 diff hunk: the old-side and new-side line spans it
+// replaced. A zero count is a pure insertion (old) or deletion (new), with the
+// start naming the line the change sits after.
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+}
+
+// NewRange is the new-side lines the hunk touched. A pure deletion reports the
+// line the removal sits at.
+func (h Hunk) NewRange() LineRange {
+	if h.NewCount == 0 {
+		return LineRange{Start: h.NewStart, End: h.NewStart}
+	}
+	return LineRange{Start: h.NewStart, End: h.NewStart + h.NewCount - 1}
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
 
 // ChangedHunksSince returns, per file, the new-side line ranges changed against
 // fromHash (working tree, or the index when staged). Uses --unified=0 so ranges
 // are tight. Untracked files never appear (they aren't in a diff). Used for
 // hunk-level scope matching in `check`.
 func (g *Git) ChangedHunksSince(fromHash string, staged bool, files []string) (map[string][]LineRange, error) {
+	hunks, err := g.HunksSince(fromHash, staged, files)
+	if err != nil {
+		return nil, err
+	}
+	return newRanges(hunks), nil
+}
+
+// HunksSince is ChangedHunksSince with both sides of each hunk, for mapping
+// lines between fromHash and the working tree (or index).
+func (g *Git) HunksSince(fromHash string, staged bool, files []string) (map[string][]Hunk, error) {
 	args := []string{"diff", "--unified=0", "--no-color"}
 	if staged {
 		args = append(args, "--cached")
 	}
 	args = append(args, fromHash)
+	return g.hunks(args, files)
+}
+
+// ChangedHunksBetween returns, per file, the new-side (toHash) line ranges
+// changed between two commits. Used for scope-aware committed staleness.
+func (g *Git) ChangedHunksBetween(fromHash, toHash string, files []string) (map[string][]LineRange, error) {
+	hunks, err := g.HunksBetween(fromHash, toHash, files)
+	if err != nil {
+		return nil, err
+	}
+	return newRanges(hunks), nil
+}
+
+// HunksBetween returns both sides of each hunk between two commits, for
+// mapping lines from toHash back to fromHash.
+func (g *Git) HunksBetween(fromHash, toHash string, files []string) (map[string][]Hunk, error) {
+	return g.hunks([]string{"diff", "--unified=0", "--no-color", fromHash, toHash}, files)
+}
+
+// CommitHunks returns the hunks a single commit introduced, in that commit's
+// own line numbers. Merge commits produce a combined diff with no parseable
+// hunks, so callers fall back to whole-file behavior for them.
+func (g *Git) CommitHunks(hash string, files []string) (map[string][]Hunk, error) {
+	return g.hunks([]string{"show", "--format=", "--unified=0", "--no-color", hash}, files)
+}
+
+func (g *Git) hunks(args []string, files []string) (map[string][]Hunk, error) {
 	if len(files) > 0 {
 		args = append(args, "--")
 		args = append(args, files...)
@@ -376,11 +532,72 @@ func (g *Git) ChangedHunksSince(fromHash string, staged bool, files []string) (m
 	if err != nil {
 		return nil, err
 	}
-	return parseHunks(out), nil
+	return parseDiffHunks(out), nil
+}
+
+// MapToOld translates a new-side line through a file's hunks to the matching
+// old-side line, so a region defined on the newer revision can be compared
+// against changes made in an older one. A line inside a changed block maps to
+// the first old line of that block (for a pure insertion, the old line that
+// followed the insertion point).
+func MapToOld(hunks []Hunk, line int) int {
+	return mapToOld(hunks, line, false)
+}
+
+// MapEndToOld is MapToOld for the last line of a region: a line inside a
+// changed block maps to the last old line of that block (for a pure insertion,
+// the old line before the insertion point), so the mapped region still covers
+// the whole block.
+func MapEndToOld(hunks []Hunk, line int) int {
+	return mapToOld(hunks, line, true)
+}
+
+func mapToOld(hunks []Hunk, line int, end bool) int {
+	delta := 0
+	for _, h := range hunks {
+		if h.NewCount == 0 {
+			if line > h.NewStart {
+				delta += h.OldCount
+				continue
+			}
+			break
+		}
+		if line < h.NewStart {
+			break
+		}
+		if line >= h.NewStart+h.NewCount {
+			delta += h.OldCount - h.NewCount
+			continue
+		}
+		switch {
+		case h.OldCount == 0 && end:
+			return h.OldStart
+		case h.OldCount == 0:
+			return h.OldStart + 1
+		case end:
+			return h.OldStart + h.OldCount - 1
+		}
+		return h.OldStart
+	}
+	return line + delta
 }
 
 func parseHunks(diff string) map[string][]LineRange {
-	result := make(map[string][]LineRange)
+	return newRanges(parseDiffHunks(diff))
+}
+
+func newRanges(hunks map[string][]Hunk) map[string][]LineRange {
+	result := make(map[string][]LineRange, len(hunks))
+	for file, hs := range hunks {
+		for _, h := range hs {
+			result[file] = append(result[file], h.NewRange())
+		}
+	}
+	return result
+}
+
+func parseDiffHunks(diff string) map[string][]Hunk {
+	result := make(map[string][]Hunk)
 	if diff == "" {
 		return result
 	}
@@ -400,21 +617,29 @@ func parseHunks(diff string) map[string][]LineRange {
 			if m == nil {
 				continue
 			}
-			start, _ := strconv.Atoi(m[1])
-			count := 1
-			if m[2] != "" {
-				count, _ = strconv.Atoi(m[2])
-			}
-			end := start + count - 1
-			if count == 0 {
-				end = start // pure deletion: the line the removal sits at
-			}
-			result[file] = append(result[file], LineRange{Start: start, End: end})
+			result[file] = append(result[file], Hunk{
+				OldStart: atoiOr(m[1], 0),
+				OldCount: atoiOr(m[2], 1),
+				NewStart: atoiOr(m[3], 0),
+				NewCount: atoiOr(m[4], 1),
+			})
 		}
 	}
 	return result
 }
 
+// atoiOr parses an optional hunk-header number; git omits a count of 1.
+func atoiOr(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
 func splitNonEmpty(s string) []string {
 	if s == "" {
 		return nil
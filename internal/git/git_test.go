@@ -363,3 +363,95 @@ func TestParseHunks(t *testing.T) {
 		t.Errorf("deletion to /dev/null should record no new-side range, got %+v", got["gone.go"])
 	}
 }
+
+func TestMapToOld(t *testing.T) {
+	hunks := []Hunk{
+		{OldStart: 1, OldCount: 0, NewStart: 2, NewCount: 3},   // 3 lines inserted after old line 1
+		{OldStart: 5, OldCount: 2, NewStart: 8, NewCount: 1},   // old 5-6 replaced by new 8
+		{OldStart: 10, OldCount: 2, NewStart: 11, NewCount: 0}, // old 10-11 deleted after new 11
+	}
+	cases := []struct{ line, want int }{
+		{1, 1},   // before any change
+		{2, 2},   // inside the insertion: old line that followed it
+		{4, 2},   // still inside the insertion
+		{5, 2},   // shifted by +3 insertion
+		{8, 5},   // inside the replacement block
+		{9, 7},   // after replacement: -3 then +1
+		{11, 9},  // at the deletion point
+		{12, 12}, // past the deletion: +2
+	}
+	for _, c := range cases {
+		if got := MapToOld(hunks, c.line); got != c.want {
+			t.Errorf("MapToOld(%d) = %d, want %d", c.line, got, c.want)
+		}
+	}
+	if got := MapToOld(nil, 7); got != 7 {
+		t.Errorf("MapToOld(nil, 7) = %d, want 7", got)
+	}
+
+	ends := []struct{ line, want int }{
+		{3, 1},  // inside the insertion: old line before it
+		{8, 6},  // inside the replacement: last replaced old line
+		{9, 7},  // unchanged lines map like MapToOld
+		{11, 9}, // at the deletion point
+	}
+	for _, c := range ends {
+		if got := MapEndToOld(hunks, c.line); got != c.want {
+			t.Errorf("MapEndToOld(%d) = %d, want %d", c.line, got, c.want)
+		}
+	}
+}
+
+func TestGit_HunksBetween(t *testing.T) {
+	dir := setupGitRepo(t)
+	first := commitFile(t, dir, "a.go", "one\ntwo\nthree\n", "first")
+	commitFile(t, dir, "a.go", "zero\none\ntwo\nTHREE\n", "second")
+
+	g := New(dir)
+	hunks, err := g.HunksBetween(first, "HEAD", nil)
+	if err != nil {
+		t.Fatalf("HunksBetween() error = %v", err)
+	}
+	want := []Hunk{
+		{OldStart: 0, OldCount: 0, NewStart: 1, NewCount: 1},
+		{OldStart: 3, OldCount: 1, NewStart: 4, NewCount: 1},
+	}
+	got := hunks["a.go"]
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("hunks = %+v, want %+v", got, want)
+	}
+	if old := MapToOld(got, 3); old != 2 {
+		t.Errorf("HEAD line 3 (\"two\") should map to old line 2, got %d", old)
+	}
+
+	commit, err := g.CommitHunks("HEAD", []string{"a.go"})
+	if err != nil {
+		t.Fatalf("CommitHunks() error = %v", err)
+	}
+	if len(commit["a.go"]) != 2 {
+		t.Errorf("CommitHunks() = %+v, want 2 hunks", commit["a.go"])
+	}
+}
+
+func TestGit_LastCommitInRange(t *testing.T) {
+	dir := setupGitRepo(t)
+	first := commitFile(t, dir, "API.md", "# API\n## Auth\nold\n## Limits\nold\n", "first")
+	commitFile(t, dir, "API.md", "# API\n## Auth\nold\n## Limits\nnew\n", "second")
+
+	g := New(dir)
+	got, err := g.LastCommitInRange("API.md", 2, 3)
+	if err != nil {
+		t.Fatalf("LastCommitInRange() error = %v", err)
+	}
+	if !strings.HasPrefix(first, got) || got == "" {
+		t.Errorf("LastCommitInRange(Auth) = %q, want the first commit %q", got, first)
+	}
+
+	content, ok, err := g.FileAt("HEAD", "API.md")
+	if err != nil || !ok || !strings.Contains(string(content), "new") {
+		t.Errorf("FileAt(HEAD) = %q, %v, %v", content, ok, err)
+	}
+	if _, ok, _ := g.FileAt("HEAD", "missing.md"); ok {
+		t.Error("FileAt() should report a missing path")
+	}
+}
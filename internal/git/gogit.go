@@ -0,0 +1,1147 @@
+package git
+
+// @doc README.md
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// shortLen is the abbreviation GoGit uses for short hashes: git's default for
+// repositories small enough that seven characters are unique.
+const shortLen = 7
+
+// GoGit is the pure-Go backend, for machines without a git binary and for
+// embedding. It reads the repository through go-git; the working tree and
+// index are compared by content hash (using the index's stat data when it
+// matches). Path-limited history visits every commit, without git's history
+// simplification; for last-commit and range queries merges never count as
+// touching a path.
+type GoGit struct {
+	workDir string
+
+	openOnce sync.Once
+	repo     *gogit.Repository
+	openErr  error
+
+	ignoreOnce sync.Once
+	ignore     gitignore.Matcher
+	ignoreErr  error
+
+	history *History // set by LoadHistory; answers per-path queries in memory
+}
+
+func NewGoGit(workDir string) *GoGit {
+	return &GoGit{workDir: workDir}
+}
+
+func (g *GoGit) open() (*gogit.Repository, error) {
+	g.openOnce.Do(func() {
+		g.repo, g.openErr = gogit.PlainOpenWithOptions(g.workDir, &gogit.PlainOpenOptions{
+			DetectDotGit:          true,
+			EnableDotGitCommonDir: true,
+		})
+	})
+	return g.repo, g.openErr
+}
+
+func (g *GoGit) commit(rev string) (*object.Commit, error) {
+	r, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("go-git: resolve %s: %w", rev, err)
+	}
+	return r.CommitObject(*h)
+}
+
+func short(h plumbing.Hash) string {
+	return h.String()[:shortLen]
+}
+
+func (g *GoGit) IsRepo() bool {
+	_, err := g.open()
+	return err == nil
+}
+
+func (g *GoGit) Dir() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	s, ok := r.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", errors.New("go-git: repository has no git directory")
+	}
+	return filepath.Abs(s.Filesystem().Root())
+}
+
+func (g *GoGit) root() (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return "", err
+	}
+	return wt.Filesystem.Root(), nil
+}
+
+// matcher loads .gitignore files, .git/info/exclude and the global and system
+// excludes once per GoGit.
+func (g *GoGit) matcher() (gitignore.Matcher, error) {
+	g.ignoreOnce.Do(func() {
+		r, err := g.open()
+		if err != nil {
+			g.ignoreErr = err
+			return
+		}
+		wt, err := r.Worktree()
+		if err != nil {
+			g.ignoreErr = err
+			return
+		}
+		root := osfs.New("/")
+		system, _ := gitignore.LoadSystemPatterns(root)
+		global, _ := gitignore.LoadGlobalPatterns(root)
+		local, err := gitignore.ReadPatterns(wt.Filesystem, nil)
+		if err != nil {
+			g.ignoreErr = err
+			return
+		}
+		g.ignore = gitignore.NewMatcher(append(append(system, global...), local...))
+	})
+	return g.ignore, g.ignoreErr
+}
+
+func (g *GoGit) index() (map[string]indexEntry, error) {
+	r, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]indexEntry, len(idx.Entries))
+	for _, e := range idx.Entries {
+		entries[e.Name] = indexEntry{hash: e.Hash, size: int64(e.Size), modTime: e.ModifiedAt}
+	}
+	return entries, nil
+}
+
+type indexEntry struct {
+	hash    plumbing.Hash
+	size    int64
+	modTime time.Time
+}
+
+// CheckIgnore mirrors `git check-ignore`: tracked paths are never reported,
+// and a trailing slash marks a directory.
+func (g *GoGit) CheckIgnore(paths []string) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+	if len(paths) == 0 {
+		return ignored, nil
+	}
+	m, err := g.matcher()
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := g.index()
+	if err != nil {
+		return nil, err
+	}
+	root, err := g.root()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		clean := strings.TrimSuffix(p, "/")
+		if clean == "" {
+			continue
+		}
+		if _, ok := tracked[clean]; ok {
+			continue
+		}
+		isDir := strings.HasSuffix(p, "/")
+		if !isDir {
+			if info, err := os.Stat(filepath.Join(root, clean)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if m.Match(strings.Split(clean, "/"), isDir) {
+			ignored[p] = true
+		}
+	}
+	return ignored, nil
+}
+
+func (g *GoGit) HeadShort() (string, error) {
+	return g.ResolveShort("HEAD")
+}
+
+func (g *GoGit) HeadFull() (string, error) {
+	c, err := g.commit("HEAD")
+	if err != nil {
+		return "", err
+	}
+	return c.Hash.String(), nil
+}
+
+func (g *GoGit) ResolveShort(ref string) (string, error) {
+	c, err := g.commit(ref)
+	if err != nil {
+		return "", err
+	}
+	return short(c.Hash), nil
+}
+
+func (g *GoGit) IsAncestor(a, b string) (bool, error) {
+	if g.history != nil {
+		if anc, ok := g.history.IsAncestor(a, b); ok {
+			return anc, nil
+		}
+	}
+	ca, err := g.commit(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := g.commit(b)
+	if err != nil {
+		return false, err
+	}
+	return ca.IsAncestor(cb)
+}
+
+// walk visits the commits reachable from rev, newest first, with the changes
+// each non-merge commit made against its parent (a root commit against an
+// empty tree). Merge commits are visited with no changes. fn returns
+// storer.ErrStop to end the walk early.
+func (g *GoGit) walk(rev string, fn func(c *object.Commit, changes object.Changes) error) error {
+	r, err := g.open()
+	if err != nil {
+		return err
+	}
+	start, err := g.commit(rev)
+	if err != nil {
+		return err
+	}
+	iter, err := r.Log(&gogit.LogOptions{From: start.Hash, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return err
+	}
+	return iter.ForEach(func(c *object.Commit) error {
+		if c.NumParents() > 1 {
+			return fn(c, nil)
+		}
+		changes, err := commitChanges(c)
+		if err != nil {
+			return err
+		}
+		return fn(c, changes)
+	})
+}
+
+// commitChanges diffs a commit against its first parent, or an empty tree.
+func commitChanges(c *object.Commit) (object.Changes, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+	return object.DiffTree(parentTree, tree)
+}
+
+func changeName(ch *object.Change) string {
+	if ch.To.Name != "" {
+		return ch.To.Name
+	}
+	return ch.From.Name
+}
+
+func findChange(changes object.Changes, path string) *object.Change {
+	for _, ch := range changes {
+		if changeName(ch) == path || ch.From.Name == path {
+			return ch
+		}
+	}
+	return nil
+}
+
+// changeContents returns both sides of a change; a missing side is "", false.
+func changeContents(ch *object.Change) (old, new string, oldOK, newOK bool, err error) {
+	from, to, err := ch.Files()
+	if err != nil {
+		return "", "", false, false, err
+	}
+	if from != nil {
+		if old, err = from.Contents(); err != nil {
+			return "", "", false, false, err
+		}
+	}
+	if to != nil {
+		if new, err = to.Contents(); err != nil {
+			return "", "", false, false, err
+		}
+	}
+	return old, new, from != nil, to != nil, nil
+}
+
+func (g *GoGit) LastCommit(path string) (string, error) {
+	if g.history != nil {
+		return g.history.LastCommit(path), nil
+	}
+	var found string
+	err := g.walk("HEAD", func(c *object.Commit, changes object.Changes) error {
+		if findChange(changes, path) != nil {
+			found = short(c.Hash)
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return found, err
+}
+
+// LastContentCommit is LastCommit, skipping commits that only renamed path
+// (same blob under a new name) and continuing under the old name.
+func (g *GoGit) LastContentCommit(path string) (string, error) {
+	if g.history != nil {
+		if hash, ok := g.history.LastContentCommit(path); ok {
+			return hash, nil
+		}
+	}
+	cur := path
+	var found string
+	err := g.walk("HEAD", func(c *object.Commit, changes object.Changes) error {
+		ch := findChange(changes, cur)
+		if ch == nil {
+			return nil
+		}
+		if from := exactRenameSource(changes, ch); from != "" {
+			cur = from
+			return nil
+		}
+		found = short(c.Hash)
+		return storer.ErrStop
+	})
+	return found, err
+}
+
+// exactRenameSource returns the path ch was renamed from when ch creates a file
+// whose blob the same commit deleted under another name, else "".
+func exactRenameSource(changes object.Changes, ch *object.Change) string {
+	if ch.From.Name != "" || ch.To.Name == "" {
+		return ""
+	}
+	var sources []string
+	for _, other := range changes {
+		if other.To.Name == "" && other.From.TreeEntry.Hash == ch.To.TreeEntry.Hash {
+			sources = append(sources, other.From.Name)
+		}
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	sort.Strings(sources)
+	return sources[0]
+}
+
+// LastCommitInRange follows lines [start,end] of path at HEAD back through the
+// commits that changed the file, mapping the range through each one's hunks,
+// and returns the first commit whose hunks touch it.
+func (g *GoGit) LastCommitInRange(path string, start, end int) (string, error) {
+	head, ok, err := g.FileAt("HEAD", path)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("go-git: no such path %s in HEAD", path)
+	}
+	if lines := countLines(string(head)); start < 1 || end < start || end > lines {
+		return "", fmt.Errorf("go-git: %s has only %d lines", path, lines)
+	}
+
+	var found string
+	err = g.walk("HEAD", func(c *object.Commit, changes object.Changes) error {
+		ch := findChange(changes, path)
+		if ch == nil {
+			return nil
+		}
+		old, new, oldOK, newOK, err := changeContents(ch)
+		if err != nil {
+			return err
+		}
+		if !newOK {
+			return nil
+		}
+		if !oldOK {
+			found = short(c.Hash)
+			return storer.ErrStop
+		}
+		hunks := lineHunks(old, new)
+		for _, h := range hunks {
+			if hunkTouches(h, start, end) {
+				found = short(c.Hash)
+				return storer.ErrStop
+			}
+		}
+		start, end = MapToOld(hunks, start), MapEndToOld(hunks, end)
+		return nil
+	})
+	return found, err
+}
+
+// hunkTouches reports whether a hunk changed new-side lines [start,end]; a
+// pure deletion counts when it sits between two lines of the range.
+func hunkTouches(h Hunk, start, end int) bool {
+	if h.NewCount == 0 {
+		return start <= h.NewStart && h.NewStart < end
+	}
+	return h.NewStart <= end && h.NewStart+h.NewCount-1 >= start
+}
+
+// LastCommitMatching is `git log -G`: the newest commit whose added or removed
+// lines in path match regex.
+func (g *GoGit) LastCommitMatching(path, regex string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	var found string
+	err = g.walk("HEAD", func(c *object.Commit, changes object.Changes) error {
+		ch := findChange(changes, path)
+		if ch == nil {
+			return nil
+		}
+		old, new, _, _, err := changeContents(ch)
+		if err != nil {
+			return err
+		}
+		for _, d := range diff.Do(old, new) {
+			if d.Type != diffmatchpatch.DiffEqual && re.MatchString(d.Text) {
+				found = short(c.Hash)
+				return storer.ErrStop
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (g *GoGit) CommitInfo(hash string) (string, error) {
+	if g.history != nil {
+		if c, ok := g.history.Lookup(hash); ok {
+			return c.Info(), nil
+		}
+	}
+	c, err := g.commit(hash)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s)", short(c.Hash), relativeDate(c.Author.When, time.Now())), nil
+}
+
+func (g *GoGit) CommitDate(hash string) (string, error) {
+	c, err := g.commit(hash)
+	if err != nil {
+		return "", err
+	}
+	return c.Committer.When.Format("2006-01-02"), nil
+}
+
+func (g *GoGit) CommitSubject(hash string) (string, error) {
+	c, err := g.commit(hash)
+	if err != nil {
+		return "", err
+	}
+	return subject(c.Message), nil
+}
+
+// subject is git's `%s`: the message's first paragraph on one line.
+func subject(message string) string {
+	para, _, _ := strings.Cut(strings.TrimLeft(message, "\n"), "\n\n")
+	var parts []string
+	for _, line := range strings.Split(para, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// commitsBetween is `git log fromHash..toHash -- files`, newest first.
+func (g *GoGit) commitsBetween(fromHash, toHash string, files []string) ([]*object.Commit, error) {
+	exclude := make(map[plumbing.Hash]bool)
+	err := g.walk(fromHash, func(c *object.Commit, _ object.Changes) error {
+		exclude[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var commits []*object.Commit
+	err = g.walk(toHash, func(c *object.Commit, changes object.Changes) error {
+		if exclude[c.Hash] {
+			return nil
+		}
+		if len(files) == 0 {
+			commits = append(commits, c)
+			return nil
+		}
+		if c.NumParents() > 1 {
+			differs, err := mergeDiffers(c, files)
+			if differs {
+				commits = append(commits, c)
+			}
+			return err
+		}
+		for _, ch := range changes {
+			if matchPathspec(changeName(ch), files) || matchPathspec(ch.From.Name, files) {
+				commits = append(commits, c)
+				break
+			}
+		}
+		return nil
+	})
+	return commits, err
+}
+
+// mergeDiffers reports whether a merge's files differ from every parent's, the
+// rule git uses to list a merge under a pathspec.
+func mergeDiffers(c *object.Commit, files []string) (bool, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+	parents := c.Parents()
+	defer parents.Close()
+	differs := true
+	err = parents.ForEach(func(p *object.Commit) error {
+		pt, err := p.Tree()
+		if err != nil {
+			return err
+		}
+		changes, err := object.DiffTree(pt, tree)
+		if err != nil {
+			return err
+		}
+		if len(filterChanges(changes, files)) == 0 {
+			differs = false
+			return storer.ErrStop
+		}
+		return nil
+	})
+	return differs, err
+}
+
+func (g *GoGit) CommitsBetween(fromHash, toHash string, files []string) ([]string, error) {
+	commits, err := g.commitsBetween(fromHash, toHash, files)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, c := range commits {
+		out = append(out, short(c.Hash)+" "+subject(c.Message))
+	}
+	return out, nil
+}
+
+func (g *GoGit) CommitDetails(fromHash, toHash string, files []string) ([]CommitDetail, error) {
+	commits, err := g.commitsBetween(fromHash, toHash, files)
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+	details := make([]CommitDetail, 0, len(commits))
+	for _, c := range commits {
+		details = append(details, CommitDetail{Hash: c.Hash.String(), Short: short(c.Hash), Subject: subject(c.Message)})
+	}
+	return details, nil
+}
+
+func (g *GoGit) FileAt(rev, path string) ([]byte, bool, error) {
+	c, err := g.commit(rev)
+	if err != nil {
+		return nil, false, nil
+	}
+	f, err := c.File(path)
+	if err != nil {
+		return nil, false, nil
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(content), true, nil
+}
+
+// matchPathspec reports whether path is one of files or inside one of them.
+// An empty pathspec matches everything.
+func matchPathspec(path string, files []string) bool {
+	if path == "" {
+		return false
+	}
+	if len(files) == 0 {
+		return true
+	}
+	for _, f := range files {
+		f = strings.TrimSuffix(f, "/")
+		if path == f || strings.HasPrefix(path, f+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// treeChanges diffs two commits, keeping changes inside files, sorted by path.
+func (g *GoGit) treeChanges(fromHash, toHash string, files []string) (object.Changes, error) {
+	from, err := g.commit(fromHash)
+	if err != nil {
+		return nil, err
+	}
+	to, err := g.commit(toHash)
+	if err != nil {
+		return nil, err
+	}
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+	return filterChanges(changes, files), nil
+}
+
+func filterChanges(changes object.Changes, files []string) object.Changes {
+	var kept object.Changes
+	for _, ch := range changes {
+		if matchPathspec(changeName(ch), files) {
+			kept = append(kept, ch)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return changeName(kept[i]) < changeName(kept[j]) })
+	return kept
+}
+
+func (g *GoGit) ChangedFilesBetween(fromHash, toHash string, files []string) ([]string, error) {
+	if g.history != nil && toHash == "HEAD" && len(files) > 0 {
+		changed, ok, err := g.history.ChangedFilesSince(fromHash, files, func(from string) ([]string, error) {
+			return g.ChangedFilesBetween(from, "HEAD", nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if len(changed) == 0 {
+				return nil, nil
+			}
+			return changed, nil
+		}
+	}
+	changes, err := g.treeChanges(fromHash, toHash, files)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ch := range changes {
+		names = append(names, changeName(ch))
+	}
+	return names, nil
+}
+
+// snapshot is one side of a working-tree or index comparison: each path's
+// blob hash, and a way to read its content.
+type snapshot struct {
+	hashes map[string]plumbing.Hash
+	read   func(path string) (string, error)
+}
+
+func (g *GoGit) commitSnapshot(rev string) (snapshot, error) {
+	c, err := g.commit(rev)
+	if err != nil {
+		return snapshot{}, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{hashes: make(map[string]plumbing.Hash)}
+	err = tree.Files().ForEach(func(f *object.File) error {
+		s.hashes[f.Name] = f.Hash
+		return nil
+	})
+	s.read = func(path string) (string, error) {
+		f, err := tree.File(path)
+		if err != nil {
+			return "", err
+		}
+		return f.Contents()
+	}
+	return s, err
+}
+
+func (g *GoGit) readBlob(hash plumbing.Hash) (string, error) {
+	r, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	blob, err := r.BlobObject(hash)
+	if err != nil {
+		return "", err
+	}
+	rd, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer rd.Close()
+	data, err := io.ReadAll(rd)
+	return string(data), err
+}
+
+// targetSnapshot is the index (staged) or the working tree. The working tree
+// covers only paths git tracks: those in base or the index.
+func (g *GoGit) targetSnapshot(base snapshot, staged bool) (snapshot, error) {
+	idx, err := g.index()
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{hashes: make(map[string]plumbing.Hash)}
+	if staged {
+		for path, e := range idx {
+			s.hashes[path] = e.hash
+		}
+		s.read = func(path string) (string, error) { return g.readBlob(s.hashes[path]) }
+		return s, nil
+	}
+
+	root, err := g.root()
+	if err != nil {
+		return snapshot{}, err
+	}
+	paths := make(map[string]bool, len(idx))
+	for path := range base.hashes {
+		paths[path] = true
+	}
+	for path := range idx {
+		paths[path] = true
+	}
+	for path := range paths {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		info, err := os.Lstat(full)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if e, ok := idx[path]; ok && info.Size() == e.size && info.ModTime().Equal(e.modTime) {
+			s.hashes[path] = e.hash
+			continue
+		}
+		data, err := readWorktreeFile(full, info)
+		if err != nil {
+			return snapshot{}, err
+		}
+		s.hashes[path] = plumbing.ComputeHash(plumbing.BlobObject, data)
+	}
+	s.read = func(path string) (string, error) {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		info, err := os.Lstat(full)
+		if err != nil {
+			return "", err
+		}
+		data, err := readWorktreeFile(full, info)
+		return string(data), err
+	}
+	return s, nil
+}
+
+// readWorktreeFile reads a file as git stores it: a symlink is its target.
+func readWorktreeFile(full string, info fs.FileInfo) ([]byte, error) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		return []byte(target), err
+	}
+	return os.ReadFile(full)
+}
+
+// snapshotChanges lists paths inside files that differ between a and b.
+func snapshotChanges(a, b snapshot, files []string) []string {
+	var changed []string
+	for path, h := range a.hashes {
+		if bh, ok := b.hashes[path]; (!ok || bh != h) && matchPathspec(path, files) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range b.hashes {
+		if _, ok := a.hashes[path]; !ok && matchPathspec(path, files) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (g *GoGit) sinceSnapshots(fromHash string, staged bool) (snapshot, snapshot, error) {
+	base, err := g.commitSnapshot(fromHash)
+	if err != nil {
+		return snapshot{}, snapshot{}, err
+	}
+	target, err := g.targetSnapshot(base, staged)
+	return base, target, err
+}
+
+func (g *GoGit) ChangedFilesSince(fromHash string, staged bool, files []string) ([]string, error) {
+	base, target, err := g.sinceSnapshots(fromHash, staged)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotChanges(base, target, files), nil
+}
+
+func (g *GoGit) FilesChangedInCommit(hash string, filterFiles []string) ([]string, error) {
+	c, err := g.commit(hash)
+	if err != nil {
+		return nil, err
+	}
+	// Like `git diff-tree` without --root or -m: root and merge commits list nothing.
+	if c.NumParents() != 1 {
+		return nil, nil
+	}
+	changes, err := commitChanges(c)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	var files []string
+	for _, ch := range filterChanges(changes, nil) {
+		name := changeName(ch)
+		if len(filterFiles) == 0 || matchPathspec(name, filterFiles) {
+			files = append(files, name)
+		}
+	}
+	if files == nil && len(filterFiles) > 0 {
+		return []string{}, nil
+	}
+	return files, nil
+}
+
+func (g *GoGit) WorkingTreeFiles() ([]string, error) {
+	tracked, err := g.ChangedFilesSince("HEAD", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := g.UntrackedFiles(nil)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, f := range append(tracked, untracked...) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func (g *GoGit) StagedFiles() ([]string, error) {
+	return g.ChangedFilesSince("HEAD", true, nil)
+}
+
+func (g *GoGit) UntrackedFiles(files []string) ([]string, error) {
+	m, err := g.matcher()
+	if err != nil {
+		return nil, err
+	}
+	tracked, err := g.index()
+	if err != nil {
+		return nil, err
+	}
+	root, err := g.root()
+	if err != nil {
+		return nil, err
+	}
+	var untracked []string
+	err = filepath.WalkDir(root, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, full)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || m.Match(strings.Split(rel, "/"), true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := tracked[rel]; ok || m.Match(strings.Split(rel, "/"), false) {
+			return nil
+		}
+		if matchPathspec(rel, files) {
+			untracked = append(untracked, rel)
+		}
+		return nil
+	})
+	sort.Strings(untracked)
+	return untracked, err
+}
+
+func (g *GoGit) Diff(fromHash, toHash string, files []string) (string, error) {
+	changes, err := g.treeChanges(fromHash, toHash, files)
+	if err != nil {
+		return "", err
+	}
+	return unifiedChanges(changes)
+}
+
+func unifiedChanges(changes object.Changes) (string, error) {
+	var b strings.Builder
+	for _, ch := range changes {
+		old, new, oldOK, newOK, err := changeContents(ch)
+		if err != nil {
+			return "", err
+		}
+		writeUnified(&b, changeName(ch), old, new, oldOK, newOK)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func (g *GoGit) DiffSince(fromHash string, staged bool, files []string) (string, error) {
+	base, target, err := g.sinceSnapshots(fromHash, staged)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, path := range snapshotChanges(base, target, files) {
+		old, new, oldOK, newOK, err := snapshotContents(base, target, path)
+		if err != nil {
+			return "", err
+		}
+		writeUnified(&b, path, old, new, oldOK, newOK)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func snapshotContents(a, b snapshot, path string) (old, new string, oldOK, newOK bool, err error) {
+	if _, oldOK = a.hashes[path]; oldOK {
+		if old, err = a.read(path); err != nil {
+			return
+		}
+	}
+	if _, newOK = b.hashes[path]; newOK {
+		new, err = b.read(path)
+	}
+	return
+}
+
+func (g *GoGit) ShowCommitDiff(hash string, files []string) (string, error) {
+	c, err := g.commit(hash)
+	if err != nil {
+		return "", err
+	}
+	if c.NumParents() > 1 {
+		return "", nil
+	}
+	changes, err := commitChanges(c)
+	if err != nil {
+		return "", err
+	}
+	return unifiedChanges(filterChanges(changes, files))
+}
+
+func (g *GoGit) ChangedHunksSince(fromHash string, staged bool, files []string) (map[string][]LineRange, error) {
+	hunks, err := g.HunksSince(fromHash, staged, files)
+	if err != nil {
+		return nil, err
+	}
+	return newRanges(hunks), nil
+}
+
+func (g *GoGit) HunksSince(fromHash string, staged bool, files []string) (map[string][]Hunk, error) {
+	base, target, err := g.sinceSnapshots(fromHash, staged)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]Hunk)
+	for _, path := range snapshotChanges(base, target, files) {
+		old, new, _, newOK, err := snapshotContents(base, target, path)
+		if err != nil {
+			return nil, err
+		}
+		addHunks(result, path, old, new, newOK)
+	}
+	return result, nil
+}
+
+// addHunks records path's --unified=0 hunks. Deleted and binary files have
+// none, as in git's output.
+func addHunks(result map[string][]Hunk, path, old, new string, newOK bool) {
+	if !newOK || strings.ContainsRune(old, 0) || strings.ContainsRune(new, 0) {
+		return
+	}
+	if hs := lineHunks(old, new); len(hs) > 0 {
+		result[path] = hs
+	}
+}
+
+func (g *GoGit) ChangedHunksBetween(fromHash, toHash string, files []string) (map[string][]LineRange, error) {
+	hunks, err := g.HunksBetween(fromHash, toHash, files)
+	if err != nil {
+		return nil, err
+	}
+	return newRanges(hunks), nil
+}
+
+func (g *GoGit) HunksBetween(fromHash, toHash string, files []string) (map[string][]Hunk, error) {
+	changes, err := g.treeChanges(fromHash, toHash, files)
+	if err != nil {
+		return nil, err
+	}
+	return changeHunks(changes)
+}
+
+func changeHunks(changes object.Changes) (map[string][]Hunk, error) {
+	result := make(map[string][]Hunk)
+	for _, ch := range changes {
+		old, new, _, newOK, err := changeContents(ch)
+		if err != nil {
+			return nil, err
+		}
+		addHunks(result, changeName(ch), old, new, newOK)
+	}
+	return result, nil
+}
+
+func (g *GoGit) CommitHunks(hash string, files []string) (map[string][]Hunk, error) {
+	c, err := g.commit(hash)
+	if err != nil {
+		return nil, err
+	}
+	if c.NumParents() > 1 {
+		return map[string][]Hunk{}, nil
+	}
+	changes, err := commitChanges(c)
+	if err != nil {
+		return nil, err
+	}
+	return changeHunks(filterChanges(changes, files))
+}
+
+// History walks HEAD's history in-process, producing what the exec backend's
+// streaming `git log --name-only` walk does.
+func (g *GoGit) History() (*History, error) {
+	h := newHistory()
+	if _, err := g.commit("HEAD"); err != nil {
+		if g.IsRepo() {
+			return h, nil // no commits yet
+		}
+		return nil, err
+	}
+	now := time.Now()
+	err := g.walk("HEAD", func(c *object.Commit, changes object.Changes) error {
+		hc := &HistoryCommit{Hash: c.Hash.String(), Short: short(c.Hash), RelDate: relativeDate(c.Author.When, now)}
+		for _, p := range c.ParentHashes {
+			hc.Parents = append(hc.Parents, p.String())
+		}
+		h.add(hc)
+		sorted := append(object.Changes(nil), changes...)
+		sort.Slice(sorted, func(i, j int) bool { return changeName(sorted[i]) < changeName(sorted[j]) })
+		for _, ch := range sorted {
+			status := byte('M')
+			switch {
+			case ch.From.Name == "":
+				status = 'A'
+			case ch.To.Name == "":
+				status = 'D'
+			}
+			h.touch(hc, status, changeName(ch))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (g *GoGit) LoadHistory() error {
+	h, err := g.History()
+	if err != nil {
+		return err
+	}
+	g.history = h
+	return nil
+}
+
+// StageAndAmend adds paths to the index and rewrites HEAD with the same
+// message, author and first parent, like `git commit --amend --no-edit`.
+func (g *GoGit) StageAndAmend(paths ...string) error {
+	r, err := g.open()
+	if err != nil {
+		return err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if _, err := wt.Add(p); err != nil {
+			return err
+		}
+	}
+	head, err := g.commit("HEAD")
+	if err != nil {
+		return err
+	}
+	committer := head.Committer
+	if cfg, err := r.ConfigScoped(gitconfig.SystemScope); err == nil && cfg.User.Name != "" && cfg.User.Email != "" {
+		committer.Name, committer.Email = cfg.User.Name, cfg.User.Email
+	}
+	committer.When = time.Now()
+	author := head.Author
+	_, err = wt.Commit(head.Message, &gogit.CommitOptions{
+		Amend:             true,
+		Author:            &author,
+		Committer:         &committer,
+		AllowEmptyCommits: true,
+	})
+	return err
+}
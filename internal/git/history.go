@@ -0,0 +1,329 @@
+package git
+
+// @doc CLAUDE.md
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// HistoryCommit is one commit from a History walk.
+type HistoryCommit struct {
+	Hash    string
+	Short   string
+	Parents []string // full hashes
+	RelDate string   // e.g. "3 days ago"
+	Files   []string // paths the commit touched (renames count as both paths)
+
+	added   map[string]bool // paths the commit created
+	deletes bool            // whether it removed any path
+}
+
+// Info formats the commit like CommitInfo: "abc1234 (3 days ago)".
+func (c *HistoryCommit) Info() string {
+	return fmt.Sprintf("%s (%s)", c.Short, c.RelDate)
+}
+
+// History is every commit reachable from HEAD with the files it touched, read
+// in one streaming `git log` walk. It answers the per-doc questions staleness
+// needs — last commit to touch a path, ancestry, files changed since a commit
+// — in memory, instead of one git process per doc. Merge commits carry no
+// files of their own; their changes are counted on the merged-in commits.
+type History struct {
+	commits   []*HistoryCommit // newest first (git log order)
+	byHash    map[string]*HistoryCommit
+	byShort   map[string]*HistoryCommit
+	lastTouch map[string]*HistoryCommit
+	since     map[string]map[string]bool // from hash -> paths touched in from..HEAD
+	differs   map[string]map[string]bool // from hash -> paths that differ between from and HEAD
+}
+
+const (
+	historyRecord = "\x1e"
+	historyField  = "\x1f"
+)
+
+// LoadHistory walks history once and keeps it, so LastCommit, IsAncestor,
+// CommitInfo and ChangedFilesBetween(…, "HEAD", files) stop spawning git per
+// call. Commits the walk doesn't know (e.g. unreachable acks) still go to git.
+// Use it before a pass over many docs; the snapshot doesn't follow new commits.
+func (g *Git) LoadHistory() error {
+	h, err := g.History()
+	if err != nil {
+		return err
+	}
+	g.history = h
+	return nil
+}
+
+// History walks the whole history of HEAD. A repository without commits yields
+// an empty History.
+func (g *Git) History() (*History, error) {
+	h := newHistory()
+	if _, err := g.run("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return h, nil
+	}
+
+	cmd := exec.Command("git", "-c", "core.quotePath=false", "log", "--no-renames", "--name-status",
+		"--format="+historyRecord+"%H"+historyField+"%h"+historyField+"%P"+historyField+"%ar", "HEAD")
+	cmd.Dir = g.workDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var cur *HistoryCommit
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, historyRecord); ok {
+			fields := strings.Split(rest, historyField)
+			if len(fields) != 4 {
+				continue
+			}
+			cur = &HistoryCommit{Hash: fields[0], Short: fields[1], Parents: strings.Fields(fields[2]), RelDate: fields[3]}
+			h.add(cur)
+			continue
+		}
+		status, path, ok := strings.Cut(line, "\t")
+		if !ok || status == "" || cur == nil {
+			continue
+		}
+		h.touch(cur, status[0], path)
+	}
+	if err := sc.Err(); err != nil {
+		cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("git log: %w: %s", err, stderr.String())
+	}
+	return h, nil
+}
+
+func newHistory() *History {
+	return &History{
+		byHash:    make(map[string]*HistoryCommit),
+		byShort:   make(map[string]*HistoryCommit),
+		lastTouch: make(map[string]*HistoryCommit),
+		since:     make(map[string]map[string]bool),
+		differs:   make(map[string]map[string]bool),
+	}
+}
+
+// add appends the next-older commit of the walk.
+func (h *History) add(c *HistoryCommit) {
+	h.commits = append(h.commits, c)
+	h.byHash[c.Hash] = c
+	h.byShort[c.Short] = c
+}
+
+// touch records that c changed path, with git's status letter for the change
+// (A, M, D, T). Commits must be added newest first.
+func (h *History) touch(c *HistoryCommit, status byte, path string) {
+	c.Files = append(c.Files, path)
+	switch status {
+	case 'A':
+		if c.added == nil {
+			c.added = make(map[string]bool)
+		}
+		c.added[path] = true
+	case 'D':
+		c.deletes = true
+	}
+	if _, seen := h.lastTouch[path]; !seen {
+		h.lastTouch[path] = c
+	}
+}
+
+// Lookup finds a commit by full or abbreviated hash, or "HEAD".
+func (h *History) Lookup(rev string) (*HistoryCommit, bool) {
+	if rev == "HEAD" {
+		if len(h.commits) == 0 {
+			return nil, false
+		}
+		return h.commits[0], true
+	}
+	if c, ok := h.byHash[rev]; ok {
+		return c, true
+	}
+	if c, ok := h.byShort[rev]; ok {
+		return c, true
+	}
+	if len(rev) < 4 {
+		return nil, false
+	}
+	var found *HistoryCommit
+	for hash, c := range h.byHash {
+		if strings.HasPrefix(hash, rev) {
+			if found != nil {
+				return nil, false // ambiguous
+			}
+			found = c
+		}
+	}
+	return found, found != nil
+}
+
+// Commits lists the walk's commits, newest first. Merge commits are included
+// with no files.
+func (h *History) Commits() []*HistoryCommit {
+	return h.commits
+}
+
+// LastCommit is the in-memory LastCommit: the short hash of the newest commit
+// that touched path, or "" if none did.
+func (h *History) LastCommit(path string) string {
+	if c, ok := h.lastTouch[path]; ok {
+		return c.Short
+	}
+	return ""
+}
+
+// LastContentCommit is the in-memory LastContentCommit. The walk has no rename
+// detection, so ok is false when the last commit to touch path created it
+// while removing something else — possibly a rename the caller must follow.
+func (h *History) LastContentCommit(path string) (hash string, ok bool) {
+	c, found := h.lastTouch[path]
+	if !found {
+		return "", true
+	}
+	if c.added[path] && c.deletes {
+		return "", false
+	}
+	return c.Short, true
+}
+
+// CoChanges walks the commits that touched path and counts how often each
+// other path changed in the same commit. Commits touching more than maxFiles
+// paths (imports, mass reformats) say little about coupling and are skipped;
+// maxFiles <= 0 keeps them all. commits is how many commits were counted.
+func (h *History) CoChanges(path string, maxFiles int) (commits int, with map[string]int) {
+	with = make(map[string]int)
+	for _, c := range h.commits {
+		if maxFiles > 0 && len(c.Files) > maxFiles {
+			continue
+		}
+		touched := false
+		for _, f := range c.Files {
+			if f == path {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			continue
+		}
+		commits++
+		for _, f := range c.Files {
+			if f != path {
+				with[f]++
+			}
+		}
+	}
+	return commits, with
+}
+
+// IsAncestor reports whether a is an ancestor of (or equal to) b. ok is false
+// when either commit isn't in the walk, so callers can fall back to git.
+func (h *History) IsAncestor(a, b string) (isAncestor, ok bool) {
+	ca, okA := h.Lookup(a)
+	cb, okB := h.Lookup(b)
+	if !okA || !okB {
+		return false, false
+	}
+	return h.ancestors(cb.Hash)[ca.Hash], true
+}
+
+// TouchedSince returns the subset of files touched by any commit in
+// from..HEAD, preserving their order. A touched file can still match its
+// version at from, e.g. after a revert; ChangedFilesSince confirms which
+// differ. ok is false when from isn't in the walk.
+func (h *History) TouchedSince(from string, files []string) (changed []string, ok bool) {
+	c, found := h.Lookup(from)
+	if !found {
+		return nil, false
+	}
+	touched, cached := h.since[c.Hash]
+	if !cached {
+		base := h.ancestors(c.Hash)
+		touched = make(map[string]bool)
+		for _, commit := range h.commits {
+			if !base[commit.Hash] {
+				for _, f := range commit.Files {
+					touched[f] = true
+				}
+			}
+		}
+		h.since[c.Hash] = touched
+	}
+	changed = make([]string, 0)
+	for _, f := range files {
+		if touched[f] {
+			changed = append(changed, f)
+		}
+	}
+	return changed, true
+}
+
+// ChangedFilesSince returns the subset of files that differ between from and
+// HEAD, preserving their order: the same answer as `git diff --name-only
+// from HEAD`. The walk narrows files to those touched since from, and only
+// when some are is diff called, once per from, to list the paths whose
+// content differs between the two trees. ok is false when from isn't in the
+// walk.
+func (h *History) ChangedFilesSince(from string, files []string, diff func(from string) ([]string, error)) (changed []string, ok bool, err error) {
+	touched, ok := h.TouchedSince(from, files)
+	if !ok || len(touched) == 0 {
+		return touched, ok, nil
+	}
+	c, _ := h.Lookup(from)
+	differs, cached := h.differs[c.Hash]
+	if !cached {
+		paths, err := diff(from)
+		if err != nil {
+			return nil, true, err
+		}
+		differs = make(map[string]bool, len(paths))
+		for _, p := range paths {
+			differs[p] = true
+		}
+		h.differs[c.Hash] = differs
+	}
+	changed = make([]string, 0, len(touched))
+	for _, f := range touched {
+		if differs[f] {
+			changed = append(changed, f)
+		}
+	}
+	return changed, true, nil
+}
+
+// ancestors returns hash and every commit reachable from it.
+func (h *History) ancestors(hash string) map[string]bool {
+	seen := map[string]bool{hash: true}
+	stack := []string{hash}
+	for len(stack) > 0 {
+		c := h.byHash[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if c == nil {
+			continue
+		}
+		for _, p := range c.Parents {
+			if !seen[p] {
+				seen[p] = true
+				stack = append(stack, p)
+			}
+		}
+	}
+	return seen
+}
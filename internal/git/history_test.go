@@ -0,0 +1,144 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	dir := setupGitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	empty, err := New(dir).History()
+	if err != nil || empty.LastCommit("a.go") != "" {
+		t.Fatalf("History() on an empty repo = %v, %v", empty, err)
+	}
+
+	base := commitFile(t, dir, "doc.md", "doc\n", "doc")
+	commitFile(t, dir, "a.go", "a\n", "a")
+	git("checkout", "-q", "-b", "side")
+	side := commitFile(t, dir, "b.go", "b\n", "b on side")
+	git("checkout", "-q", "-")
+	commitFile(t, dir, "c.go", "c\n", "c")
+	git("merge", "-q", "--no-edit", "side")
+
+	g := New(dir)
+	h, err := g.History()
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+
+	if got := h.LastCommit("b.go"); got != side {
+		t.Errorf("LastCommit(b.go) = %q, want %q", got, side)
+	}
+	if got := h.LastCommit("missing.go"); got != "" {
+		t.Errorf("LastCommit(missing.go) = %q, want empty", got)
+	}
+
+	if anc, ok := h.IsAncestor(base, side); !ok || !anc {
+		t.Errorf("IsAncestor(base, side) = %v, %v; want true", anc, ok)
+	}
+	if anc, ok := h.IsAncestor(side, base); !ok || anc {
+		t.Errorf("IsAncestor(side, base) = %v, %v; want false", anc, ok)
+	}
+	if _, ok := h.IsAncestor("0000000", "HEAD"); ok {
+		t.Error("IsAncestor() with an unknown commit should not be ok")
+	}
+
+	files := []string{"doc.md", "a.go", "b.go", "c.go"}
+	if got, ok := h.TouchedSince(base, files); !ok || !reflect.DeepEqual(got, []string{"a.go", "b.go", "c.go"}) {
+		t.Errorf("TouchedSince(base) = %v, %v", got, ok)
+	}
+	if got, ok := h.TouchedSince(side, files); !ok || !reflect.DeepEqual(got, []string{"c.go"}) {
+		t.Errorf("TouchedSince(side) = %v, %v; merged-in commits should not count", got, ok)
+	}
+
+	if c, ok := h.Lookup(side[:4]); !ok || c.Short != side {
+		t.Errorf("Lookup(%q) = %v, %v", side[:4], c, ok)
+	}
+
+	t.Run("loaded into Git", func(t *testing.T) {
+		if err := g.LoadHistory(); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := New(dir).ChangedFilesBetween(side, "HEAD", files)
+		if got, _ := g.ChangedFilesBetween(side, "HEAD", files); !reflect.DeepEqual(got, want) {
+			t.Errorf("ChangedFilesBetween() = %v, git diff gives %v", got, want)
+		}
+		want2, _ := New(dir).CommitInfo(side)
+		if got, _ := g.CommitInfo(side); got != want2 {
+			t.Errorf("CommitInfo() = %q, want %q", got, want2)
+		}
+	})
+}
+
+func TestChangedFilesBetween_RevertedChange(t *testing.T) {
+	dir := setupGitRepo(t)
+	base := commitFile(t, dir, "a.go", "a\n", "a")
+	commitFile(t, dir, "a.go", "a changed\n", "change a")
+	cmd := exec.Command("git", "revert", "--no-edit", "HEAD")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git revert: %v\n%s", err, out)
+	}
+	commitFile(t, dir, "b.go", "b\n", "b")
+
+	for name, r := range backends(dir) {
+		t.Run(name, func(t *testing.T) {
+			if err := r.LoadHistory(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.ChangedFilesBetween(base, "HEAD", []string{"a.go", "b.go"})
+			if err != nil || !reflect.DeepEqual(got, []string{"b.go"}) {
+				t.Errorf("ChangedFilesBetween() = %v, %v; want [b.go], a reverted change is no change", got, err)
+			}
+		})
+	}
+}
+
+func TestHistory_CoChanges(t *testing.T) {
+	dir := setupGitRepo(t)
+	n := 0
+	commit := func(files ...string) {
+		t.Helper()
+		n++
+		for _, f := range files {
+			os.WriteFile(filepath.Join(dir, f), []byte(strconv.Itoa(n)), 0644)
+		}
+		cmd := exec.Command("git", "add", "-A")
+		cmd.Dir = dir
+		cmd.Run()
+		cmd = exec.Command("git", "commit", "-q", "-m", "change")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v\n%s", err, out)
+		}
+	}
+	commit("a.go", "doc.md", "b.go", "c.go")
+	commit("a.go", "doc.md")
+	commit("a.go", "b.go")
+	commit("c.go")
+
+	h, err := New(dir).History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, with := h.CoChanges("a.go", 3)
+	if commits != 2 || !reflect.DeepEqual(with, map[string]int{"doc.md": 1, "b.go": 1}) {
+		t.Errorf("CoChanges(a.go, 3) = %d, %v; the four-file commit should be skipped", commits, with)
+	}
+	if commits, with := h.CoChanges("a.go", 0); commits != 3 || with["doc.md"] != 2 {
+		t.Errorf("CoChanges(a.go, 0) = %d, %v", commits, with)
+	}
+}
@@ -0,0 +1,70 @@
+package git
+
+// @doc README.md
+
+import "github.com/StevenBock/docdiff/internal/config"
+
+// Repo is the git access docdiff's commands and scanner need. Short hashes,
+// relative dates and path lists come back in git's own formats, so callers
+// can't tell the backends apart. Commits are named by anything rev-parse
+// accepts for the exec backend; the go-git backend takes hashes, hash
+// prefixes, refs and HEAD.
+type Repo interface {
+	IsRepo() bool
+	Dir() (string, error)
+	CheckIgnore(paths []string) (map[string]bool, error)
+
+	HeadShort() (string, error)
+	HeadFull() (string, error)
+	ResolveShort(ref string) (string, error)
+	IsAncestor(a, b string) (bool, error)
+
+	LastCommit(path string) (string, error)
+	LastContentCommit(path string) (string, error)
+	LastCommitInRange(path string, start, end int) (string, error)
+	LastCommitMatching(path, regex string) (string, error)
+	CommitInfo(hash string) (string, error)
+	CommitDate(hash string) (string, error)
+	CommitSubject(hash string) (string, error)
+	CommitsBetween(fromHash, toHash string, files []string) ([]string, error)
+	CommitDetails(fromHash, toHash string, files []string) ([]CommitDetail, error)
+	FileAt(rev, path string) ([]byte, bool, error)
+
+	ChangedFilesBetween(fromHash, toHash string, files []string) ([]string, error)
+	ChangedFilesSince(fromHash string, staged bool, files []string) ([]string, error)
+	FilesChangedInCommit(hash string, filterFiles []string) ([]string, error)
+	WorkingTreeFiles() ([]string, error)
+	StagedFiles() ([]string, error)
+	UntrackedFiles(files []string) ([]string, error)
+
+	Diff(fromHash, toHash string, files []string) (string, error)
+	DiffSince(fromHash string, staged bool, files []string) (string, error)
+	ShowCommitDiff(hash string, files []string) (string, error)
+	ChangedHunksSince(fromHash string, staged bool, files []string) (map[string][]LineRange, error)
+	HunksSince(fromHash string, staged bool, files []string) (map[string][]Hunk, error)
+	ChangedHunksBetween(fromHash, toHash string, files []string) (map[string][]LineRange, error)
+	HunksBetween(fromHash, toHash string, files []string) (map[string][]Hunk, error)
+	CommitHunks(hash string, files []string) (map[string][]Hunk, error)
+
+	History() (*History, error)
+	LoadHistory() error
+
+	StageAndAmend(paths ...string) error
+}
+
+var (
+	_ Repo = (*Git)(nil)
+	_ Repo = (*GoGit)(nil)
+)
+
+// Open returns the backend named by backend ("" means exec) for workDir. The
+// names are config's GitBackend constants.
+func Open(workDir, backend string) (Repo, error) {
+	if err := config.ValidateGitBackend(backend); err != nil {
+		return nil, err
+	}
+	if backend == config.GitBackendGoGit {
+		return NewGoGit(workDir), nil
+	}
+	return New(workDir), nil
+}
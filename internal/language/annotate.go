@@ -0,0 +1,84 @@
+package language
+
+// @doc README.md
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CommentSyntax is how a language writes a comment: a line-comment prefix, or
+// a block delimiter pair when it has none.
+type CommentSyntax struct {
+	Line       string
+	BlockStart string
+	BlockEnd   string
+}
+
+// Wrap turns text into a one-line comment. ok is false if the syntax is
+// unknown, as for a language declared only through comment_patterns.
+func (c CommentSyntax) Wrap(text string) (string, bool) {
+	switch {
+	case c.Line != "":
+		return c.Line + " " + text, true
+	case c.BlockStart != "" && c.BlockEnd != "":
+		return c.BlockStart + " " + text + " " + c.BlockEnd, true
+	}
+	return "", false
+}
+
+// Insertion adds Lines before line At (0-based) of a file; At may equal the
+// line count to append.
+type Insertion struct {
+	At    int
+	Lines []string
+}
+
+// Annotator places a new file-level annotation. Every strategy embedding
+// BaseStrategy gets the default: the top of the file, after any shebang and
+// encoding lines. Languages with a stronger convention override it.
+type Annotator interface {
+	PlaceAnnotation(lines []string, annotation string) (Insertion, bool)
+}
+
+// CommentSyntax is the syntax new annotations are written with.
+func (b *BaseStrategy) CommentSyntax() CommentSyntax {
+	return b.comment
+}
+
+// PlaceAnnotation puts annotation, as a comment, at the top of the file below
+// its preamble. lines are the file's lines without line endings.
+func (b *BaseStrategy) PlaceAnnotation(lines []string, annotation string) (Insertion, bool) {
+	text, ok := b.comment.Wrap(annotation)
+	if !ok {
+		return Insertion{}, false
+	}
+	return Insertion{At: preambleEnd(lines), Lines: []string{text}}, true
+}
+
+// magicComment matches the lines that must stay at the top of a file: Python
+// and Ruby encoding declarations and Ruby's frozen_string_literal pragma.
+var magicComment = regexp.MustCompile(`^#.*(coding[:=]|frozen_string_literal:)`)
+
+// preambleEnd is the index of the first line after a shebang and any magic
+// comments that follow it.
+func preambleEnd(lines []string) int {
+	i := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		i++
+	}
+	for i < len(lines) && magicComment.MatchString(lines[i]) {
+		i++
+	}
+	return i
+}
+
+// afterLine sets text off below line i with a blank line on each side, the
+// way an annotation sits under a Go package clause or a PHP open tag.
+func afterLine(lines []string, i int, text string) Insertion {
+	ins := Insertion{At: i + 1, Lines: []string{"", text}}
+	if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+		ins.Lines = append(ins.Lines, "")
+	}
+	return ins
+}
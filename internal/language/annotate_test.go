@@ -0,0 +1,120 @@
+package language
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/config"
+)
+
+func TestPlaceAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		content  string
+		want     string
+	}{
+		{
+			name:     "go after package clause",
+			strategy: NewGoStrategy(),
+			content:  "// Package a does things.\npackage a\n\nimport \"fmt\"\n",
+			want:     "// Package a does things.\npackage a\n\n// @doc docs/A.md\n\nimport \"fmt\"\n",
+		},
+		{
+			name:     "go package clause followed by code",
+			strategy: NewGoStrategy(),
+			content:  "//go:build linux\n\npackage a\nvar X = 1\n",
+			want:     "//go:build linux\n\npackage a\n\n// @doc docs/A.md\n\nvar X = 1\n",
+		},
+		{
+			name:     "php inside leading docblock",
+			strategy: NewPHPStrategy(),
+			content:  "<?php\n\nnamespace App;\n\n/**\n * Handles users.\n */\nclass User {}\n",
+			want:     "<?php\n\nnamespace App;\n\n/**\n * Handles users.\n * @doc docs/A.md\n */\nclass User {}\n",
+		},
+		{
+			name:     "php without docblock",
+			strategy: NewPHPStrategy(),
+			content:  "<?php\nclass User {}\n",
+			want:     "<?php\n\n// @doc docs/A.md\n\nclass User {}\n",
+		},
+		{
+			name:     "php docblock after code is not the file's",
+			strategy: NewPHPStrategy(),
+			content:  "<?php\n$x = 1;\n/**\n * f\n */\nfunction f() {}\n",
+			want:     "<?php\n\n// @doc docs/A.md\n\n$x = 1;\n/**\n * f\n */\nfunction f() {}\n",
+		},
+		{
+			name:     "python after shebang and encoding",
+			strategy: NewPythonStrategy(),
+			content:  "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n\"\"\"Tool.\"\"\"\n",
+			want:     "#!/usr/bin/env python\n# -*- coding: utf-8 -*-\n# @doc docs/A.md\n\"\"\"Tool.\"\"\"\n",
+		},
+		{
+			name:     "ruby after magic comment",
+			strategy: NewRubyStrategy(),
+			content:  "# frozen_string_literal: true\nclass A; end\n",
+			want:     "# frozen_string_literal: true\n# @doc docs/A.md\nclass A; end\n",
+		},
+		{
+			name:     "shell after shebang",
+			strategy: NewShellStrategy(),
+			content:  "#!/bin/sh\nset -e\n",
+			want:     "#!/bin/sh\n# @doc docs/A.md\nset -e\n",
+		},
+		{
+			name:     "javascript at top",
+			strategy: NewJavaScriptStrategy(),
+			content:  "import x from 'x'\n",
+			want:     "// @doc docs/A.md\nimport x from 'x'\n",
+		},
+		{
+			name:     "vue inside script",
+			strategy: NewVueStrategy(),
+			content:  "<template><div/></template>\n<script setup lang=\"ts\">\nconst a = 1\n</script>\n",
+			want:     "<template><div/></template>\n<script setup lang=\"ts\">\n// @doc docs/A.md\nconst a = 1\n</script>\n",
+		},
+		{
+			name:     "vue without script",
+			strategy: NewVueStrategy(),
+			content:  "<template><div/></template>\n",
+			want:     "<!-- @doc docs/A.md -->\n<template><div/></template>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.content, "\n")
+			ins, ok := tt.strategy.(Annotator).PlaceAnnotation(lines, "@doc docs/A.md")
+			if !ok {
+				t.Fatal("PlaceAnnotation() not ok")
+			}
+			out := append(append(append([]string{}, lines[:ins.At]...), ins.Lines...), lines[ins.At:]...)
+			got := strings.Join(out, "\n")
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if found := tt.strategy.ExtractAnnotations([]byte(got), "@doc"); len(found) != 1 || found[0] != "docs/A.md" {
+				t.Errorf("the placed annotation should extract, got %v", found)
+			}
+		})
+	}
+}
+
+func TestCommentSyntax(t *testing.T) {
+	r, err := FromConfig(map[string]config.LanguageConfig{
+		"lua":  {Extensions: []string{"lua"}, LineComments: []string{"--"}},
+		"ocml": {Extensions: []string{"ml"}, BlockComments: []config.BlockComment{{Start: "(*", End: "*)"}}},
+		"raw":  {Extensions: []string{"raw"}, CommentPatterns: []string{`;;[^\n]*`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"lua": "-- @doc X", "ocml": "(* @doc X *)", "raw": ""} {
+		s, _ := r.GetByName(name)
+		ins, ok := s.(Annotator).PlaceAnnotation(nil, "@doc X")
+		if got := strings.Join(ins.Lines, ""); got != want || ok != (want != "") {
+			t.Errorf("%s: PlaceAnnotation() = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+}
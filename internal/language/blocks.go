@@ -0,0 +1,257 @@
+package language
+
+// @doc CLAUDE.md
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// BlockFinder is an optional Strategy extension used for declaration-scoped
+// ownership. DeclarationBlock returns the 1-based inclusive line extent of the
+// declaration an annotation on `line` sits directly above (or trails on the
+// same line). ok is false when no declaration follows — e.g. a blank line
+// separates them — or its extent can't be found, and callers fall back to
+// next-annotation ownership.
+type BlockFinder interface {
+	DeclarationBlock(content []byte, line int) (start, end int, ok bool)
+}
+
+type blockStyle int
+
+const (
+	braceBlocks  blockStyle = iota + 1 // `{ ... }` bodies: Go, Java, JS, Rust, PHP
+	indentBlocks                       // `:` + deeper indentation: Python
+	endBlocks                          // keyword ... `end`: Ruby
+)
+
+// blockSyntax is the little a strategy needs to tell where a declaration ends
+// without a real parser. Comments come from the strategy's own patterns.
+type blockSyntax struct {
+	style blockStyle
+	// quotes are string delimiters whose contents are ignored. A backtick
+	// string may span lines; the others end at the line break.
+	quotes string
+	// semicolons: a brace-less statement runs until `;` (Java, Rust, PHP)
+	// rather than the end of its line (Go, JS).
+	semicolons bool
+	// prefixes are attribute/decorator line starts skipped between the
+	// annotation and the declaration, e.g. `@Override` or `#[derive(...)]`.
+	prefixes []string
+}
+
+// findDeclaration implements BlockFinder for any strategy given its comment
+// patterns and block syntax.
+func findDeclaration(content []byte, line int, patterns []*regexp.Regexp, syn blockSyntax) (int, int, bool) {
+	raw := strings.Split(string(content), "\n")
+	code := strings.Split(string(maskCode(content, patterns, syn.quotes)), "\n")
+	if line < 1 || line > len(raw) {
+		return 0, 0, false
+	}
+
+	start := -1
+	if strings.TrimSpace(code[line-1]) != "" {
+		start = line - 1 // annotation trails code on the same line
+	} else {
+		for i := line; i < len(raw); i++ {
+			trimmed := strings.TrimSpace(code[i])
+			if strings.TrimSpace(raw[i]) == "" {
+				return 0, 0, false // blank line: not directly above anything
+			}
+			if trimmed == "" || hasAnyPrefix(trimmed, syn.prefixes) {
+				continue // comment continuation or decorator
+			}
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+
+	var end int
+	var ok bool
+	switch syn.style {
+	case braceBlocks:
+		end, ok = braceEnd(code, start, syn.semicolons)
+	case indentBlocks:
+		end, ok = indentEnd(code, start)
+	case endBlocks:
+		end, ok = keywordEnd(code, start)
+	}
+	if !ok {
+		return 0, 0, false
+	}
+	return start + 1, end + 1, true
+}
+
+// maskCode blanks comments and string literals (keeping newlines) so bracket,
+// indentation and keyword counting only sees code.
+func maskCode(content []byte, patterns []*regexp.Regexp, quotes string) []byte {
+	masked := bytes.Clone(content)
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	}
+	for _, p := range patterns {
+		for _, loc := range p.FindAllIndex(content, -1) {
+			blank(loc[0], loc[1])
+		}
+	}
+
+	for i := 0; i < len(masked); i++ {
+		q := masked[i]
+		if q == ' ' || !strings.ContainsRune(quotes, rune(q)) {
+			continue
+		}
+		j := i + 1
+		for j < len(masked) && masked[j] != q {
+			if masked[j] == '\n' && q != '`' {
+				break
+			}
+			if masked[j] == '\\' && q != '`' {
+				j++
+			}
+			j++
+		}
+		if j >= len(masked) || masked[j] != q {
+			continue // unterminated: leave it, most likely not a string
+		}
+		blank(i+1, j)
+		i = j
+	}
+	return masked
+}
+
+var continuationSuffixes = []string{"=", ",", "(", "[", "+", "-", "*", "&&", "||", "=>", ".", "?", ":"}
+
+// braceEnd finds the line where the statement starting at `start` ends: the
+// close of its outermost bracket group when it has a `{` body, else its `;`
+// (semicolon languages) or the first line break outside brackets.
+func braceEnd(code []string, start int, semicolons bool) (int, bool) {
+	depth := 0
+	sawBrace := false
+	sawSemicolon := false
+	for i := start; i < len(code); i++ {
+		for _, c := range code[i] {
+			switch c {
+			case '{':
+				sawBrace = true
+				depth++
+			case '(', '[':
+				depth++
+			case ')', ']', '}':
+				depth--
+			case ';':
+				if depth == 0 {
+					sawSemicolon = true
+				}
+			}
+		}
+		if depth > 0 {
+			continue
+		}
+		if depth < 0 {
+			return i, true // closed the enclosing block: the statement ended here
+		}
+		trimmed := strings.TrimSpace(code[i])
+		switch {
+		case sawBrace || sawSemicolon:
+			return i, true
+		case semicolons:
+			continue // signature still heading for its `{` or `;`
+		case trimmed == "" || hasAnySuffix(trimmed, continuationSuffixes):
+			continue
+		default:
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// indentEnd handles Python: a header ending in `:` owns every following line
+// indented deeper than it; anything else is a (possibly bracketed) statement.
+func indentEnd(code []string, start int) (int, bool) {
+	depth := 0
+	header := start
+	for ; header < len(code); header++ {
+		depth += strings.Count(code[header], "(") + strings.Count(code[header], "[") + strings.Count(code[header], "{")
+		depth -= strings.Count(code[header], ")") + strings.Count(code[header], "]") + strings.Count(code[header], "}")
+		if depth <= 0 {
+			break
+		}
+	}
+	if header >= len(code) {
+		return 0, false
+	}
+	if !strings.HasSuffix(strings.TrimSpace(code[header]), ":") {
+		return header, true
+	}
+
+	indent := indentation(code[start])
+	end := header
+	for i := header + 1; i < len(code); i++ {
+		if strings.TrimSpace(code[i]) == "" {
+			continue
+		}
+		if indentation(code[i]) <= indent {
+			break
+		}
+		end = i
+	}
+	return end, true
+}
+
+var (
+	rubyOpener     = regexp.MustCompile(`^(?:def|class|module|if|unless|while|until|case|begin|for)\b`)
+	rubyEndlessDef = regexp.MustCompile(`^def\s+[\w.?!]+(?:\([^)]*\))?\s*=[^=~]`)
+	rubyDo         = regexp.MustCompile(`\bdo\b`)
+	rubyEnd        = regexp.MustCompile(`\bend\b`)
+)
+
+// keywordEnd handles Ruby: count block openers (leading keywords and `do`)
+// against `end` until they balance. A line that opens nothing is a statement.
+func keywordEnd(code []string, start int) (int, bool) {
+	depth := 0
+	for i := start; i < len(code); i++ {
+		trimmed := strings.TrimSpace(code[i])
+		if rubyOpener.MatchString(trimmed) && !rubyEndlessDef.MatchString(trimmed) {
+			depth++
+		}
+		depth += len(rubyDo.FindAllString(trimmed, -1))
+		depth -= len(rubyEnd.FindAllString(trimmed, -1))
+		if i == start && depth == 0 {
+			return i, true
+		}
+		if depth <= 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, p := range suffixes {
+		if strings.HasSuffix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package language
+
+import "testing"
+
+func TestDeclarationBlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		strategy  Strategy
+		content   string
+		line      int
+		wantStart int
+		wantEnd   int
+		wantOK    bool
+	}{
+		{
+			name:     "go function",
+			strategy: NewGoStrategy(),
+			content: `package main
+
+// @doc docs/A.md #a
+func A() {
+	if x {
+		s := "}"
+	}
+}
+
+func unrelated() {}
+`,
+			line: 3, wantStart: 4, wantEnd: 8, wantOK: true,
+		},
+		{
+			name:     "go doc comment continues below the annotation",
+			strategy: NewGoStrategy(),
+			content: `// @doc docs/A.md #a
+// A does things.
+/* more prose */
+type A struct {
+	X int
+}
+`,
+			line: 1, wantStart: 4, wantEnd: 6, wantOK: true,
+		},
+		{
+			name:     "go single-line declaration",
+			strategy: NewGoStrategy(),
+			content:  "// @doc docs/A.md #a\nvar A = 1\nvar B = 2\n",
+			line:     1, wantStart: 2, wantEnd: 2, wantOK: true,
+		},
+		{
+			name:     "go grouped declaration",
+			strategy: NewGoStrategy(),
+			content:  "// @doc docs/A.md #a\nvar (\n\tA = 1\n\tB = 2\n)\nvar C = 3\n",
+			line:     1, wantStart: 2, wantEnd: 5, wantOK: true,
+		},
+		{
+			name:     "blank line separates annotation from code",
+			strategy: NewGoStrategy(),
+			content:  "// @doc docs/A.md #a\n\nfunc A() {}\n",
+			line:     1, wantOK: false,
+		},
+		{
+			name:     "trailing annotation owns its own line's declaration",
+			strategy: NewGoStrategy(),
+			content:  "func A() { // @doc docs/A.md #a\n\treturn\n}\nfunc B() {}\n",
+			line:     1, wantStart: 1, wantEnd: 3, wantOK: true,
+		},
+		{
+			name:     "java method with annotation and Allman brace",
+			strategy: NewJavaStrategy(),
+			content: `class C {
+    // @doc docs/A.md #a
+    @Override
+    public void run(
+        int x)
+    {
+        call();
+    }
+
+    void other() {}
+}
+`,
+			line: 2, wantStart: 4, wantEnd: 8, wantOK: true,
+		},
+		{
+			name:     "javascript arrow function across lines",
+			strategy: NewJavaScriptStrategy(),
+			content: `// @doc docs/A.md #a
+export const handler =
+  async (req) => {
+    return ` + "`${req}}`" + `;
+  };
+const other = 1;
+`,
+			line: 1, wantStart: 2, wantEnd: 5, wantOK: true,
+		},
+		{
+			name:     "rust function with attribute and lifetime",
+			strategy: NewRustStrategy(),
+			content: `/// @doc docs/A.md #a
+#[inline]
+fn first<'a>(s: &'a str) -> &'a str {
+    &s[..1]
+}
+fn other() {}
+`,
+			line: 1, wantStart: 3, wantEnd: 5, wantOK: true,
+		},
+		{
+			name:     "php method",
+			strategy: NewPHPStrategy(),
+			content: `<?php
+class C {
+    /** @doc docs/A.md #a */
+    public function a(): string {
+        return '{';
+    }
+    public function b() {}
+}
+`,
+			line: 3, wantStart: 4, wantEnd: 6, wantOK: true,
+		},
+		{
+			name:     "python function by indentation",
+			strategy: NewPythonStrategy(),
+			content: `# @doc docs/A.md #a
+@decorator
+def a(x,
+      y):
+    """Docstring.
+Column-zero docstring text stays inside."""
+    if x:
+
+        return y
+    return x
+
+def other():
+    pass
+`,
+			line: 1, wantStart: 3, wantEnd: 10, wantOK: true,
+		},
+		{
+			name:     "python statement",
+			strategy: NewPythonStrategy(),
+			content:  "# @doc docs/A.md #a\nLIMIT = 10\nOTHER = 2\n",
+			line:     1, wantStart: 2, wantEnd: 2, wantOK: true,
+		},
+		{
+			name:     "ruby method with nested blocks",
+			strategy: NewRubyStrategy(),
+			content: `class C
+  # @doc docs/A.md #a
+  def a
+    items.each do |i|
+      puts "the end" if i
+    end
+  end
+
+  def b; end
+end
+`,
+			line: 2, wantStart: 3, wantEnd: 7, wantOK: true,
+		},
+		{
+			name:     "ruby endless method",
+			strategy: NewRubyStrategy(),
+			content:  "# @doc docs/A.md #a\ndef a = 1\ndef b\nend\n",
+			line:     1, wantStart: 2, wantEnd: 2, wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder, ok := tt.strategy.(BlockFinder)
+			if !ok {
+				t.Fatalf("%s does not implement BlockFinder", tt.strategy.Name())
+			}
+			start, end, ok := finder.DeclarationBlock([]byte(tt.content), tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("DeclarationBlock() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (start != tt.wantStart || end != tt.wantEnd) {
+				t.Errorf("DeclarationBlock() = [%d, %d], want [%d, %d]", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+
+	t.Run("strategies without block syntax don't implement it", func(t *testing.T) {
+		if _, ok := Strategy(NewShellStrategy()).(BlockFinder); ok {
+			t.Error("shell should fall back to next-annotation ownership")
+		}
+	})
+}
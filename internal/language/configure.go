@@ -0,0 +1,194 @@
+package language
+
+// @doc README.md
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// FromConfig builds the default registry adjusted by the `languages:` config
+// block. A language with `enabled: false` is dropped entirely (no extension,
+// shebang, modeline, or content match will select it). For a built-in, extra
+// `extensions`, `interpreters` and `modelines` are added to its own, and any
+// comment syntax is appended to its patterns; `exclude` globs scope any
+// language out of matching paths. A name that isn't built in declares a new
+// language from the same fields. Languages are applied in name order after the
+// defaults, so configured extensions win over built-in ones and errors are
+// deterministic.
+func FromConfig(langs map[string]config.LanguageConfig) (*Registry, error) {
+	r := DefaultRegistry()
+
+	names := make([]string, 0, len(langs))
+	for name := range langs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lc := langs[name]
+		if !lc.IsEnabled() {
+			r.Unregister(name)
+			continue
+		}
+
+		exts, err := normalizeExtensions(name, lc.Extensions)
+		if err != nil {
+			return nil, err
+		}
+		patterns, err := commentPatterns(name, lc)
+		if err != nil {
+			return nil, err
+		}
+		interpreters, modelines, err := detectionNames(name, lc)
+		if err != nil {
+			return nil, err
+		}
+		for i, pattern := range lc.Exclude {
+			if !doublestar.ValidatePattern(pattern) {
+				return nil, fmt.Errorf("languages.%s.exclude[%d]: invalid glob %q", name, i, pattern)
+			}
+		}
+
+		s, builtin := r.GetByName(name)
+		if builtin {
+			b, ok := s.(interface{ base() *BaseStrategy })
+			if !ok {
+				return nil, fmt.Errorf("languages.%s: language cannot be configured", name)
+			}
+			extendStrategy(b.base(), exts, patterns)
+		} else {
+			if len(patterns) == 0 {
+				return nil, fmt.Errorf("languages.%s: unknown language; declare line_comments, block_comments or comment_patterns to define it", name)
+			}
+			if len(exts) == 0 && len(interpreters) == 0 && len(modelines) == 0 {
+				return nil, fmt.Errorf("languages.%s: declares no extensions, interpreters or modelines, so no file can be detected as it", name)
+			}
+			custom := NewCustomStrategy(name, exts, patterns)
+			custom.comment = commentSyntax(lc)
+			s = custom
+		}
+
+		r.Register(s)
+		for _, interp := range interpreters {
+			r.RegisterInterpreter(interp, name)
+		}
+		for _, mode := range modelines {
+			r.RegisterModeline(mode, name)
+		}
+		if len(lc.Exclude) > 0 {
+			r.ExcludePaths(name, lc.Exclude)
+		}
+	}
+
+	return r, nil
+}
+
+func extendStrategy(b *BaseStrategy, exts []string, patterns []*regexp.Regexp) {
+	for _, ext := range exts {
+		if !containsString(b.extensions, ext) {
+			b.extensions = append(b.extensions, ext)
+		}
+	}
+	b.patterns = append(b.patterns, patterns...)
+}
+
+// normalizeExtensions lowercases extensions and adds a missing leading dot, so
+// `pyi`, `.pyi` and `.PYI` all register as `.pyi` (the detector lowercases the
+// file's extension before lookup).
+func normalizeExtensions(name string, exts []string) ([]string, error) {
+	out := make([]string, 0, len(exts))
+	for i, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "" || ext == "." || strings.ContainsAny(ext, `/\ `) {
+			return nil, fmt.Errorf("languages.%s.extensions[%d]: invalid extension %q", name, i, exts[i])
+		}
+		out = append(out, ext)
+	}
+	return out, nil
+}
+
+// commentPatterns turns declared line/block delimiters into comment regexes
+// and compiles any raw comment_patterns after them.
+func commentPatterns(name string, lc config.LanguageConfig) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for i, tok := range lc.LineComments {
+		if strings.TrimSpace(tok) == "" {
+			return nil, fmt.Errorf("languages.%s.line_comments[%d]: empty delimiter", name, i)
+		}
+		out = append(out, regexp.MustCompile(regexp.QuoteMeta(tok)+`[^\n]*`))
+	}
+	for i, bc := range lc.BlockComments {
+		if strings.TrimSpace(bc.Start) == "" || strings.TrimSpace(bc.End) == "" {
+			return nil, fmt.Errorf("languages.%s.block_comments[%d]: both start and end are required", name, i)
+		}
+		out = append(out, regexp.MustCompile(`(?s)`+regexp.QuoteMeta(bc.Start)+`.*?`+regexp.QuoteMeta(bc.End)))
+	}
+	for i, p := range lc.CommentPatterns {
+		if strings.TrimSpace(p) == "" {
+			return nil, fmt.Errorf("languages.%s.comment_patterns[%d]: empty pattern", name, i)
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("languages.%s.comment_patterns[%d]: invalid regex %q: %w", name, i, p, err)
+		}
+		if re.MatchString("") {
+			return nil, fmt.Errorf("languages.%s.comment_patterns[%d]: pattern %q matches the empty string", name, i, p)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// commentSyntax is how annotate writes comments in a declared language: its
+// first line comment, else its first block comment. Raw comment_patterns give
+// no syntax to write with.
+func commentSyntax(lc config.LanguageConfig) CommentSyntax {
+	if len(lc.LineComments) > 0 {
+		return CommentSyntax{Line: lc.LineComments[0]}
+	}
+	if len(lc.BlockComments) > 0 {
+		return CommentSyntax{BlockStart: lc.BlockComments[0].Start, BlockEnd: lc.BlockComments[0].End}
+	}
+	return CommentSyntax{}
+}
+
+var (
+	interpreterName = regexp.MustCompile(`^[^\s/]+$`)
+	modelineName    = regexp.MustCompile(`^\w+$`)
+)
+
+// detectionNames validates shebang interpreters and modeline names against
+// what the detector's patterns can actually capture.
+func detectionNames(name string, lc config.LanguageConfig) ([]string, []string, error) {
+	for i, interp := range lc.Interpreters {
+		if !interpreterName.MatchString(interp) {
+			return nil, nil, fmt.Errorf("languages.%s.interpreters[%d]: invalid interpreter %q (use the bare program name, e.g. \"lua\")", name, i, interp)
+		}
+	}
+	modes := make([]string, 0, len(lc.Modelines))
+	for i, mode := range lc.Modelines {
+		if !modelineName.MatchString(mode) {
+			return nil, nil, fmt.Errorf("languages.%s.modelines[%d]: invalid modeline name %q", name, i, mode)
+		}
+		modes = append(modes, strings.ToLower(mode))
+	}
+	return lc.Interpreters, modes, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
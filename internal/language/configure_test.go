@@ -0,0 +1,178 @@
+package language
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/config"
+)
+
+func TestFromConfig(t *testing.T) {
+	disabled := false
+
+	t.Run("empty config matches defaults", func(t *testing.T) {
+		r, err := FromConfig(nil)
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		if got, want := len(r.AllStrategies()), len(DefaultRegistry().AllStrategies()); got != want {
+			t.Errorf("AllStrategies() = %d, want %d", got, want)
+		}
+	})
+
+	t.Run("disabled language is dropped", func(t *testing.T) {
+		r, err := FromConfig(map[string]config.LanguageConfig{
+			"php": {Enabled: &disabled},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		if _, ok := r.GetByName("php"); ok {
+			t.Error("php should not be registered")
+		}
+		if _, ok := r.GetByExtension(".php"); ok {
+			t.Error(".php should not map to any strategy")
+		}
+		if _, ok := r.GetByName("go"); !ok {
+			t.Error("other languages should stay registered")
+		}
+	})
+
+	t.Run("extra extensions map onto existing strategies", func(t *testing.T) {
+		r, err := FromConfig(map[string]config.LanguageConfig{
+			"python":     {Extensions: []string{".py", ".pyi"}},
+			"javascript": {Extensions: []string{"mts", ".CTS"}},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		for ext, want := range map[string]string{
+			".py": "python", ".pyw": "python", ".pyi": "python",
+			".js": "javascript", ".mts": "javascript", ".cts": "javascript",
+		} {
+			s, ok := r.GetByExtension(ext)
+			if !ok || s.Name() != want {
+				t.Errorf("GetByExtension(%s) = %v, want %s", ext, s, want)
+			}
+		}
+	})
+
+	t.Run("comment patterns extend extraction", func(t *testing.T) {
+		r, err := FromConfig(map[string]config.LanguageConfig{
+			"go": {CommentPatterns: []string{`(?m)^;;[^\n]*`}},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		s, _ := r.GetByName("go")
+		got := s.ExtractDetailed([]byte("package main\n;; @doc docs/X.md\n// @doc docs/Y.md\n"), "@doc")
+		if len(got) != 2 {
+			t.Fatalf("ExtractDetailed() = %+v, want 2 annotations", got)
+		}
+	})
+
+	t.Run("configuring does not leak into the default registry", func(t *testing.T) {
+		if _, err := FromConfig(map[string]config.LanguageConfig{
+			"python": {Extensions: []string{".pyi"}},
+		}); err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		if _, ok := DefaultRegistry().GetByExtension(".pyi"); ok {
+			t.Error("DefaultRegistry should not see configured extensions")
+		}
+	})
+
+	t.Run("declares a new language", func(t *testing.T) {
+		r, err := FromConfig(map[string]config.LanguageConfig{
+			"lua": {
+				Extensions:    []string{".lua"},
+				Interpreters:  []string{"lua"},
+				Modelines:     []string{"Lua"},
+				LineComments:  []string{"--"},
+				BlockComments: []config.BlockComment{{Start: "--[[", End: "]]"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		s, ok := r.GetByExtension(".lua")
+		if !ok || s.Name() != "lua" {
+			t.Fatalf("GetByExtension(.lua) = %v, want lua", s)
+		}
+		if name, ok := r.LanguageForInterpreter("lua"); !ok || name != "lua" {
+			t.Errorf("LanguageForInterpreter(lua) = %q, want lua", name)
+		}
+		if name, ok := r.LanguageForModeline("lua"); !ok || name != "lua" {
+			t.Errorf("LanguageForModeline(lua) = %q, want lua", name)
+		}
+
+		src := "-- @doc docs/A.md\nlocal x = 1\n--[[\n  @doc docs/B.md #init\n]]\n"
+		got := s.ExtractDetailed([]byte(src), "@doc")
+		if len(got) != 2 {
+			t.Fatalf("ExtractDetailed() = %+v, want 2 annotations", got)
+		}
+		if got[1].Path != "docs/B.md" || got[1].Scope != "init" || got[1].Line != 4 {
+			t.Errorf("block annotation = %+v, want {docs/B.md init 4}", got[1])
+		}
+	})
+
+	t.Run("disabling a declared language removes its detection names", func(t *testing.T) {
+		r := DefaultRegistry()
+		r.Register(NewCustomStrategy("sql", []string{".sql"}, nil))
+		r.RegisterInterpreter("psql", "sql")
+		r.Unregister("sql")
+		if _, ok := r.LanguageForInterpreter("psql"); ok {
+			t.Error("Unregister should drop the language's interpreters")
+		}
+	})
+
+	t.Run("exclude scopes a language out of paths", func(t *testing.T) {
+		r, err := FromConfig(map[string]config.LanguageConfig{
+			"php": {Exclude: []string{"legacy/**", "*.tpl.php"}},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig() error = %v", err)
+		}
+		for path, want := range map[string]bool{
+			"legacy/index.php":     true,
+			"legacy/a/b.php":       true,
+			"views/page.tpl.php":   true,
+			"src/index.php":        false,
+			"src/legacy/index.php": false,
+		} {
+			if got := r.Excludes("php", path); got != want {
+				t.Errorf("Excludes(php, %s) = %v, want %v", path, got, want)
+			}
+		}
+		if r.Excludes("go", "legacy/main.go") {
+			t.Error("excludes should apply only to the configured language")
+		}
+		if r.Signature() == DefaultRegistry().Signature() {
+			t.Error("Signature() should change with excludes")
+		}
+	})
+
+	errCases := []struct {
+		name  string
+		langs map[string]config.LanguageConfig
+		want  string
+	}{
+		{"unknown language", map[string]config.LanguageConfig{"cobol": {}}, "languages.cobol: unknown language"},
+		{"undetectable language", map[string]config.LanguageConfig{"sql": {LineComments: []string{"--"}}}, "languages.sql: declares no extensions"},
+		{"half a block delimiter", map[string]config.LanguageConfig{"sql": {Extensions: []string{".sql"}, BlockComments: []config.BlockComment{{Start: "/*"}}}}, "languages.sql.block_comments[0]"},
+		{"bad interpreter", map[string]config.LanguageConfig{"lua": {Extensions: []string{".lua"}, LineComments: []string{"--"}, Interpreters: []string{"/usr/bin/lua"}}}, "languages.lua.interpreters[0]"},
+		{"bad modeline", map[string]config.LanguageConfig{"lua": {Extensions: []string{".lua"}, LineComments: []string{"--"}, Modelines: []string{"lua-mode"}}}, "languages.lua.modelines[0]"},
+		{"bad regex", map[string]config.LanguageConfig{"go": {CommentPatterns: []string{`(unclosed`}}}, "languages.go.comment_patterns[0]: invalid regex"},
+		{"empty-matching regex", map[string]config.LanguageConfig{"go": {CommentPatterns: []string{`x*`}}}, "matches the empty string"},
+		{"bad extension", map[string]config.LanguageConfig{"go": {Extensions: []string{"a/b"}}}, "languages.go.extensions[0]: invalid extension"},
+		{"bad exclude", map[string]config.LanguageConfig{"php": {Exclude: []string{"legacy/[a"}}}, "languages.php.exclude[0]: invalid glob"},
+	}
+	for _, tt := range errCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromConfig(tt.langs)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FromConfig() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package language
+
+import "regexp"
+
+// CustomStrategy is a language declared entirely in `.docdiff.yaml` rather
+// than compiled in. Its comment patterns come from the declared delimiters.
+type CustomStrategy struct {
+	BaseStrategy
+}
+
+func NewCustomStrategy(name string, extensions []string, patterns []*regexp.Regexp) *CustomStrategy {
+	return &CustomStrategy{
+		BaseStrategy: BaseStrategy{
+			name:       name,
+			extensions: extensions,
+			patterns:   patterns,
+		},
+	}
+}
+
+func (c *CustomStrategy) ExtractAnnotations(content []byte, tag string) []string {
+	return c.ExtractFromPatterns(content, tag, c.patterns)
+}
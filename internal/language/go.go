@@ -15,6 +15,7 @@ func NewGoStrategy() *GoStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`(?s)/\*.*?\*/`),
 			},
+			comment: CommentSyntax{Line: "//"},
 		},
 	}
 }
@@ -22,3 +23,30 @@ func NewGoStrategy() *GoStrategy {
 func (g *GoStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return g.ExtractFromPatterns(content, tag, g.patterns)
 }
+
+var goBlocks = blockSyntax{style: braceBlocks, quotes: "\"'`"}
+
+func (g *GoStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, g.patterns, goBlocks)
+}
+
+// goDecl matches exported top-level funcs, methods, types, vars and consts.
+var goDecl = regexp.MustCompile(`(?m)^(?:func(?:\s*\([^)]*\))?|type|var|const)\s+([A-Z]\w*)`)
+
+func (g *GoStrategy) DeclaredSymbols(content []byte) []string {
+	return matchSymbols(goDecl, content)
+}
+
+// PlaceAnnotation puts the annotation below the package clause, where it
+// can't be mistaken for the package doc comment or a build constraint.
+func (g *GoStrategy) PlaceAnnotation(lines []string, annotation string) (Insertion, bool) {
+	text, _ := g.comment.Wrap(annotation)
+	for i, line := range lines {
+		if goPackageClause.MatchString(line) {
+			return afterLine(lines, i, text), true
+		}
+	}
+	return g.BaseStrategy.PlaceAnnotation(lines, annotation)
+}
+
+var goPackageClause = regexp.MustCompile(`^package\s+\w+`)
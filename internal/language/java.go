@@ -16,6 +16,7 @@ func NewJavaStrategy() *JavaStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`(?s)/\*.*?\*/`),
 			},
+			comment: CommentSyntax{Line: "//"},
 		},
 	}
 }
@@ -23,3 +24,16 @@ func NewJavaStrategy() *JavaStrategy {
 func (j *JavaStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return j.ExtractFromPatterns(content, tag, j.patterns)
 }
+
+var javaBlocks = blockSyntax{style: braceBlocks, quotes: `"'`, semicolons: true, prefixes: []string{"@"}}
+
+func (j *JavaStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, j.patterns, javaBlocks)
+}
+
+// javaDecl matches class, interface, enum and record declarations.
+var javaDecl = regexp.MustCompile(`\b(?:class|interface|enum|record)\s+([A-Z]\w*)`)
+
+func (j *JavaStrategy) DeclaredSymbols(content []byte) []string {
+	return matchSymbols(javaDecl, content)
+}
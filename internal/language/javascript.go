@@ -15,6 +15,7 @@ func NewJavaScriptStrategy() *JavaScriptStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`(?s)/\*.*?\*/`),
 			},
+			comment: CommentSyntax{Line: "//"},
 		},
 	}
 }
@@ -22,3 +23,16 @@ func NewJavaScriptStrategy() *JavaScriptStrategy {
 func (j *JavaScriptStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return j.ExtractFromPatterns(content, tag, j.patterns)
 }
+
+var javaScriptBlocks = blockSyntax{style: braceBlocks, quotes: "\"'`", prefixes: []string{"@"}}
+
+func (j *JavaScriptStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, j.patterns, javaScriptBlocks)
+}
+
+// javaScriptDecl matches named exports, TypeScript types included.
+var javaScriptDecl = regexp.MustCompile(`(?m)^export\s+(?:default\s+)?(?:async\s+)?(?:function\*?|class|const|let|var|interface|type|enum)\s+([A-Za-z_$][\w$]*)`)
+
+func (j *JavaScriptStrategy) DeclaredSymbols(content []byte) []string {
+	return matchSymbols(javaScriptDecl, content)
+}
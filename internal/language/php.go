@@ -1,6 +1,9 @@
 package language
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
 type PHPStrategy struct {
 	BaseStrategy
@@ -16,6 +19,7 @@ func NewPHPStrategy() *PHPStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`#[^\n]*`),
 			},
+			comment: CommentSyntax{Line: "//"},
 		},
 	}
 }
@@ -23,3 +27,41 @@ func NewPHPStrategy() *PHPStrategy {
 func (p *PHPStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return p.ExtractFromPatterns(content, tag, p.patterns)
 }
+
+var phpBlocks = blockSyntax{style: braceBlocks, quotes: `"'`, semicolons: true}
+
+func (p *PHPStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, p.patterns, phpBlocks)
+}
+
+// PlaceAnnotation adds the annotation as a tag in the file's leading docblock
+// if it has one, else below the opening <?php tag.
+func (p *PHPStrategy) PlaceAnnotation(lines []string, annotation string) (Insertion, bool) {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "<?php") || phpHeaderLine.MatchString(trimmed):
+			continue
+		case strings.HasPrefix(trimmed, "/**") && !strings.Contains(trimmed, "*/"):
+			for j := i + 1; j < len(lines); j++ {
+				if end := strings.TrimSpace(lines[j]); strings.HasPrefix(end, "*/") {
+					indent := lines[j][:strings.Index(lines[j], "*/")]
+					return Insertion{At: j, Lines: []string{indent + "* " + annotation}}, true
+				}
+			}
+		}
+		break
+	}
+
+	text, _ := p.comment.Wrap(annotation)
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "<?php") {
+			return afterLine(lines, i, text), true
+		}
+	}
+	return p.BaseStrategy.PlaceAnnotation(lines, annotation)
+}
+
+// phpHeaderLine matches the statements that may precede a file's leading
+// docblock.
+var phpHeaderLine = regexp.MustCompile(`^(declare\s*\(|namespace\s|use\s)`)
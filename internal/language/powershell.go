@@ -15,6 +15,7 @@ func NewPowerShellStrategy() *PowerShellStrategy {
 				regexp.MustCompile(`#[^\n]*`),
 				regexp.MustCompile(`(?s)<#.*?#>`),
 			},
+			comment: CommentSyntax{Line: "#"},
 		},
 	}
 }
@@ -16,6 +16,7 @@ func NewPythonStrategy() *PythonStrategy {
 				regexp.MustCompile(`(?s)""".*?"""`),
 				regexp.MustCompile(`(?s)'''.*?'''`),
 			},
+			comment: CommentSyntax{Line: "#"},
 		},
 	}
 }
@@ -23,3 +24,17 @@ func NewPythonStrategy() *PythonStrategy {
 func (p *PythonStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return p.ExtractFromPatterns(content, tag, p.patterns)
 }
+
+var pythonBlocks = blockSyntax{style: indentBlocks, quotes: `"'`, prefixes: []string{"@"}}
+
+func (p *PythonStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, p.patterns, pythonBlocks)
+}
+
+// pythonDecl matches top-level defs and classes; a leading underscore marks a
+// name private.
+var pythonDecl = regexp.MustCompile(`(?m)^(?:async\s+)?(?:def|class)\s+([A-Za-z]\w*)`)
+
+func (p *PythonStrategy) DeclaredSymbols(content []byte) []string {
+	return matchSymbols(pythonDecl, content)
+}
@@ -2,18 +2,32 @@ package language
 
 // @doc CLAUDE.md
 
-import "sync"
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
 
 type Registry struct {
-	mu         sync.RWMutex
-	strategies map[string]Strategy
-	extMap     map[string]Strategy
+	mu           sync.RWMutex
+	strategies   map[string]Strategy
+	extMap       map[string]Strategy
+	interpreters map[string]string   // shebang interpreter -> language name
+	modelines    map[string]string   // vim/emacs modeline name -> language name
+	excludes     map[string][]string // language name -> path globs it doesn't apply to
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		strategies: make(map[string]Strategy),
-		extMap:     make(map[string]Strategy),
+		strategies:   make(map[string]Strategy),
+		extMap:       make(map[string]Strategy),
+		interpreters: make(map[string]string),
+		modelines:    make(map[string]string),
+		excludes:     make(map[string][]string),
 	}
 }
 
@@ -42,6 +56,91 @@ func (r *Registry) Register(s Strategy) {
 	}
 }
 
+// Unregister removes a strategy and every extension mapped to it. Unknown
+// names are a no-op.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.strategies[name]
+	if !ok {
+		return
+	}
+	delete(r.strategies, name)
+	for ext, mapped := range r.extMap {
+		if mapped == s {
+			delete(r.extMap, ext)
+		}
+	}
+	for interp, lang := range r.interpreters {
+		if lang == name {
+			delete(r.interpreters, interp)
+		}
+	}
+	for mode, lang := range r.modelines {
+		if lang == name {
+			delete(r.modelines, mode)
+		}
+	}
+	delete(r.excludes, name)
+}
+
+// RegisterInterpreter maps a shebang interpreter (e.g. "elixir") to a
+// language. Registry entries take precedence over the detector's built-in map.
+func (r *Registry) RegisterInterpreter(interpreter, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interpreters[interpreter] = name
+}
+
+// RegisterModeline maps a vim/emacs modeline name (e.g. "terraform") to a
+// language. Names are matched case-insensitively.
+func (r *Registry) RegisterModeline(mode, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.modelines[strings.ToLower(mode)] = name
+}
+
+// ExcludePaths scopes a language out of the repo-relative paths matching
+// patterns. A pattern without a slash matches the basename at any depth.
+func (r *Registry) ExcludePaths(name string, patterns []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.excludes[name] = append(r.excludes[name], patterns...)
+}
+
+// Excludes reports whether the language is scoped out of relPath.
+func (r *Registry) Excludes(name, relPath string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	base := path.Base(relPath)
+	for _, pattern := range r.excludes[name] {
+		if ok, _ := doublestar.Match(pattern, relPath); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := doublestar.Match(pattern, base); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Registry) LanguageForInterpreter(interpreter string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.interpreters[interpreter]
+	return name, ok
+}
+
+func (r *Registry) LanguageForModeline(mode string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.modelines[strings.ToLower(mode)]
+	return name, ok
+}
+
 func (r *Registry) GetByExtension(ext string) (Strategy, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -75,3 +174,44 @@ func (r *Registry) AllExtensions() []string {
 	}
 	return exts
 }
+
+// Signature describes everything about the registry that affects detection
+// and extraction — each strategy's name, extensions and comment patterns, plus
+// the interpreter and modeline maps and per-language excludes — in a stable
+// order. Caches of extracted
+// annotations use it to notice when the configured languages change.
+func (r *Registry) Signature() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var b strings.Builder
+	for _, name := range sortedKeys(r.strategies) {
+		s := r.strategies[name]
+		fmt.Fprintf(&b, "strategy %s %T %q\n", name, s, s.Extensions())
+		for _, p := range s.CommentPatterns() {
+			fmt.Fprintf(&b, "  pattern %q\n", p.String())
+		}
+	}
+	for _, ext := range sortedKeys(r.extMap) {
+		fmt.Fprintf(&b, "ext %s %s\n", ext, r.extMap[ext].Name())
+	}
+	for _, interp := range sortedKeys(r.interpreters) {
+		fmt.Fprintf(&b, "interpreter %s %s\n", interp, r.interpreters[interp])
+	}
+	for _, mode := range sortedKeys(r.modelines) {
+		fmt.Fprintf(&b, "modeline %s %s\n", mode, r.modelines[mode])
+	}
+	for _, name := range sortedKeys(r.excludes) {
+		fmt.Fprintf(&b, "exclude %s %q\n", name, r.excludes[name])
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
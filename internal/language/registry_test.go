@@ -122,3 +122,19 @@ func TestRegistry(t *testing.T) {
 		}
 	})
 }
+
+func TestRegistry_Signature(t *testing.T) {
+	if DefaultRegistry().Signature() != DefaultRegistry().Signature() {
+		t.Error("Signature() should be stable across identical registries")
+	}
+	r := DefaultRegistry()
+	r.RegisterInterpreter("lua", "go")
+	if r.Signature() == DefaultRegistry().Signature() {
+		t.Error("Signature() should change when detection names change")
+	}
+	r = DefaultRegistry()
+	r.Unregister("php")
+	if r.Signature() == DefaultRegistry().Signature() {
+		t.Error("Signature() should change when a strategy is removed")
+	}
+}
@@ -15,6 +15,7 @@ func NewRubyStrategy() *RubyStrategy {
 				regexp.MustCompile(`#[^\n]*`),
 				regexp.MustCompile(`(?s)=begin.*?=end`),
 			},
+			comment: CommentSyntax{Line: "#"},
 		},
 	}
 }
@@ -22,3 +23,9 @@ func NewRubyStrategy() *RubyStrategy {
 func (r *RubyStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return r.ExtractFromPatterns(content, tag, r.patterns)
 }
+
+var rubyBlocks = blockSyntax{style: endBlocks, quotes: `"'`}
+
+func (r *RubyStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, r.patterns, rubyBlocks)
+}
@@ -15,6 +15,7 @@ func NewRustStrategy() *RustStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`(?s)/\*.*?\*/`),
 			},
+			comment: CommentSyntax{Line: "//"},
 		},
 	}
 }
@@ -22,3 +23,9 @@ func NewRustStrategy() *RustStrategy {
 func (r *RustStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return r.ExtractFromPatterns(content, tag, r.patterns)
 }
+
+var rustBlocks = blockSyntax{style: braceBlocks, quotes: `"`, semicolons: true, prefixes: []string{"#["}}
+
+func (r *RustStrategy) DeclarationBlock(content []byte, line int) (int, int, bool) {
+	return findDeclaration(content, line, r.patterns, rustBlocks)
+}
@@ -14,6 +14,7 @@ func NewShellStrategy() *ShellStrategy {
 			patterns: []*regexp.Regexp{
 				regexp.MustCompile(`#[^\n]*`),
 			},
+			comment: CommentSyntax{Line: "#"},
 		},
 	}
 }
@@ -12,11 +12,18 @@ import (
 // Scope (an optional "#name" suffix, e.g. `@doc docs/X.md #settings.general`)
 // narrows ownership: a scoped annotation owns the code region from its line to
 // the next annotation, so a change elsewhere in a central file doesn't flag it.
-// An empty Scope means whole-file ownership (the original behavior).
+// An empty Scope means whole-file ownership (the original behavior). End is
+// set only under declaration ownership, when the annotation sits directly above
+// a declaration a BlockFinder could measure; the region then stops there.
 type DocAnnotation struct {
 	Path  string
 	Scope string
 	Line  int // 1-based line where the annotation appears
+	End   int // last owned line for a declaration-scoped annotation; 0 if unset
+
+	// Declared marks a region a mapping-file link declares rather than a
+	// comment in the file: it owns exactly Line..End and bounds no other region.
+	Declared bool
 }
 
 type Strategy interface {
@@ -31,6 +38,7 @@ type BaseStrategy struct {
 	name       string
 	extensions []string
 	patterns   []*regexp.Regexp
+	comment    CommentSyntax
 }
 
 func (b *BaseStrategy) Name() string {
@@ -45,6 +53,12 @@ func (b *BaseStrategy) CommentPatterns() []*regexp.Regexp {
 	return b.patterns
 }
 
+// base exposes the embedded BaseStrategy so the config layer can extend a
+// built-in strategy's extensions and patterns in place.
+func (b *BaseStrategy) base() *BaseStrategy {
+	return b
+}
+
 // ExtractDetailed returns every @doc annotation with its scope and line, using
 // the strategy's own comment patterns. All strategies embed BaseStrategy, so
 // they inherit this for free.
@@ -0,0 +1,47 @@
+package language
+
+// @doc CLAUDE.md
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SymbolDeclarer is an optional Strategy extension that lists the public
+// names a file declares, so docs that mention `Name` can be traced back to
+// the file defining it. It is a lightweight regex pass, not a parser: it may
+// miss unusual declarations, and names shorter than minSymbolLength are left
+// out because they'd match too many unrelated code spans.
+type SymbolDeclarer interface {
+	DeclaredSymbols(content []byte) []string
+}
+
+const minSymbolLength = 4
+
+// matchSymbols returns the first submatch of every re match in content,
+// deduplicated, in order.
+func matchSymbols(re *regexp.Regexp, content []byte) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range re.FindAllSubmatch(content, -1) {
+		name := string(m[1])
+		if len(name) >= minSymbolLength && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// DeclarationLine returns the 1-based line declaring name, by running the
+// declarer over content one line at a time.
+func DeclarationLine(declarer SymbolDeclarer, content []byte, name string) (int, bool) {
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, declared := range declarer.DeclaredSymbols([]byte(line)) {
+			if declared == name {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
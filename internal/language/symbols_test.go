@@ -0,0 +1,59 @@
+package language
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeclaredSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		content  string
+		want     []string
+	}{
+		{
+			name:     "go exported only",
+			strategy: NewGoStrategy(),
+			content:  "package a\n\nfunc HandleRequest() {}\nfunc (s *Server) ServeHTTP() {}\nfunc helper() {}\ntype Invoice struct{}\nconst MaxSize = 1\nvar ID = 2\n",
+			want:     []string{"HandleRequest", "ServeHTTP", "Invoice", "MaxSize"},
+		},
+		{
+			name:     "python top-level defs and classes",
+			strategy: NewPythonStrategy(),
+			content:  "class Billing:\n    def method(self): pass\n\nasync def fetch_all():\n    pass\n\ndef _private():\n    pass\n",
+			want:     []string{"Billing", "fetch_all"},
+		},
+		{
+			name:     "javascript exports",
+			strategy: NewJavaScriptStrategy(),
+			content:  "export default class Router {}\nexport async function loadUser() {}\nexport interface Props {}\nfunction internal() {}\nexport const $store = 1\n",
+			want:     []string{"Router", "loadUser", "Props", "$store"},
+		},
+		{
+			name:     "java types",
+			strategy: NewJavaStrategy(),
+			content:  "public class UserService {\n  private enum State {}\n}\nrecord Point(int x) {}\n",
+			want:     []string{"UserService", "State", "Point"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.strategy.(SymbolDeclarer).DeclaredSymbols([]byte(tt.content))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeclaredSymbols() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclarationLine(t *testing.T) {
+	content := []byte("package a\n\n// Total sums lines.\nfunc InvoiceTotal() int {\n\treturn 0\n}\n")
+	if line, ok := DeclarationLine(NewGoStrategy(), content, "InvoiceTotal"); !ok || line != 4 {
+		t.Errorf("DeclarationLine() = %d, %v, want 4, true", line, ok)
+	}
+	if _, ok := DeclarationLine(NewGoStrategy(), content, "Missing"); ok {
+		t.Error("DeclarationLine() should not find an undeclared name")
+	}
+}
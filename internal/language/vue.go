@@ -16,6 +16,7 @@ func NewVueStrategy() *VueStrategy {
 				regexp.MustCompile(`//[^\n]*`),
 				regexp.MustCompile(`(?s)/\*.*?\*/`),
 			},
+			comment: CommentSyntax{BlockStart: "<!--", BlockEnd: "-->"},
 		},
 	}
 }
@@ -23,3 +24,17 @@ func NewVueStrategy() *VueStrategy {
 func (v *VueStrategy) ExtractAnnotations(content []byte, tag string) []string {
 	return v.ExtractFromPatterns(content, tag, v.patterns)
 }
+
+// PlaceAnnotation puts the annotation at the top of the <script> block as a
+// script comment, or at the top of the file as an HTML comment when the
+// component has no script.
+func (v *VueStrategy) PlaceAnnotation(lines []string, annotation string) (Insertion, bool) {
+	for i, line := range lines {
+		if vueScriptOpen.MatchString(line) {
+			return Insertion{At: i + 1, Lines: []string{"// " + annotation}}, true
+		}
+	}
+	return v.BaseStrategy.PlaceAnnotation(lines, annotation)
+}
+
+var vueScriptOpen = regexp.MustCompile(`^\s*<script\b[^>]*>\s*$`)
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	frame := func(body string, extra ...string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n%s\r\n%s", len(body), strings.Join(extra, ""), body)
+	}
+	in := frame(`{"jsonrpc":"2.0","id":1,"method":"hover","params":{}}`, "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n") +
+		frame(`{"jsonrpc":"2.0","method":"initialized"}`)
+	var out bytes.Buffer
+	c := NewConn(strings.NewReader(in), &out)
+
+	req, err := c.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if req.Method != "hover" || string(req.ID) != "1" || req.IsNotification() {
+		t.Errorf("first message = %+v", req)
+	}
+	req, err = c.Read()
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if req.Method != "initialized" || !req.IsNotification() {
+		t.Errorf("second message = %+v", req)
+	}
+	if _, err := c.Read(); err != io.EOF {
+		t.Errorf("Read() at end = %v, want io.EOF", err)
+	}
+
+	c.Reply(json.RawMessage("1"), map[string]int{"n": 1}, nil)
+	c.Reply(json.RawMessage("2"), nil, &Error{Code: CodeMethodNotFound, Message: "nope"})
+	c.Notify("window/logMessage", map[string]string{"message": "hi"})
+	want := frame(`{"jsonrpc":"2.0","id":1,"result":{"n":1}}`) +
+		frame(`{"jsonrpc":"2.0","id":2,"result":null,"error":{"code":-32601,"message":"nope"}}`) +
+		frame(`{"jsonrpc":"2.0","method":"window/logMessage","params":{"message":"hi"}}`)
+	if out.String() != want {
+		t.Errorf("written:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	line := "// é 😀 @doc docs/A.md"
+	at := strings.Index(line, "@doc")
+	col := Column(line, at)
+	if col != 8 { // 😀 is two UTF-16 units, é one
+		t.Errorf("Column() = %d, want 8", col)
+	}
+	if got := Offset(line, col); got != at {
+		t.Errorf("Offset(Column()) = %d, want %d", got, at)
+	}
+	if got := Offset(line, 1000); got != len(line) {
+		t.Errorf("Offset past the end = %d, want %d", got, len(line))
+	}
+}
+
+func TestURIs(t *testing.T) {
+	uri := PathToURI("/tmp/my project/a.go")
+	if uri != "file:///tmp/my%20project/a.go" {
+		t.Errorf("PathToURI() = %q", uri)
+	}
+	if got := URIToPath(uri); got != "/tmp/my project/a.go" {
+		t.Errorf("URIToPath() = %q", got)
+	}
+	if got := URIToPath("untitled:Untitled-1"); got != "" {
+		t.Errorf("URIToPath(untitled) = %q, want empty", got)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+
+	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 type HumanFormatter struct {
@@ -49,6 +51,14 @@ func (h *HumanFormatter) formatFull(report *Report) ([]byte, error) {
 		buf.WriteString("No stale docs found. All documentation is up to date.\n\n")
 	}
 
+	if len(report.BrokenAnnotations) > 0 {
+		buf.WriteString("BROKEN ANNOTATIONS (doc path does not exist):\n")
+		for _, b := range report.BrokenAnnotations {
+			writeBroken(&buf, b, h.tag())
+		}
+		buf.WriteString("\n")
+	}
+
 	buf.WriteString("By Documentation File:\n")
 	docs := sortedKeys(report.FilesByDoc)
 	for _, doc := range docs {
@@ -94,7 +104,7 @@ func (h *HumanFormatter) formatFull(report *Report) ([]byte, error) {
 
 		byDoc := make(map[string][]string)
 		for _, ref := range report.UndocumentedRefs {
-			byDoc[ref.DocPath] = append(byDoc[ref.DocPath], ref.SourceFile)
+			byDoc[ref.DocPath] = append(byDoc[ref.DocPath], refLine(ref, tag))
 		}
 
 		docs := sortedKeys(byDoc)
@@ -102,12 +112,18 @@ func (h *HumanFormatter) formatFull(report *Report) ([]byte, error) {
 			files := byDoc[doc]
 			fmt.Fprintf(&buf, "  %s references:\n", doc)
 			for _, f := range files {
-				fmt.Fprintf(&buf, "    - %s (add: // %s %s)\n", f, tag, doc)
+				fmt.Fprintf(&buf, "    - %s\n", f)
 			}
 		}
 		buf.WriteString("\n")
 	}
 
+	if len(report.DeadRefs) > 0 {
+		buf.WriteString("DEAD REFERENCES (docs mention files or symbols removed since their baseline):\n")
+		writeDeadRefs(&buf, report.DeadRefs)
+		buf.WriteString("\n")
+	}
+
 	if len(report.DirectoryCoverage) > 0 {
 		buf.WriteString("Coverage by Directory:\n")
 		for _, dc := range report.DirectoryCoverage {
@@ -126,6 +142,12 @@ func (h *HumanFormatter) formatFull(report *Report) ([]byte, error) {
 	if report.Summary.UndocumentedRefs > 0 {
 		fmt.Fprintf(&buf, "  Undocumented refs: %d\n", report.Summary.UndocumentedRefs)
 	}
+	if report.Summary.BrokenAnnotations > 0 {
+		fmt.Fprintf(&buf, "  Broken annotations: %d\n", report.Summary.BrokenAnnotations)
+	}
+	if report.Summary.DeadRefs > 0 {
+		fmt.Fprintf(&buf, "  Dead references: %d\n", report.Summary.DeadRefs)
+	}
 
 	return buf.Bytes(), nil
 }
@@ -192,7 +214,7 @@ func (h *HumanFormatter) formatUndocumentedOnly(report *Report) ([]byte, error)
 
 	byDoc := make(map[string][]string)
 	for _, ref := range report.UndocumentedRefs {
-		byDoc[ref.DocPath] = append(byDoc[ref.DocPath], ref.SourceFile)
+		byDoc[ref.DocPath] = append(byDoc[ref.DocPath], refLine(ref, tag))
 	}
 
 	docs := sortedKeys(byDoc)
@@ -200,7 +222,7 @@ func (h *HumanFormatter) formatUndocumentedOnly(report *Report) ([]byte, error)
 		files := byDoc[doc]
 		fmt.Fprintf(&buf, "  %s references:\n", doc)
 		for _, f := range files {
-			fmt.Fprintf(&buf, "    - %s (add: // %s %s)\n", f, tag, doc)
+			fmt.Fprintf(&buf, "    - %s\n", f)
 		}
 		buf.WriteString("\n")
 	}
@@ -208,6 +230,21 @@ func (h *HumanFormatter) formatUndocumentedOnly(report *Report) ([]byte, error)
 	return buf.Bytes(), nil
 }
 
+func (h *HumanFormatter) tag() string {
+	if h.Tag == "" {
+		return "@doc"
+	}
+	return h.Tag
+}
+
+// writeBroken prints one broken annotation, with its fix when one is known.
+func writeBroken(buf *bytes.Buffer, b scanner.BrokenAnnotation, tag string) {
+	fmt.Fprintf(buf, "  x %s:%d %s %s (%s)\n", b.SourceFile, b.Line, tag, b.DocPath, b.Reason)
+	if b.Suggestion != "" {
+		fmt.Fprintf(buf, "    Did you mean %s?\n", b.Suggestion)
+	}
+}
+
 func sortedKeys[V any](m map[string]V) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -216,3 +253,46 @@ func sortedKeys[V any](m map[string]V) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+// refLabel names the referenced file, and the symbol when the doc mentioned
+// that rather than the path, or the lines a line-anchored link points at.
+func refLabel(ref scanner.UndocumentedRef) string {
+	if ref.Symbol != "" {
+		return fmt.Sprintf("%s (via `%s`)", ref.SourceFile, ref.Symbol)
+	}
+	if ref.Start > 0 {
+		return fmt.Sprintf("%s lines %d-%d", ref.SourceFile, ref.Start, ref.End)
+	}
+	return ref.SourceFile
+}
+
+// refLine is one missing back-link with its fix: a scoped annotation at the
+// first line when the doc links to a line range, a whole-file one otherwise.
+func refLine(ref scanner.UndocumentedRef, tag string) string {
+	if ref.Start > 0 {
+		return fmt.Sprintf("%s (add at line %d: // %s %s #<scope>)", refLabel(ref), ref.Start, tag, ref.DocPath)
+	}
+	return fmt.Sprintf("%s (add: // %s %s)", refLabel(ref), tag, ref.DocPath)
+}
+
+// writeDeadRefs lists dead references by doc, each with its doc line.
+func writeDeadRefs(buf *bytes.Buffer, refs []DeadRef) {
+	byDoc := make(map[string][]DeadRef)
+	for _, r := range refs {
+		byDoc[r.DocPath] = append(byDoc[r.DocPath], r)
+	}
+	for _, doc := range sortedKeys(byDoc) {
+		fmt.Fprintf(buf, "  %s:\n", doc)
+		for _, r := range byDoc[doc] {
+			fmt.Fprintf(buf, "    line %d: %s\n", r.Line, DeadRefText(r))
+		}
+	}
+}
+
+// DeadRefText describes what a dead reference named and where it went.
+func DeadRefText(r DeadRef) string {
+	if r.Kind == DeadRefSymbol {
+		return fmt.Sprintf("`%s` is no longer declared (was in %s at %s)", r.Ref, r.DeclaredIn, r.Baseline)
+	}
+	return fmt.Sprintf("%s no longer exists (present at %s)", r.Ref, r.Baseline)
+}
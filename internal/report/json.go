@@ -13,6 +13,8 @@ type jsonOutput struct {
 	FilesByDoc        map[string][]string         `json:"files_by_doc"`
 	OrphanedFiles     []string                    `json:"orphaned_files"`
 	UndocumentedRefs  []scanner.UndocumentedRef   `json:"undocumented_refs"`
+	BrokenAnnotations []scanner.BrokenAnnotation  `json:"broken_annotations"`
+	DeadRefs          []DeadRef                   `json:"dead_refs"`
 	DirectoryCoverage []DirectoryCoverage         `json:"directory_coverage,omitempty"`
 	Summary           Summary                     `json:"summary"`
 }
@@ -23,6 +25,8 @@ func (j *JSONFormatter) Format(report *Report) ([]byte, error) {
 		FilesByDoc:        report.FilesByDoc,
 		OrphanedFiles:     report.OrphanedFiles,
 		UndocumentedRefs:  report.UndocumentedRefs,
+		BrokenAnnotations: report.BrokenAnnotations,
+		DeadRefs:          report.DeadRefs,
 		DirectoryCoverage: report.DirectoryCoverage,
 		Summary:           report.Summary,
 	}
@@ -17,6 +17,22 @@ type StaleDoc struct {
 	ChangedFiles   []string
 }
 
+// DeadRef is a file or symbol a doc mentions that existed at the doc's
+// baseline but is gone now: the doc still describes something removed.
+type DeadRef struct {
+	DocPath    string `json:"doc_path"`
+	Line       int    `json:"line"`
+	Kind       string `json:"kind"`                  // DeadRefFile or DeadRefSymbol
+	Ref        string `json:"ref"`                   // the path or symbol name as the doc gives it
+	DeclaredIn string `json:"declared_in,omitempty"` // symbols: the file declaring it at the baseline
+	Baseline   string `json:"baseline"`
+}
+
+const (
+	DeadRefFile   = "file"
+	DeadRefSymbol = "symbol"
+)
+
 type DirectoryCoverage struct {
 	Path            string
 	TotalFiles      int
@@ -29,18 +45,22 @@ type Report struct {
 	FilesByDoc        map[string][]string
 	OrphanedFiles     []string
 	UndocumentedRefs  []scanner.UndocumentedRef
+	BrokenAnnotations []scanner.BrokenAnnotation
+	DeadRefs          []DeadRef
 	DirectoryCoverage []DirectoryCoverage
 	Summary           Summary
 }
 
 type Summary struct {
-	TotalDocs        int
-	TotalFiles       int
-	DocumentedFiles  int
-	OrphanedFiles    int
-	StaleDocs        int
-	UndocumentedRefs int
-	CoveragePercent  float64
+	TotalDocs         int
+	TotalFiles        int
+	DocumentedFiles   int
+	OrphanedFiles     int
+	StaleDocs         int
+	UndocumentedRefs  int
+	BrokenAnnotations int
+	DeadRefs          int
+	CoveragePercent   float64
 }
 
 type Formatter interface {
@@ -49,21 +69,25 @@ type Formatter interface {
 
 func NewReport() *Report {
 	return &Report{
-		StaleDocs:        make(map[string]*StaleDoc),
-		FilesByDoc:       make(map[string][]string),
-		OrphanedFiles:    make([]string, 0),
-		UndocumentedRefs: make([]scanner.UndocumentedRef, 0),
+		StaleDocs:         make(map[string]*StaleDoc),
+		FilesByDoc:        make(map[string][]string),
+		OrphanedFiles:     make([]string, 0),
+		UndocumentedRefs:  make([]scanner.UndocumentedRef, 0),
+		BrokenAnnotations: make([]scanner.BrokenAnnotation, 0),
+		DeadRefs:          make([]DeadRef, 0),
 	}
 }
 
 func (r *Report) CalculateSummary(totalFiles, documentedFiles int) {
 	r.Summary = Summary{
-		TotalDocs:        len(r.FilesByDoc),
-		TotalFiles:       totalFiles,
-		DocumentedFiles:  documentedFiles,
-		OrphanedFiles:    len(r.OrphanedFiles),
-		StaleDocs:        len(r.StaleDocs),
-		UndocumentedRefs: len(r.UndocumentedRefs),
+		TotalDocs:         len(r.FilesByDoc),
+		TotalFiles:        totalFiles,
+		DocumentedFiles:   documentedFiles,
+		OrphanedFiles:     len(r.OrphanedFiles),
+		StaleDocs:         len(r.StaleDocs),
+		UndocumentedRefs:  len(r.UndocumentedRefs),
+		BrokenAnnotations: len(r.BrokenAnnotations),
+		DeadRefs:          len(r.DeadRefs),
 	}
 
 	if r.Summary.TotalFiles > 0 {
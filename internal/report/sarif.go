@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+
+	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 type SARIFFormatter struct {
@@ -60,6 +62,11 @@ type sarifLocation struct {
 
 type sarifPhysicalLocation struct {
 	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
 }
 
 type sarifArtifactLocation struct {
@@ -96,6 +103,20 @@ func (s *SARIFFormatter) Format(report *Report) ([]byte, error) {
 							FullDescription:  sarifDescription{Text: "A documentation file references a source file that does not have an @doc annotation pointing back to the documentation."},
 							DefaultConfig:    sarifConfig{Level: "note"},
 						},
+						{
+							ID:               "broken-annotation",
+							Name:             "Broken Annotation",
+							ShortDescription: sarifDescription{Text: "Annotation links to a doc that does not exist"},
+							FullDescription:  sarifDescription{Text: "A @doc annotation names a path that is missing or outside the repository, so the linked doc can never be checked for staleness."},
+							DefaultConfig:    sarifConfig{Level: "error"},
+						},
+						{
+							ID:               "dead-reference",
+							Name:             "Dead Reference",
+							ShortDescription: sarifDescription{Text: "Doc mentions a file or symbol that was removed"},
+							FullDescription:  sarifDescription{Text: "A documentation file mentions a source file or declared symbol that existed when the doc was last reviewed but no longer does."},
+							DefaultConfig:    sarifConfig{Level: "warning"},
+						},
 					},
 				},
 			},
@@ -132,8 +153,7 @@ func (s *SARIFFormatter) Format(report *Report) ([]byte, error) {
 		result := sarifResult{
 			RuleID: "undocumented-ref",
 			Message: sarifDescription{
-				Text: fmt.Sprintf("Documentation '%s' references '%s' but this file has no @doc annotation pointing back.",
-					ref.DocPath, ref.SourceFile),
+				Text: undocumentedRefText(ref),
 			},
 			Locations: []sarifLocation{{
 				PhysicalLocation: sarifPhysicalLocation{
@@ -146,5 +166,63 @@ func (s *SARIFFormatter) Format(report *Report) ([]byte, error) {
 		sarif.Runs[0].Results = append(sarif.Runs[0].Results, result)
 	}
 
+	for _, b := range report.BrokenAnnotations {
+		text := fmt.Sprintf("Annotation links to '%s', which is %s.", b.DocPath, brokenReasonText(b.Reason))
+		if b.Suggestion != "" {
+			text += fmt.Sprintf(" Did you mean '%s'?", b.Suggestion)
+		}
+		result := sarifResult{
+			RuleID:  "broken-annotation",
+			Message: sarifDescription{Text: text},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{
+						URI: b.SourceFile,
+					},
+					Region: &sarifRegion{StartLine: b.Line},
+				},
+			}},
+		}
+		sarif.Runs[0].Results = append(sarif.Runs[0].Results, result)
+	}
+
+	for _, r := range report.DeadRefs {
+		result := sarifResult{
+			RuleID: "dead-reference",
+			Message: sarifDescription{
+				Text: fmt.Sprintf("Documentation '%s' mentions a removed reference: %s.", r.DocPath, DeadRefText(r)),
+			},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{
+						URI: r.DocPath,
+					},
+					Region: &sarifRegion{StartLine: r.Line},
+				},
+			}},
+		}
+		sarif.Runs[0].Results = append(sarif.Runs[0].Results, result)
+	}
+
 	return json.MarshalIndent(sarif, "", "  ")
 }
+
+func brokenReasonText(reason string) string {
+	if reason == scanner.BrokenOutsideRepo {
+		return "outside the repository"
+	}
+	return "missing"
+}
+
+func undocumentedRefText(ref scanner.UndocumentedRef) string {
+	if ref.Symbol != "" {
+		return fmt.Sprintf("Documentation '%s' references '%s', declared in '%s', but this file has no @doc annotation pointing back.",
+			ref.DocPath, ref.Symbol, ref.SourceFile)
+	}
+	if ref.Start > 0 {
+		return fmt.Sprintf("Documentation '%s' links to lines %d-%d of '%s' but this file has no @doc annotation pointing back.",
+			ref.DocPath, ref.Start, ref.End, ref.SourceFile)
+	}
+	return fmt.Sprintf("Documentation '%s' references '%s' but this file has no @doc annotation pointing back.",
+		ref.DocPath, ref.SourceFile)
+}
@@ -2,7 +2,10 @@ package report
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/StevenBock/docdiff/internal/scanner"
 )
 
 func TestSARIFFormatter_Format(t *testing.T) {
@@ -62,8 +65,8 @@ func TestSARIFFormatter_Format(t *testing.T) {
 		}
 
 		rules := driver["rules"].([]interface{})
-		if len(rules) != 2 {
-			t.Fatalf("Should have 2 rules, got %d", len(rules))
+		if len(rules) != 4 {
+			t.Fatalf("Should have 4 rules, got %d", len(rules))
 		}
 
 		rule := rules[0].(map[string]interface{})
@@ -75,6 +78,11 @@ func TestSARIFFormatter_Format(t *testing.T) {
 		if rule2["id"] != "undocumented-ref" {
 			t.Errorf("rule[1] id = %v, want undocumented-ref", rule2["id"])
 		}
+
+		rule3 := rules[2].(map[string]interface{})
+		if rule3["id"] != "broken-annotation" {
+			t.Errorf("rule[2] id = %v, want broken-annotation", rule3["id"])
+		}
 	})
 
 	t.Run("has results for stale docs", func(t *testing.T) {
@@ -197,3 +205,81 @@ func TestSARIFFormatter_Format_MultipleStale(t *testing.T) {
 		t.Errorf("Should have 2 results for 2 stale docs, got %d", len(results))
 	}
 }
+
+func TestSARIFFormatter_Format_BrokenAnnotation(t *testing.T) {
+	r := &Report{
+		BrokenAnnotations: []scanner.BrokenAnnotation{{
+			SourceFile: "src/handler.go",
+			Line:       7,
+			DocPath:    "docs/APi.md",
+			Reason:     scanner.BrokenMissing,
+			Suggestion: "docs/API.md",
+		}},
+	}
+
+	output, err := (&SARIFFormatter{}).Format(r)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var sarif struct {
+		Runs []struct {
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(output, &sarif); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	results := sarif.Runs[0].Results
+	if len(results) != 1 {
+		t.Fatalf("Should have 1 result, got %d", len(results))
+	}
+	res := results[0]
+	if res.RuleID != "broken-annotation" {
+		t.Errorf("ruleId = %q, want broken-annotation", res.RuleID)
+	}
+	loc := res.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "src/handler.go" || loc.Region.StartLine != 7 {
+		t.Errorf("location = %+v, want src/handler.go line 7", loc)
+	}
+	if !strings.Contains(res.Message.Text, "Did you mean 'docs/API.md'?") {
+		t.Errorf("message should carry the suggestion, got %q", res.Message.Text)
+	}
+}
+
+func TestSARIFFormatter_Format_DeadRef(t *testing.T) {
+	r := &Report{
+		DeadRefs: []DeadRef{{DocPath: "docs/API.md", Line: 12, Kind: DeadRefSymbol, Ref: "InvoiceTotal", DeclaredIn: "src/billing.go", Baseline: "abc1234"}},
+	}
+
+	output, err := (&SARIFFormatter{}).Format(r)
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	for _, want := range []string{
+		`"ruleId": "dead-reference"`,
+		`"uri": "docs/API.md"`,
+		`"startLine": 12`,
+		"`InvoiceTotal` is no longer declared (was in src/billing.go at abc1234)",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("SARIF output missing %s:\n%s", want, output)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package scanner
 
-import "github.com/StevenBock/docdiff/internal/language"
+import (
+	"path"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/language"
+)
 
 type Annotation struct {
 	FilePath string
@@ -13,27 +19,48 @@ type UndocumentedRef struct {
 	DocPath    string `json:"doc_path"`
 	SourceFile string `json:"source_file"`
 	Line       int    `json:"line"`
+	Symbol     string `json:"symbol,omitempty"`     // set when the doc names a symbol SourceFile declares, not its path
+	Start      int    `json:"start_line,omitempty"` // lines of SourceFile a #L10-L20 link points at, so the back-link can be scoped
+	End        int    `json:"end_line,omitempty"`
 }
 
 type Result struct {
-	Annotations      map[string]*Annotation
-	FilesByDoc       map[string][]string
-	AllFiles         []string
-	Errors           []error
-	UndocumentedRefs []UndocumentedRef
+	Annotations       map[string]*Annotation
+	FilesByDoc        map[string][]string
+	AllFiles          []string
+	Errors            []error
+	UndocumentedRefs  []UndocumentedRef
+	BrokenAnnotations []BrokenAnnotation
+	DocFiles          []string              // every file in a doc format (docformat), excludes applied
+	Docs              []string              // the docs checked for references to source files, wherever they live
+	Symbols           *docparse.SymbolIndex // declared names per file; nil unless the scan indexed them
+	Links             []Link                // doc-to-file links declared outside the files, also in FilesByDoc
+
+	aliases   map[string]string      // config aliases, applied by AddAnnotation
+	ownership map[string]*Annotation // Ownership, built on first use
 }
 
 func NewResult() *Result {
 	return &Result{
-		Annotations:      make(map[string]*Annotation),
-		FilesByDoc:       make(map[string][]string),
-		AllFiles:         make([]string, 0),
-		Errors:           make([]error, 0),
-		UndocumentedRefs: make([]UndocumentedRef, 0),
+		Annotations:       make(map[string]*Annotation),
+		FilesByDoc:        make(map[string][]string),
+		AllFiles:          make([]string, 0),
+		Errors:            make([]error, 0),
+		UndocumentedRefs:  make([]UndocumentedRef, 0),
+		BrokenAnnotations: make([]BrokenAnnotation, 0),
 	}
 }
 
+// AddAnnotation records filePath's annotations, with every doc target resolved
+// to its repo-relative key (see ResolveDocPath) and then through the aliases.
 func (r *Result) AddAnnotation(filePath string, details []language.DocAnnotation, lang string) {
+	resolved := make([]language.DocAnnotation, len(details))
+	for i, d := range details {
+		d.Path = ResolveAlias(r.aliases, ResolveDocPath(filePath, d.Path))
+		resolved[i] = d
+	}
+	details = resolved
+
 	docPaths := make([]string, 0, len(details))
 	seen := make(map[string]bool)
 	for _, d := range details {
@@ -63,20 +90,94 @@ func (r *Result) AddError(err error) {
 	r.Errors = append(r.Errors, err)
 }
 
+// OrphanedFiles lists the files no doc links to, by annotation or declared
+// link.
 func (r *Result) OrphanedFiles() []string {
+	linked := make(map[string]bool, len(r.Links))
+	for _, l := range r.Links {
+		linked[l.File] = true
+	}
 	orphaned := make([]string, 0)
 	for _, f := range r.AllFiles {
-		if _, ok := r.Annotations[f]; !ok {
+		if _, ok := r.Annotations[f]; !ok && !linked[f] {
 			orphaned = append(orphaned, f)
 		}
 	}
 	return orphaned
 }
 
-func (r *Result) AddUndocumentedRef(docPath, sourceFile string, line int) {
-	r.UndocumentedRefs = append(r.UndocumentedRefs, UndocumentedRef{
-		DocPath:    docPath,
-		SourceFile: sourceFile,
-		Line:       line,
-	})
+// AddUndocumentedRef records a doc reference to a file that doesn't link back.
+func (r *Result) AddUndocumentedRef(ref UndocumentedRef) {
+	r.UndocumentedRefs = append(r.UndocumentedRefs, ref)
+}
+
+// ResolveDocPath turns a doc target as written in sourceFile (repo-relative,
+// slash-separated) into the repo-relative key it names:
+//
+//   - backslashes are separators, so docs\API.md is docs/API.md
+//   - /docs/API.md is anchored at the repository root
+//   - ./API.md and ../docs/API.md are relative to sourceFile's directory
+//   - any other path is relative to the repository root
+//
+// "." and ".." segments are cleaned away; a path that climbs above the root
+// keeps its leading "../" so it is reported as outside the repository. A
+// section anchor is carried over unchanged, and URLs are left alone.
+func ResolveDocPath(sourceFile, target string) string {
+	if strings.Contains(target, "://") {
+		return target
+	}
+	p, anchor := docparse.SplitAnchor(target)
+	p = strings.ReplaceAll(p, "\\", "/")
+
+	switch {
+	case strings.HasPrefix(p, "/"):
+		p = strings.TrimLeft(p, "/")
+	case p == "." || p == ".." || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../"):
+		p = path.Join(path.Dir(sourceFile), p)
+	}
+	if p != "" {
+		p = path.Clean(p)
+	}
+
+	if anchor != "" {
+		return p + "#" + anchor
+	}
+	return p
+}
+
+// ResolveAlias maps a resolved doc target through the config's aliases, which
+// let annotations keep naming a doc's old path while a rename rolls out. A key
+// matches the doc path exactly or, as a directory, any path under it; the
+// longest match wins and the anchor is kept.
+func ResolveAlias(aliases map[string]string, target string) string {
+	if len(aliases) == 0 || strings.Contains(target, "://") {
+		return target
+	}
+	p, anchor := docparse.SplitAnchor(target)
+
+	best, to := "", ""
+	for from, dest := range aliases {
+		from = cleanAliasPath(from)
+		if from == "" || len(from) <= len(best) {
+			continue
+		}
+		if p == from || strings.HasPrefix(p, from+"/") {
+			best, to = from, cleanAliasPath(dest)+p[len(from):]
+		}
+	}
+	if best == "" {
+		return target
+	}
+	if anchor != "" {
+		return to + "#" + anchor
+	}
+	return to
+}
+
+func cleanAliasPath(p string) string {
+	p = strings.Trim(strings.ReplaceAll(p, "\\", "/"), "/")
+	if p == "" {
+		return ""
+	}
+	return path.Clean(p)
 }
@@ -0,0 +1,143 @@
+package scanner
+
+// @doc README.md
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/StevenBock/docdiff/internal/docparse"
+)
+
+// Reasons an annotation is broken.
+const (
+	BrokenMissing     = "missing"            // the doc file does not exist
+	BrokenOutsideRepo = "outside repository" // the path leaves the repository root
+)
+
+// BrokenAnnotation is an annotation whose doc can never be reviewed: the path
+// names no file in the repository. Without this finding such a typo creates a
+// phantom doc that has no commits and so is never stale.
+type BrokenAnnotation struct {
+	SourceFile string `json:"source_file"`
+	Line       int    `json:"line"`
+	DocPath    string `json:"doc_path"`
+	Reason     string `json:"reason"`
+	Suggestion string `json:"suggestion,omitempty"` // closest existing doc, with the anchor kept
+}
+
+// findBrokenAnnotations checks every annotated doc path against the tree,
+// suggesting the closest of the scan's docs.
+func findBrokenAnnotations(rootDir string, result *Result) {
+	files := make([]string, 0, len(result.Annotations))
+	for f := range result.Annotations {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	reasons := make(map[string]string) // doc path -> reason, "" when it exists
+	for _, f := range files {
+		for _, d := range result.Annotations[f].Details {
+			docPath, anchor := docparse.SplitAnchor(d.Path)
+			reason, seen := reasons[docPath]
+			if !seen {
+				reason = BrokenReason(rootDir, docPath)
+				reasons[docPath] = reason
+			}
+			if reason == "" {
+				continue
+			}
+			b := BrokenAnnotation{SourceFile: f, Line: d.Line, DocPath: d.Path, Reason: reason}
+			if s := SuggestDoc(docPath, result.DocFiles); s != "" {
+				b.Suggestion = s
+				if anchor != "" {
+					b.Suggestion += "#" + anchor
+				}
+			}
+			result.BrokenAnnotations = append(result.BrokenAnnotations, b)
+		}
+	}
+}
+
+// BrokenReason checks a doc path already resolved by ResolveDocPath, returning
+// BrokenMissing, BrokenOutsideRepo, or "" when the doc exists. Letter case
+// must match the tree exactly, even on case-insensitive filesystems.
+func BrokenReason(rootDir, docPath string) string {
+	if docPath == ".." || strings.HasPrefix(docPath, "../") || driveLetter.MatchString(docPath) || strings.Contains(docPath, "://") {
+		return BrokenOutsideRepo
+	}
+	if !existsExactCase(rootDir, docPath) {
+		return BrokenMissing
+	}
+	return ""
+}
+
+// existsExactCase reports whether docPath names a regular file under rootDir
+// with every element spelled in the same case. os.Stat alone would accept
+// docs/api.md for docs/API.md on case-insensitive filesystems (macOS,
+// Windows), hiding a link that breaks on Linux and in git.
+func existsExactCase(rootDir, docPath string) bool {
+	dir := rootDir
+	for _, name := range strings.Split(docPath, "/") {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return false
+		}
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Name() >= name })
+		if i == len(entries) || entries[i].Name() != name {
+			return false
+		}
+		dir = filepath.Join(dir, name)
+	}
+	info, err := os.Stat(dir)
+	return err == nil && !info.IsDir()
+}
+
+var driveLetter = regexp.MustCompile(`^[A-Za-z]:/`)
+
+// SuggestDoc returns the candidate closest to target by edit distance
+// (ignoring case), or "" when none is close. A candidate with the same file
+// name counts as close wherever it lives.
+func SuggestDoc(target string, candidates []string) string {
+	lowTarget := strings.ToLower(target)
+	lowBase := path.Base(lowTarget)
+	limit := max(2, len(lowTarget)/4)
+
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		low := strings.ToLower(c)
+		dist := editDistance(lowTarget, low)
+		if dist > limit && path.Base(low) != lowBase {
+			continue
+		}
+		if bestDist < 0 || dist < bestDist || (dist == bestDist && c < best) {
+			best, bestDist = c, dist
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
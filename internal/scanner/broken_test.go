@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/language"
+)
+
+func TestBrokenAnnotations(t *testing.T) {
+	tmpDir := setupTestDir(t, map[string]string{
+		"docs/API.md":     "# API\n",
+		"docs/guide.md":   "# Guide\n",
+		"README.md":       "# Readme\n",
+		"src/ok.go":       "package main\n// @doc docs/API.md\nfunc OK() {}\n",
+		"src/typo.go":     "package main\n\n// @doc docs/APi.md#auth\nfunc Typo() {}\n",
+		"src/moved.go":    "package main\n// @doc guide.md\nfunc Moved() {}\n",
+		"src/escape.go":   "package main\n// @doc ../../other/API.md\nfunc Escape() {}\n",
+		"src/nothing.go":  "package main\n// @doc docs/zzzzzzzz.txt\nfunc Nothing() {}\n",
+		"vendor/x/doc.md": "# vendored\n",
+	})
+
+	result, err := New(config.DefaultConfig(), language.DefaultRegistry()).Scan(tmpDir)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	want := []BrokenAnnotation{
+		{SourceFile: "src/escape.go", Line: 2, DocPath: "../other/API.md", Reason: BrokenOutsideRepo, Suggestion: "docs/API.md"},
+		{SourceFile: "src/moved.go", Line: 2, DocPath: "guide.md", Reason: BrokenMissing, Suggestion: "docs/guide.md"},
+		{SourceFile: "src/nothing.go", Line: 2, DocPath: "docs/zzzzzzzz.txt", Reason: BrokenMissing},
+		{SourceFile: "src/typo.go", Line: 3, DocPath: "docs/APi.md#auth", Reason: BrokenMissing, Suggestion: "docs/API.md#auth"},
+	}
+	if !reflect.DeepEqual(result.BrokenAnnotations, want) {
+		t.Errorf("BrokenAnnotations =\n%+v\nwant\n%+v", result.BrokenAnnotations, want)
+	}
+	if !reflect.DeepEqual(result.DocFiles, []string{"README.md", "docs/API.md", "docs/guide.md"}) {
+		t.Errorf("DocFiles = %v, want excluded dirs left out", result.DocFiles)
+	}
+}
+
+func TestBrokenReason(t *testing.T) {
+	tmpDir := setupTestDir(t, map[string]string{
+		"docs/API.md": "# API\n",
+	})
+	tests := []struct {
+		docPath string
+		want    string
+	}{
+		{"docs/API.md", ""},
+		{"docs/api.md", BrokenMissing},
+		{"Docs/API.md", BrokenMissing},
+		{"docs", BrokenMissing},
+		{"../docs/API.md", BrokenOutsideRepo},
+	}
+	for _, tt := range tests {
+		if got := BrokenReason(tmpDir, tt.docPath); got != tt.want {
+			t.Errorf("BrokenReason(%q) = %q, want %q", tt.docPath, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestDoc(t *testing.T) {
+	docs := []string{"README.md", "docs/API.md", "docs/ARCHITECTURE.md", "docs/api/README.md"}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"docs/APi.md", "docs/API.md"},
+		{"docs/AIP.md", "docs/API.md"},
+		{"docs/ARCHITECTUR.md", "docs/ARCHITECTURE.md"},
+		{"readme.md", "README.md"},
+		{"docs/README.md", "docs/api/README.md"}, // same name: the nearest path wins
+		{"docs/DEPLOYMENT.md", ""},
+	}
+	for _, tt := range tests {
+		if got := SuggestDoc(tt.target, docs); got != tt.want {
+			t.Errorf("SuggestDoc(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
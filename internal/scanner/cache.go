@@ -0,0 +1,187 @@
+package scanner
+
+// @doc README.md
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/StevenBock/docdiff/internal/git"
+	"github.com/StevenBock/docdiff/internal/language"
+)
+
+// cacheVersion is part of every cache fingerprint. Bump it whenever detection
+// or extraction changes in a way the registry signature can't see (a new
+// built-in matcher, a fix to the block finders, a change to cacheEntry).
+const cacheVersion = 2
+
+// racyWindow guards against edits that land within the filesystem's timestamp
+// granularity of a scan: a file modified this recently is re-read next time
+// instead of trusting its (possibly unchanged) mtime and size.
+const racyWindow = 2 * time.Second
+
+// cacheEntry is what a scan learned about one file: the language it was
+// detected as ("" if none), its annotations and, when a scan indexed them, the
+// symbols it declares. It is valid while size and mtime still match.
+type cacheEntry struct {
+	Size     int64
+	ModTime  int64 // UnixNano
+	Language string
+	Details  []language.DocAnnotation
+	Symbols  []string
+	Indexed  bool // Symbols was filled in
+}
+
+type cacheFile struct {
+	Fingerprint string
+	Entries     map[string]cacheEntry
+}
+
+// scanCache maps relative paths to extraction results on disk, under
+// <git-dir>/docdiff/cache-<root>, so unchanged files skip reading and regex
+// scanning. Paths are relative to the scanned root, so each root (`--dir`) has
+// its own file and scans of different subtrees don't evict each other. The
+// whole cache is dropped when its fingerprint (root, annotation tag, scope
+// ownership mode, language registry, cacheVersion) no longer matches.
+type scanCache struct {
+	path        string
+	fingerprint string
+	started     time.Time
+	old         map[string]cacheEntry
+	entries     map[string]cacheEntry
+	dirty       bool
+}
+
+// openScanCache returns the cache for this scan: the previous scan's entries
+// when the Scanner retains them, else the on-disk cache for rootDir. It is nil
+// when caching is disabled or rootDir isn't a git checkout, unless retaining.
+// A nil cache is valid and inert.
+func (s *Scanner) openScanCache(rootDir string) *scanCache {
+	if s.retain && s.retained != nil {
+		return &scanCache{started: time.Now(), old: s.retained, entries: make(map[string]cacheEntry)}
+	}
+	c := s.openDiskCache(rootDir)
+	if c == nil && s.retain {
+		c = &scanCache{started: time.Now(), entries: make(map[string]cacheEntry)}
+	}
+	return c
+}
+
+// openDiskCache loads rootDir's cache file under <git-dir>/docdiff, or returns
+// nil when caching is disabled or rootDir isn't a git checkout.
+func (s *Scanner) openDiskCache(rootDir string) *scanCache {
+	if !s.config.ScanCacheEnabled() {
+		return nil
+	}
+	g, err := git.Open(rootDir, s.config.GitBackend)
+	if err != nil {
+		return nil
+	}
+	gitDir, err := g.Dir()
+	if err != nil || gitDir == "" {
+		return nil
+	}
+
+	root, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rootSum := sha256.Sum256([]byte(root))
+
+	h := sha256.New()
+	h.Write([]byte{cacheVersion})
+	h.Write([]byte(root + "\x00" + s.config.AnnotationTag + "\x00" + s.config.ScopeOwnership + "\x00"))
+	h.Write([]byte(s.registry.Signature()))
+
+	c := &scanCache{
+		path:        filepath.Join(gitDir, "docdiff", "cache-"+hex.EncodeToString(rootSum[:6])),
+		fingerprint: hex.EncodeToString(h.Sum(nil)),
+		started:     time.Now(),
+		entries:     make(map[string]cacheEntry),
+	}
+	if f, err := os.Open(c.path); err == nil {
+		var cf cacheFile
+		if gob.NewDecoder(f).Decode(&cf) == nil && cf.Fingerprint == c.fingerprint {
+			c.old = cf.Entries
+		}
+		f.Close()
+	}
+	if c.old == nil {
+		c.dirty = true // missing, corrupt or invalidated: rewrite it
+	}
+	return c
+}
+
+// lookup returns the cached entry for relPath if the file is unchanged. It
+// only reads the loaded cache, so scan workers may call it concurrently.
+func (c *scanCache) lookup(relPath string, info fs.FileInfo) (cacheEntry, bool) {
+	if c == nil || info == nil {
+		return cacheEntry{}, false
+	}
+	e, ok := c.old[relPath]
+	if !ok || e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() || c.racy(info) {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+// keep carries a cache hit over into the next saved cache.
+func (c *scanCache) keep(relPath string, e cacheEntry) {
+	if c == nil {
+		return
+	}
+	c.entries[relPath] = e
+}
+
+// store records a fresh extraction result. Racily-modified files aren't kept.
+func (c *scanCache) store(relPath string, info fs.FileInfo, e cacheEntry) {
+	if c == nil || info == nil || c.racy(info) {
+		return
+	}
+	e.Size = info.Size()
+	e.ModTime = info.ModTime().UnixNano()
+	c.entries[relPath] = e
+	c.dirty = true
+}
+
+func (c *scanCache) racy(info fs.FileInfo) bool {
+	return !info.ModTime().Before(c.started.Add(-racyWindow))
+}
+
+// save writes the cache if anything changed, dropping entries for files this
+// scan didn't visit. Failures are ignored: the cache is only an optimization.
+// A memory-only cache (no path) is never written.
+func (c *scanCache) save() {
+	if c == nil || c.path == "" {
+		return
+	}
+	if !c.dirty && len(c.entries) == len(c.old) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "cache-*.tmp")
+	if err != nil {
+		return
+	}
+	err = gob.NewEncoder(tmp).Encode(cacheFile{Fingerprint: c.fingerprint, Entries: c.entries})
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), c.path) != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,163 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/language"
+)
+
+func TestScanCache(t *testing.T) {
+	tmpDir := setupTestDir(t, map[string]string{
+		"src/app.go": "package main\n// @doc docs/A.md\nfunc main() {}\n",
+	})
+	gitInit(t, tmpDir)
+	app := filepath.Join(tmpDir, "src", "app.go")
+	old := time.Now().Add(-time.Hour)
+
+	// rewrite swaps the file's content for same-size content and restores its
+	// mtime, so only a cache hit can still report the original annotation.
+	rewrite := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(app, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(app, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docsWith := func(cfg *config.Config, registry *language.Registry) []string {
+		t.Helper()
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		ann, ok := result.Annotations["src/app.go"]
+		if !ok {
+			return nil
+		}
+		return ann.DocPaths
+	}
+	docs := func(cfg *config.Config) []string {
+		return docsWith(cfg, language.DefaultRegistry())
+	}
+
+	rewrite("package main\n// @doc docs/A.md\nfunc main() {}\n")
+	if got := docs(config.DefaultConfig()); len(got) != 1 || got[0] != "docs/A.md" {
+		t.Fatalf("first scan = %v, want [docs/A.md]", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, ".git", "docdiff", "cache-*")); len(files) != 1 {
+		t.Fatalf("cache files = %v, want one", files)
+	}
+
+	rewrite("package main\n// @doc docs/B.md\nfunc main() {}\n")
+	if got := docs(config.DefaultConfig()); len(got) != 1 || got[0] != "docs/A.md" {
+		t.Errorf("unchanged size+mtime should hit the cache, got %v", got)
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		off := false
+		cfg.ScanCache = &off
+		if got := docs(cfg); len(got) != 1 || got[0] != "docs/B.md" {
+			t.Errorf("scan_cache: false should read the file, got %v", got)
+		}
+	})
+
+	t.Run("language change invalidates", func(t *testing.T) {
+		rewrite("package main\n// @doc docs/A.md\nfunc main() {}\n")
+		docs(config.DefaultConfig())
+		rewrite("package main\n// @doc docs/B.md\nfunc main() {}\n")
+		registry, err := language.FromConfig(map[string]config.LanguageConfig{"go": {Extensions: []string{".gox"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := docsWith(config.DefaultConfig(), registry); len(got) != 1 || got[0] != "docs/B.md" {
+			t.Errorf("a different registry should drop the cache, got %v", got)
+		}
+	})
+
+	t.Run("tag change invalidates", func(t *testing.T) {
+		rewrite("package main\n// @doc docs/A.md\nfunc main() {}\n")
+		docs(config.DefaultConfig())
+		rewrite("package main\n// @see docs/B.md\nfunc main() {}\n")
+		cfg := config.DefaultConfig()
+		cfg.AnnotationTag = "@see"
+		if got := docs(cfg); len(got) != 1 || got[0] != "docs/B.md" {
+			t.Errorf("a different tag should drop the cache, got %v", got)
+		}
+	})
+
+	t.Run("size change misses", func(t *testing.T) {
+		rewrite("package main\n// @doc docs/CC.md\nfunc main() {}\n")
+		if got := docs(config.DefaultConfig()); len(got) != 1 || got[0] != "docs/CC.md" {
+			t.Errorf("a resized file should be re-read, got %v", got)
+		}
+	})
+
+	t.Run("racy files are not trusted", func(t *testing.T) {
+		os.WriteFile(app, []byte("package main\n// @doc docs/D.md\nfunc main() {}\n"), 0644)
+		docs(config.DefaultConfig())
+		os.WriteFile(app, []byte("package main\n// @doc docs/E.md\nfunc main() {}\n"), 0644)
+		if got := docs(config.DefaultConfig()); len(got) != 1 || got[0] != "docs/E.md" {
+			t.Errorf("a just-modified file must be re-read, got %v", got)
+		}
+	})
+
+	t.Run("entries without symbols miss when indexing", func(t *testing.T) {
+		rewrite("package main\n// @doc docs/A.md\nfunc Main() {}\n")
+		docs(config.DefaultConfig())
+		rewrite("package main\n// @doc docs/A.md\nfunc Fain() {}\n")
+		s := New(config.DefaultConfig(), language.DefaultRegistry())
+		s.IndexSymbols()
+		result, err := s.Scan(tmpDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.Symbols.Declared("src/app.go"); len(got) != 1 || got[0] != "Fain" {
+			t.Errorf("a cached entry without symbols should be re-read, got %v", got)
+		}
+	})
+
+	t.Run("subtree scans keep their own cache", func(t *testing.T) {
+		rewrite("package main\n// @doc docs/A.md\nfunc main() {}\n")
+		docs(config.DefaultConfig())
+		if _, err := New(config.DefaultConfig(), language.DefaultRegistry()).Scan(filepath.Join(tmpDir, "src")); err != nil {
+			t.Fatalf("Scan(src) error = %v", err)
+		}
+		rewrite("package main\n// @doc docs/B.md\nfunc main() {}\n")
+		if got := docs(config.DefaultConfig()); len(got) != 1 || got[0] != "docs/A.md" {
+			t.Errorf("a scan rooted at src/ should not evict the root's cache, got %v", got)
+		}
+	})
+
+	t.Run("retained in memory", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		off := false
+		cfg.ScanCache = &off
+		s := New(cfg, language.DefaultRegistry())
+		s.Retain()
+		scan := func() []string {
+			t.Helper()
+			result, err := s.Scan(tmpDir)
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			return result.Annotations["src/app.go"].DocPaths
+		}
+
+		rewrite("package main\n// @doc docs/A.md\nfunc main() {}\n")
+		scan()
+		rewrite("package main\n// @doc docs/B.md\nfunc main() {}\n")
+		if got := scan(); len(got) != 1 || got[0] != "docs/A.md" {
+			t.Errorf("a retaining scanner should reuse its last result, got %v", got)
+		}
+		s.Forget("src/app.go")
+		if got := scan(); len(got) != 1 || got[0] != "docs/B.md" {
+			t.Errorf("Forget should force a re-read, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package scanner
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docparse"
+	"github.com/StevenBock/docdiff/internal/language"
+)
+
+// Link is a doc-to-file link declared outside the file, so no annotation
+// records it: a source glob in the doc's front matter, or a rule in the
+// mapping file.
+type Link struct {
+	Doc     string `json:"doc"`
+	File    string `json:"file"`
+	Kind    string `json:"kind"`    // LinkFrontMatter or LinkMapping
+	Pattern string `json:"pattern"` // the glob or path that matched File, as declared
+	Scope   string `json:"scope,omitempty"`
+	Start   int    `json:"start,omitempty"` // the scope's owned lines; 0 for the whole file
+	End     int    `json:"end,omitempty"`
+}
+
+const (
+	LinkFrontMatter = "front-matter"
+	LinkMapping     = "mapping"
+)
+
+// AddLink records l and adds l.File to l.Doc's linked files, unless the file
+// annotates the doc itself: its own annotations take precedence. A file can
+// carry several links to one doc, one per scope.
+func (r *Result) AddLink(l Link) {
+	if ann := r.Annotations[l.File]; ann != nil && slices.Contains(ann.DocPaths, l.Doc) {
+		return
+	}
+	linked := false
+	for _, e := range r.Links {
+		if e.Doc == l.Doc && e.File == l.File {
+			if e.Scope == l.Scope {
+				return
+			}
+			linked = true
+		}
+	}
+	r.Links = append(r.Links, l)
+	if !linked {
+		r.FilesByDoc[l.Doc] = append(r.FilesByDoc[l.Doc], l.File)
+	}
+	r.ownership = nil
+}
+
+// LinksFor returns the declared links from doc to file, empty when the pair
+// is linked by an annotation or not at all.
+func (r *Result) LinksFor(doc, file string) []Link {
+	var links []Link
+	for _, l := range r.Links {
+		if l.Doc == doc && l.File == file {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// Ownership is Annotations with the region of every scoped link folded in as
+// a Declared detail, for working out which changes hit which doc. A link
+// without a region needs no detail: a file with no detail for a doc is owned
+// whole. When any link to a doc owns the whole file, its scoped links to that
+// doc are dropped, as whole-file ownership wins.
+func (r *Result) Ownership() map[string]*Annotation {
+	if r.ownership != nil {
+		return r.ownership
+	}
+	whole := make(map[[2]string]bool)
+	for _, l := range r.Links {
+		if l.Start == 0 {
+			whole[[2]string{l.Doc, l.File}] = true
+		}
+	}
+	own := make(map[string]*Annotation, len(r.Annotations))
+	for f, ann := range r.Annotations {
+		own[f] = ann
+	}
+	for _, l := range r.Links {
+		if l.Start == 0 || whole[[2]string{l.Doc, l.File}] {
+			continue
+		}
+		ann := &Annotation{FilePath: l.File}
+		if existing := own[l.File]; existing != nil {
+			*ann = *existing
+			ann.Details = slices.Clone(existing.Details)
+		}
+		ann.Details = append(ann.Details, language.DocAnnotation{Path: l.Doc, Scope: l.Scope, Line: l.Start, End: l.End, Declared: true})
+		own[l.File] = ann
+	}
+	r.ownership = own
+	return own
+}
+
+// IsGlob reports whether a declared source pattern has glob syntax, as
+// opposed to naming a single file.
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
+}
+
+// linkFrontMatter reads each doc's front matter and links it to the
+// files its `docdiff: sources` globs match. Patterns resolve like annotation
+// targets: ./ and ../ are relative to the doc, anything else to the root.
+// They match every file the walk saw, not just files in a known language, so
+// schemas and fixtures can be declared too.
+func linkFrontMatter(rootDir string, result *Result, tree []string) {
+	for _, doc := range result.DocFiles {
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(doc)))
+		if err != nil {
+			continue
+		}
+		fm, err := docparse.ParseFrontMatter(content)
+		if err != nil {
+			log.Printf("Warning: %s: %v", doc, err)
+			continue
+		}
+		for _, pattern := range fm.Sources {
+			matches := matchTree(ResolveDocPath(doc, pattern), tree)
+			if len(matches) == 0 {
+				log.Printf("Warning: %s: front matter source %q matches no files", doc, pattern)
+			}
+			for _, f := range matches {
+				if f != doc {
+					result.AddLink(Link{Doc: doc, File: f, Kind: LinkFrontMatter, Pattern: pattern})
+				}
+			}
+		}
+	}
+}
+
+// linkMappings links the files each mapping rule matches to its doc, one link
+// per scope. Patterns and docs are repository-relative; docs go through the
+// aliases like annotation targets. A scope that can't be resolved in a file,
+// such as a symbol it doesn't declare, falls back to the whole file with a
+// warning, so a change is never missed.
+func (s *Scanner) linkMappings(rootDir string, result *Result, tree []string) {
+	for _, rule := range s.config.Links {
+		doc := ResolveAlias(result.aliases, ResolveDocPath("", rule.Doc))
+		if path, _ := docparse.SplitAnchor(doc); BrokenReason(rootDir, path) != "" {
+			log.Printf("Warning: links: %s for %s is %s", rule.Doc, rule.Files, BrokenReason(rootDir, path))
+			continue
+		}
+		matches := matchTree(ResolveDocPath("", rule.Files), tree)
+		if len(matches) == 0 {
+			log.Printf("Warning: links: %q matches no files", rule.Files)
+		}
+		for _, f := range matches {
+			if f == doc {
+				continue
+			}
+			if len(rule.Scopes) == 0 {
+				result.AddLink(Link{Doc: doc, File: f, Kind: LinkMapping, Pattern: rule.Files})
+				continue
+			}
+			for _, scope := range rule.Scopes {
+				l := Link{Doc: doc, File: f, Kind: LinkMapping, Pattern: rule.Files, Scope: scope}
+				var ok bool
+				if l.Start, l.End, ok = s.scopeRegion(rootDir, f, scope); !ok {
+					log.Printf("Warning: links: scope %q not found in %s; linking the whole file", scope, f)
+				}
+				result.AddLink(l)
+			}
+		}
+	}
+}
+
+// scopeRegion resolves a mapping scope in file to the lines it owns: a line
+// range as written, or a symbol's declaration, found with the language's
+// SymbolDeclarer and BlockFinder.
+func (s *Scanner) scopeRegion(rootDir, file, scope string) (int, int, bool) {
+	if start, end, ok := config.ParseLineRange(scope); ok {
+		return start, end, true
+	}
+	path := filepath.Join(rootDir, filepath.FromSlash(file))
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	strategy, ok := s.detector.Detect(file, content)
+	if !ok {
+		return 0, 0, false
+	}
+	declarer, ok := strategy.(language.SymbolDeclarer)
+	if !ok {
+		return 0, 0, false
+	}
+	line, ok := language.DeclarationLine(declarer, content, scope)
+	if !ok {
+		return 0, 0, false
+	}
+	if finder, ok := strategy.(language.BlockFinder); ok {
+		if start, end, ok := finder.DeclarationBlock(content, line); ok {
+			return start, end, true
+		}
+	}
+	return line, line, true
+}
+
+// matchTree returns the paths in tree that pattern matches, sorted.
+func matchTree(pattern string, tree []string) []string {
+	if !doublestar.ValidatePattern(pattern) {
+		log.Printf("Warning: invalid source pattern %q", pattern)
+		return nil
+	}
+	var matches []string
+	for _, f := range tree {
+		if ok, _ := doublestar.Match(pattern, f); ok {
+			matches = append(matches, f)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -7,11 +7,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
 
 	"github.com/StevenBock/docdiff/internal/config"
+	"github.com/StevenBock/docdiff/internal/docformat"
 	"github.com/StevenBock/docdiff/internal/docparse"
 	"github.com/StevenBock/docdiff/internal/filetype"
 	"github.com/StevenBock/docdiff/internal/git"
@@ -22,6 +25,11 @@ type Scanner struct {
 	config   *config.Config
 	detector *filetype.Detector
 	registry *language.Registry
+	formats  *docformat.Registry
+
+	retain   bool                  // keep extraction results between scans (Retain)
+	retained map[string]cacheEntry // entries from the previous scan, when retaining
+	symbols  bool                  // build Result.Symbols (IndexSymbols or symbol_refs)
 }
 
 func New(cfg *config.Config, registry *language.Registry) *Scanner {
@@ -29,9 +37,65 @@ func New(cfg *config.Config, registry *language.Registry) *Scanner {
 		config:   cfg,
 		detector: filetype.NewDetector(registry),
 		registry: registry,
+		formats:  docformat.DefaultRegistry(),
+		symbols:  cfg.SymbolRefs,
+	}
+}
+
+// IndexSymbols makes Scan build Result.Symbols, the names each file declares,
+// even when symbol_refs is off. With symbol_refs on, Scan also resolves the
+// docs' code spans through it for back-link checks.
+func (s *Scanner) IndexSymbols() {
+	s.symbols = true
+}
+
+// Retain makes this Scanner keep every file's extraction result in memory and
+// reuse it on the next Scan while the file's size and mtime are unchanged, even
+// when scan_cache is off or there is no git checkout. For long-running callers
+// such as `watch`, so a rescan re-reads only edited files.
+func (s *Scanner) Retain() {
+	s.retain = true
+}
+
+// Forget drops retained results for relPaths, forcing them to be re-read on the
+// next Scan even if their size and mtime look unchanged.
+func (s *Scanner) Forget(relPaths ...string) {
+	for _, p := range relPaths {
+		delete(s.retained, p)
 	}
 }
 
+// Dirs lists the directories a Scan of rootDir walks (rootDir itself as "."),
+// after excludes, .docdiffignore and gitignore pruning.
+func (s *Scanner) Dirs(rootDir string) ([]string, error) {
+	excludes := append([]string{}, s.config.Exclude...)
+	excludes = append(excludes, loadDocdiffIgnore(rootDir)...)
+	gitignore := newGitignorePruner(rootDir, s.config)
+
+	var dirs []string
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == rootDir {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if shouldSkipDir(rootDir, path, excludes, gitignore) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(rootDir, path)
+		if err != nil {
+			return nil
+		}
+		dirs = append(dirs, filepath.ToSlash(rel))
+		return nil
+	})
+	return dirs, err
+}
+
 type candidate struct {
 	path    string
 	relPath string
@@ -39,12 +103,17 @@ type candidate struct {
 
 func (s *Scanner) Scan(rootDir string) (*Result, error) {
 	result := NewResult()
+	result.aliases = s.config.Aliases
+	if s.symbols {
+		result.Symbols = docparse.NewSymbolIndex()
+	}
 
 	excludes := append([]string{}, s.config.Exclude...)
 	excludes = append(excludes, loadDocdiffIgnore(rootDir)...)
 	gitignore := newGitignorePruner(rootDir, s.config)
 
-	var candidates []candidate
+	var walked []candidate
+	var walkedDocs []string
 	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			if path == rootDir {
@@ -71,15 +140,18 @@ func (s *Scanner) Scan(rootDir string) (*Result, error) {
 
 		relPath = filepath.ToSlash(relPath)
 
+		isDoc := s.isDoc(relPath)
+		if isDoc {
+			walkedDocs = append(walkedDocs, relPath) // doc_globs can name docs exclude skips
+		}
 		if isExcluded(relPath, excludes) {
 			return nil
 		}
-
-		if len(s.config.Include) > 0 && !s.isIncluded(relPath) {
-			return nil
+		if isDoc {
+			result.DocFiles = append(result.DocFiles, relPath)
 		}
 
-		candidates = append(candidates, candidate{path: path, relPath: relPath})
+		walked = append(walked, candidate{path: path, relPath: relPath})
 		return nil
 	})
 
@@ -87,33 +159,161 @@ func (s *Scanner) Scan(rootDir string) (*Result, error) {
 		return result, err
 	}
 
-	candidates = s.filterGitignored(rootDir, candidates)
+	// Every file the walk kept can be a front matter source; only included
+	// ones are read for annotations.
+	walked = s.filterGitignored(rootDir, walked)
+	tree := make([]string, 0, len(walked))
+	var candidates []candidate
+	for _, c := range walked {
+		tree = append(tree, c.relPath)
+		if len(s.config.Include) == 0 || s.isIncluded(c.relPath) {
+			candidates = append(candidates, c)
+		}
+	}
 
-	for _, c := range candidates {
-		content, err := os.ReadFile(c.path)
-		if err != nil {
-			result.AddError(err)
+	cache := s.openScanCache(rootDir)
+	for i, o := range s.extractAll(candidates, cache) {
+		c := candidates[i]
+		switch {
+		case o.err != nil:
+			result.AddError(o.err)
 			continue
+		case o.cached:
+			cache.keep(c.relPath, o.entry)
+		default:
+			cache.store(c.relPath, o.info, o.entry)
 		}
-
-		strategy, ok := s.detector.Detect(c.path, content)
-		if !ok {
+		if o.entry.Language == "" {
 			continue
 		}
-
 		result.AddFile(c.relPath)
+		if result.Symbols != nil {
+			result.Symbols.Add(c.relPath, o.entry.Symbols)
+		}
+		if len(o.entry.Details) > 0 {
+			result.AddAnnotation(c.relPath, o.entry.Details, o.entry.Language)
+		}
+	}
+	cache.save()
+	if s.retain && cache != nil {
+		s.retained = cache.entries
+	}
+	findBrokenAnnotations(rootDir, result)
+	linkFrontMatter(rootDir, result, tree)
+	s.linkMappings(rootDir, result, tree)
+
+	s.scanDocsForRefs(rootDir, result, walkedDocs)
 
-		details := strategy.ExtractDetailed(content, s.config.AnnotationTag)
-		if len(details) > 0 {
-			result.AddAnnotation(c.relPath, details, strategy.Name())
+	return result, nil
+}
+
+// extraction is the outcome of reading one candidate: its language ("" when
+// no strategy claims it) and annotations, from the cache or a fresh read.
+type extraction struct {
+	entry  cacheEntry
+	info   fs.FileInfo
+	cached bool
+	err    error
+}
+
+// extractAll reads, detects and extracts every candidate on a bounded pool of
+// workers (config scan_workers, default GOMAXPROCS). Outcomes are returned in
+// candidate order, so merging them into a Result stays deterministic.
+func (s *Scanner) extractAll(candidates []candidate, cache *scanCache) []extraction {
+	out := make([]extraction, len(candidates))
+	workers := min(s.config.Workers(), len(candidates))
+	if workers <= 1 {
+		for i, c := range candidates {
+			out[i] = s.extract(c, cache)
 		}
+		return out
 	}
 
-	if err := s.scanDocsForRefs(rootDir, result); err != nil {
-		log.Printf("Warning: failed to scan docs for references: %v", err)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = s.extract(candidates[i], cache)
+			}
+		}()
 	}
+	for i := range candidates {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out
+}
 
-	return result, nil
+func (s *Scanner) extract(c candidate, cache *scanCache) extraction {
+	info, _ := os.Stat(c.path)
+	if e, ok := cache.lookup(c.relPath, info); ok && (e.Indexed || !s.symbols || e.Language == "") {
+		return extraction{entry: e, info: info, cached: true}
+	}
+
+	content, err := os.ReadFile(c.path)
+	if err != nil {
+		return extraction{err: err}
+	}
+
+	strategy, ok := s.detector.Detect(c.relPath, content)
+	if !ok {
+		return extraction{info: info}
+	}
+	e := cacheEntry{Language: strategy.Name(), Details: s.extractDetails(strategy, content)}
+	if s.symbols {
+		e.Indexed = true
+		if declarer, ok := strategy.(language.SymbolDeclarer); ok {
+			e.Symbols = declarer.DeclaredSymbols(content)
+		}
+	}
+	return extraction{entry: e, info: info}
+}
+
+// Strategy is the language the scan detects for the file at the repo-relative
+// path with the given content.
+func (s *Scanner) Strategy(path string, content []byte) (language.Strategy, bool) {
+	return s.detector.Detect(path, content)
+}
+
+// ExtractContent extracts annotations from content as if it were the file at
+// the repo-relative path, the way Scan would, without touching the disk. It
+// reports false when no language claims the file. Editors use it on unsaved
+// buffers.
+func (s *Scanner) ExtractContent(path string, content []byte) (string, []language.DocAnnotation, bool) {
+	strategy, ok := s.detector.Detect(path, content)
+	if !ok {
+		return "", nil, false
+	}
+	return strategy.Name(), s.extractDetails(strategy, content), true
+}
+
+func (s *Scanner) extractDetails(strategy language.Strategy, content []byte) []language.DocAnnotation {
+	details := strategy.ExtractDetailed(content, s.config.AnnotationTag)
+	if s.config.DeclarationScopes() {
+		declarationExtents(strategy, content, details)
+	}
+	return details
+}
+
+// declarationExtents sets End on each scoped annotation that sits directly
+// above a declaration, for strategies that can find one.
+func declarationExtents(strategy language.Strategy, content []byte, details []language.DocAnnotation) {
+	finder, ok := strategy.(language.BlockFinder)
+	if !ok {
+		return
+	}
+	for i, d := range details {
+		if d.Scope == "" {
+			continue
+		}
+		if _, end, ok := finder.DeclarationBlock(content, d.Line); ok {
+			details[i].End = end
+		}
+	}
 }
 
 func shouldSkipDir(rootDir, path string, excludes []string, gitignore *gitignorePruner) bool {
@@ -137,7 +337,7 @@ func shouldSkipDir(rootDir, path string, excludes []string, gitignore *gitignore
 
 type gitignorePruner struct {
 	enabled bool
-	g       *git.Git
+	g       git.Repo
 }
 
 func newGitignorePruner(rootDir string, cfg *config.Config) *gitignorePruner {
@@ -145,8 +345,8 @@ func newGitignorePruner(rootDir string, cfg *config.Config) *gitignorePruner {
 	if !cfg.GitignoreRespected() {
 		return p
 	}
-	g := git.New(rootDir)
-	if !g.IsRepo() {
+	g, err := git.Open(rootDir, cfg.GitBackend)
+	if err != nil || !g.IsRepo() {
 		return p
 	}
 	p.enabled = true
@@ -174,8 +374,8 @@ func (s *Scanner) filterGitignored(rootDir string, candidates []candidate) []can
 	if !s.config.GitignoreRespected() || len(candidates) == 0 {
 		return candidates
 	}
-	g := git.New(rootDir)
-	if !g.IsRepo() {
+	g, err := git.Open(rootDir, s.config.GitBackend)
+	if err != nil || !g.IsRepo() {
 		return candidates
 	}
 	rels := make([]string, len(candidates))
@@ -302,18 +502,83 @@ func (s *Scanner) isIncluded(relPath string) bool {
 	return false
 }
 
-func (s *Scanner) scanDocsForRefs(rootDir string, result *Result) error {
+// isDoc reports whether relPath is documentation: in a doc format and, for a
+// location-bound format such as plain text, under docs_directory or matching
+// doc_globs.
+func (s *Scanner) isDoc(relPath string) bool {
+	if !s.formats.IsDoc(relPath) {
+		return false
+	}
+	if !s.formats.LocationBound(relPath) {
+		return true
+	}
+	dir := filepath.ToSlash(filepath.Clean(s.config.DocsDirectory))
+	if dir == "." || strings.HasPrefix(relPath, dir+"/") {
+		return true
+	}
+	for _, pattern := range s.config.DocGlobs {
+		if ok, _ := doublestar.Match(pattern, relPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// docsToScan returns the docs checked for references, sorted: files in a doc
+// format under docs_directory or among walked matching doc_globs, and every
+// existing doc an annotation or link targets, wherever it lives.
+func (s *Scanner) docsToScan(rootDir string, result *Result, walked []string) []string {
+	docs := make(map[string]bool)
 	docsDir := s.config.DocsPath(rootDir)
+	if _, err := os.Stat(docsDir); err == nil {
+		filepath.WalkDir(docsDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !s.formats.IsDoc(path) {
+				return nil
+			}
+			if rel, err := filepath.Rel(rootDir, path); err == nil {
+				docs[filepath.ToSlash(rel)] = true
+			}
+			return nil
+		})
+	}
+	for _, pattern := range s.config.DocGlobs {
+		for _, doc := range matchTree(pattern, walked) {
+			docs[doc] = true
+		}
+	}
+	for target := range result.FilesByDoc {
+		doc, _ := docparse.SplitAnchor(target)
+		if docs[doc] || strings.HasPrefix(doc, "../") || !s.formats.IsDoc(doc) {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(doc))); err == nil && info.Mode().IsRegular() {
+			docs[doc] = true
+		}
+	}
 
-	if _, err := os.Stat(docsDir); os.IsNotExist(err) {
-		return nil
+	sorted := make([]string, 0, len(docs))
+	for doc := range docs {
+		sorted = append(sorted, doc)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func (s *Scanner) scanDocsForRefs(rootDir string, result *Result, walked []string) {
+	docs := s.docsToScan(rootDir, result, walked)
+	if len(docs) == 0 {
+		return
 	}
 
 	extensions := s.registry.AllExtensions()
 	parser := docparse.New(result.AllFiles, extensions)
+	if s.config.SymbolRefs {
+		parser.WithSymbols(result.Symbols)
+	}
 
 	filesWithDocToThis := make(map[string]map[string]bool)
-	for doc, files := range result.FilesByDoc {
+	for target, files := range result.FilesByDoc {
+		doc, _ := docparse.SplitAnchor(target) // a section link covers refs in its doc
 		for _, f := range files {
 			if filesWithDocToThis[doc] == nil {
 				filesWithDocToThis[doc] = make(map[string]bool)
@@ -322,41 +587,25 @@ func (s *Scanner) scanDocsForRefs(rootDir string, result *Result) error {
 		}
 	}
 
-	return filepath.WalkDir(docsDir, func(path string, d fs.DirEntry, err error) error {
+	for _, relDocPath := range docs {
+		format, _ := s.formats.ForPath(relDocPath)
+		content, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(relDocPath)))
 		if err != nil {
-			return err
-		}
-
-		if d.IsDir() {
-			return nil
-		}
-
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".md" && ext != ".markdown" {
-			return nil
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		relDocPath, err := filepath.Rel(rootDir, path)
-		if err != nil {
-			return nil
+			continue
 		}
-		relDocPath = filepath.ToSlash(relDocPath)
+		result.Docs = append(result.Docs, relDocPath)
 
-		refs := parser.Parse(content)
+		prose := format.Prose(content)
+		refs := parser.AppendLinks(parser.Parse(prose), relDocPath, format.Links(prose))
 		linkedFiles := filesWithDocToThis[relDocPath]
 
 		for _, ref := range refs {
 			if linkedFiles != nil && linkedFiles[ref.Path] {
 				continue
 			}
-			result.AddUndocumentedRef(relDocPath, ref.Path, ref.Line)
+			result.AddUndocumentedRef(UndocumentedRef{
+				DocPath: relDocPath, SourceFile: ref.Path, Line: ref.Line, Symbol: ref.Symbol, Start: ref.Start, End: ref.End,
+			})
 		}
-
-		return nil
-	})
+	}
 }
@@ -1,8 +1,12 @@
 package scanner
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/StevenBock/docdiff/internal/config"
@@ -336,6 +340,256 @@ func handler() {}`,
 			t.Errorf("Expected 1 annotation (only .go supported), got %d", len(result.Annotations))
 		}
 	})
+
+	t.Run("symbol refs resolve code spans to declaring files", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/BILLING.md":    "# Billing\n\n`InvoiceTotal` sums the lines; `Handler` is linked.\n",
+			"billing/invoice.go": "package billing\n\nfunc InvoiceTotal() int { return 0 }\n",
+			"api/handler.go":     "package api\n\n// @doc docs/BILLING.md\nfunc Handler() {}\n",
+		})
+
+		cfg := config.DefaultConfig()
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if result.Symbols != nil || len(result.UndocumentedRefs) != 0 {
+			t.Errorf("symbol refs are opt-in, got %v, %+v", result.Symbols, result.UndocumentedRefs)
+		}
+
+		cfg.SymbolRefs = true
+		result, err = New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		want := []UndocumentedRef{{DocPath: "docs/BILLING.md", SourceFile: "billing/invoice.go", Line: 3, Symbol: "InvoiceTotal"}}
+		if !reflect.DeepEqual(result.UndocumentedRefs, want) {
+			t.Errorf("UndocumentedRefs = %+v, want %+v", result.UndocumentedRefs, want)
+		}
+	})
+
+	t.Run("front matter sources link docs to matching files", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/BILLING.md":      "---\ndocdiff: { sources: [\"src/billing/**\", \"schemas/invoice.json\"] }\n---\n# Billing\n",
+			"src/billing/tax.go":   "package billing\n\nfunc Tax() {}\n",
+			"src/billing/total.go": "package billing\n\n// @doc docs/BILLING.md\nfunc Total() {}\n",
+			"src/api/handler.go":   "package api\n\nfunc Handler() {}\n",
+			"schemas/invoice.json": "{}\n",
+		})
+
+		result, err := New(config.DefaultConfig(), registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		files := append([]string(nil), result.FilesByDoc["docs/BILLING.md"]...)
+		sort.Strings(files)
+		wantFiles := []string{"schemas/invoice.json", "src/billing/tax.go", "src/billing/total.go"}
+		if !reflect.DeepEqual(files, wantFiles) {
+			t.Errorf("FilesByDoc = %v, want %v", files, wantFiles)
+		}
+		wantLinks := []Link{
+			{Doc: "docs/BILLING.md", File: "src/billing/tax.go", Kind: LinkFrontMatter, Pattern: "src/billing/**"},
+			{Doc: "docs/BILLING.md", File: "schemas/invoice.json", Kind: LinkFrontMatter, Pattern: "schemas/invoice.json"},
+		}
+		if !reflect.DeepEqual(result.Links, wantLinks) {
+			t.Errorf("Links = %+v, want %+v (the annotated file keeps its annotation)", result.Links, wantLinks)
+		}
+		if orphaned := result.OrphanedFiles(); !reflect.DeepEqual(orphaned, []string{"src/api/handler.go"}) {
+			t.Errorf("OrphanedFiles() = %v, want only the unlinked handler", orphaned)
+		}
+	})
+
+	t.Run("mapping links files and scopes to docs", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/MOBILE.md":       "# Mobile\n",
+			"docs/SCHEMAS.md":      "# Schemas\n",
+			"schemas/invoice.json": "{}\n",
+			"src/settings.go":      "package main\n\n// Mobile settings.\nvar MobileSettings = map[string]int{\n\t\"a\": 1,\n}\n\nvar General = 1\n",
+		})
+
+		cfg := config.DefaultConfig()
+		cfg.Links = []config.LinkRule{
+			{Files: "schemas/*.json", Doc: "docs/SCHEMAS.md"},
+			{Files: "src/settings.go", Doc: "docs/MOBILE.md", Scopes: []string{"MobileSettings", "L8", "Missing"}},
+		}
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		want := []Link{
+			{Doc: "docs/SCHEMAS.md", File: "schemas/invoice.json", Kind: LinkMapping, Pattern: "schemas/*.json"},
+			{Doc: "docs/MOBILE.md", File: "src/settings.go", Kind: LinkMapping, Pattern: "src/settings.go", Scope: "MobileSettings", Start: 4, End: 6},
+			{Doc: "docs/MOBILE.md", File: "src/settings.go", Kind: LinkMapping, Pattern: "src/settings.go", Scope: "L8", Start: 8, End: 8},
+			{Doc: "docs/MOBILE.md", File: "src/settings.go", Kind: LinkMapping, Pattern: "src/settings.go", Scope: "Missing"},
+		}
+		if !reflect.DeepEqual(result.Links, want) {
+			t.Errorf("Links = %+v, want %+v", result.Links, want)
+		}
+		if files := result.FilesByDoc["docs/MOBILE.md"]; !reflect.DeepEqual(files, []string{"src/settings.go"}) {
+			t.Errorf("FilesByDoc[docs/MOBILE.md] = %v, want the file once", files)
+		}
+		// The unresolved scope links the whole file, and whole-file wins.
+		if ann := result.Ownership()["src/settings.go"]; ann != nil {
+			t.Errorf("Ownership() = %+v, want no regions once a link owns the whole file", ann.Details)
+		}
+
+		cfg.Links[1].Scopes = cfg.Links[1].Scopes[:2]
+		result, err = New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		wantDetails := []language.DocAnnotation{
+			{Path: "docs/MOBILE.md", Scope: "MobileSettings", Line: 4, End: 6, Declared: true},
+			{Path: "docs/MOBILE.md", Scope: "L8", Line: 8, End: 8, Declared: true},
+		}
+		if ann := result.Ownership()["src/settings.go"]; ann == nil || !reflect.DeepEqual(ann.Details, wantDetails) {
+			t.Errorf("Ownership() = %+v, want the declared regions", ann)
+		}
+		if _, ok := result.Annotations["src/settings.go"]; ok {
+			t.Error("declared regions must not show up as annotations")
+		}
+	})
+
+	t.Run("back-links in every doc format", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/guide.rst":   "Guide\n=====\n\nSee `the handler <../src/handler.go>`_.\n\n.. code-block:: go\n\n   src/util.go\n",
+			"docs/api.adoc":    "= API\n\nlink:../src/util.go[util]\n",
+			"docs/notes.txt":   "Mind src/handler.go when editing.\n",
+			"docs/intro.mdx":   "Read [util](../src/util.go).\n",
+			"docs/diagram.svg": "<svg>src/util.go</svg>\n",
+			"src/handler.go":   "package main\n\nfunc Handler() {}\n",
+			"src/util.go":      "package main\n\nfunc Util() {}\n",
+		})
+
+		result, err := New(config.DefaultConfig(), registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		want := []UndocumentedRef{
+			{DocPath: "docs/api.adoc", SourceFile: "src/util.go", Line: 3},
+			{DocPath: "docs/guide.rst", SourceFile: "src/handler.go", Line: 4},
+			{DocPath: "docs/intro.mdx", SourceFile: "src/util.go", Line: 1},
+			{DocPath: "docs/notes.txt", SourceFile: "src/handler.go", Line: 1},
+		}
+		if !reflect.DeepEqual(result.UndocumentedRefs, want) {
+			t.Errorf("UndocumentedRefs = %+v, want %+v", result.UndocumentedRefs, want)
+		}
+		wantDocs := []string{"docs/api.adoc", "docs/guide.rst", "docs/intro.mdx", "docs/notes.txt"}
+		if !reflect.DeepEqual(result.DocFiles, wantDocs) {
+			t.Errorf("DocFiles = %v, want %v", result.DocFiles, wantDocs)
+		}
+	})
+
+	t.Run("markdown links resolve against the doc and keep line anchors", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/api/README.md": "# API\n\nSee [the handler](../../src/api/handler.go#L3-L5) and [util][].\n\n" +
+				"~~~\nsrc/legacy.go\n~~~\n\n<!-- src/legacy.go -->\n\n[util]: ../../src/util.go\n",
+			"src/api/handler.go": "package api\n\nfunc Handler() {\n\treturn\n}\n",
+			"src/util.go":        "package main\n\nfunc Util() {}\n",
+			"src/legacy.go":      "package main\n",
+		})
+
+		result, err := New(config.DefaultConfig(), registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		want := []UndocumentedRef{
+			{DocPath: "docs/api/README.md", SourceFile: "src/api/handler.go", Line: 3, Start: 3, End: 5},
+			{DocPath: "docs/api/README.md", SourceFile: "src/util.go", Line: 3},
+		}
+		if !reflect.DeepEqual(result.UndocumentedRefs, want) {
+			t.Errorf("UndocumentedRefs = %+v, want %+v", result.UndocumentedRefs, want)
+		}
+	})
+
+	t.Run("docs outside docs_directory are checked for references", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/API.md":         "See src/api.go here\n",
+			"README.md":           "Start at src/main.go and src/api.go today\n",
+			"adr/0001-storage.md": "Decided in src/store.go for now\n",
+			"notes/scratch.md":    "Ignore src/store.go here\n",
+			"src/main.go":         "// @doc README.md\npackage main\n",
+			"src/api.go":          "// @doc docs/API.md\npackage main\n",
+			"src/store.go":        "package main\n",
+		})
+
+		cfg := config.DefaultConfig()
+		cfg.DocGlobs = []string{"adr/*.md"}
+		cfg.Exclude = append(cfg.Exclude, "*.md") // annotations only; docs are still read
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		wantDocs := []string{"README.md", "adr/0001-storage.md", "docs/API.md"}
+		if !reflect.DeepEqual(result.Docs, wantDocs) {
+			t.Errorf("Docs = %v, want %v", result.Docs, wantDocs)
+		}
+		want := []UndocumentedRef{
+			{DocPath: "README.md", SourceFile: "src/api.go", Line: 1},
+			{DocPath: "adr/0001-storage.md", SourceFile: "src/store.go", Line: 1},
+		}
+		if !reflect.DeepEqual(result.UndocumentedRefs, want) {
+			t.Errorf("UndocumentedRefs = %+v, want %+v", result.UndocumentedRefs, want)
+		}
+	})
+
+	t.Run("text files are docs only under docs_directory or doc_globs", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"docs/notes.txt":       "Mind src/main.go\n",
+			"runbooks/deploy.txt":  "Restart src/main.go\n",
+			"requirements.txt":     "requests==2.31\n",
+			"cmake/CMakeLists.txt": "add_executable(src/main.go)\n",
+			"src/main.go":          "package main\n",
+		})
+
+		cfg := config.DefaultConfig()
+		cfg.DocGlobs = []string{"runbooks/*.txt"}
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		wantDocs := []string{"docs/notes.txt", "runbooks/deploy.txt"}
+		if !reflect.DeepEqual(result.DocFiles, wantDocs) {
+			t.Errorf("DocFiles = %v, want %v", result.DocFiles, wantDocs)
+		}
+		if !reflect.DeepEqual(result.Docs, wantDocs) {
+			t.Errorf("Docs = %v, want %v", result.Docs, wantDocs)
+		}
+	})
+
+	t.Run("declaration ownership records declaration extents", func(t *testing.T) {
+		tmpDir := setupTestDir(t, map[string]string{
+			"src/central.go": `package main
+
+// @doc docs/A.md #a
+func A() {
+}
+
+// @doc docs/B.md
+func B() {}
+`,
+		})
+
+		cfg := config.DefaultConfig()
+		cfg.ScopeOwnership = config.ScopeOwnershipDeclaration
+		result, err := New(cfg, registry).Scan(tmpDir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		details := result.Annotations["src/central.go"].Details
+		if len(details) != 2 {
+			t.Fatalf("Details = %+v, want 2", details)
+		}
+		if details[0].End != 5 {
+			t.Errorf("scoped annotation End = %d, want 5", details[0].End)
+		}
+		if details[1].End != 0 {
+			t.Errorf("unscoped annotation End = %d, want 0 (whole-file)", details[1].End)
+		}
+	})
 }
 
 func TestResult_AddAnnotation(t *testing.T) {
@@ -366,6 +620,83 @@ func TestResult_AddAnnotation(t *testing.T) {
 	}
 }
 
+func TestResult_AddAnnotationResolvesPaths(t *testing.T) {
+	r := NewResult()
+	r.AddAnnotation("pkg/api/handler.go", []language.DocAnnotation{
+		{Path: "../../docs/API.md", Line: 1},
+		{Path: "./README.md", Line: 2},
+		{Path: "/docs/API.md#auth", Scope: "login", Line: 3},
+		{Path: `docs\GUIDE.md`, Line: 4},
+	}, "go")
+
+	want := []string{"docs/API.md", "pkg/api/README.md", "docs/API.md#auth", "docs/GUIDE.md"}
+	if !reflect.DeepEqual(r.Annotations["pkg/api/handler.go"].DocPaths, want) {
+		t.Errorf("DocPaths = %v, want %v", r.Annotations["pkg/api/handler.go"].DocPaths, want)
+	}
+	if d := r.Annotations["pkg/api/handler.go"].Details[2]; d.Path != "docs/API.md#auth" || d.Scope != "login" || d.Line != 3 {
+		t.Errorf("resolved detail = %+v, want path, scope and line kept", d)
+	}
+	if len(r.FilesByDoc["docs/API.md"]) != 1 {
+		t.Errorf("FilesByDoc[docs/API.md] = %v, want the handler once", r.FilesByDoc["docs/API.md"])
+	}
+}
+
+func TestResolveDocPath(t *testing.T) {
+	tests := []struct {
+		source, target, want string
+	}{
+		{"src/a.go", "docs/API.md", "docs/API.md"},
+		{"src/a.go", "docs/./sub/../API.md", "docs/API.md"},
+		{"src/a.go", "/docs/API.md", "docs/API.md"},
+		{"src/a.go", "//docs/API.md", "docs/API.md"},
+		{"src/a.go", "./NOTES.md", "src/NOTES.md"},
+		{"src/pkg/a.go", "../../docs/API.md#auth", "docs/API.md#auth"},
+		{"a.go", "../outside.md", "../outside.md"},
+		{"src/a.go", `..\docs\API.md`, "docs/API.md"},
+		{"src/a.go", `docs\API.md`, "docs/API.md"},
+		{"src/a.go", "https://example.com/x.md", "https://example.com/x.md"},
+	}
+	for _, tt := range tests {
+		if got := ResolveDocPath(tt.source, tt.target); got != tt.want {
+			t.Errorf("ResolveDocPath(%q, %q) = %q, want %q", tt.source, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestResolveAlias(t *testing.T) {
+	aliases := map[string]string{
+		"docs/API.md":   "docs/api/http.md",
+		"guides/":       "docs/guides",
+		"guides/old.md": "docs/legacy.md",
+	}
+	tests := []struct {
+		target, want string
+	}{
+		{"docs/API.md", "docs/api/http.md"},
+		{"docs/API.md#auth", "docs/api/http.md#auth"},
+		{"docs/API.mdx", "docs/API.mdx"},
+		{"guides/setup.md", "docs/guides/setup.md"},
+		{"guides/old.md", "docs/legacy.md"},
+		{"guidesx/setup.md", "guidesx/setup.md"},
+		{"docs/GUIDE.md", "docs/GUIDE.md"},
+	}
+	for _, tt := range tests {
+		if got := ResolveAlias(aliases, tt.target); got != tt.want {
+			t.Errorf("ResolveAlias(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+	if got := ResolveAlias(nil, "docs/API.md"); got != "docs/API.md" {
+		t.Errorf("ResolveAlias(nil) = %q, want the target unchanged", got)
+	}
+
+	r := NewResult()
+	r.aliases = aliases
+	r.AddAnnotation("src/a.go", []language.DocAnnotation{{Path: "../docs/API.md"}}, "go")
+	if docs := r.Annotations["src/a.go"].DocPaths; len(docs) != 1 || docs[0] != "docs/api/http.md" {
+		t.Errorf("AddAnnotation() should resolve the path, then the alias; got %v", docs)
+	}
+}
+
 func TestResult_AddFile(t *testing.T) {
 	r := NewResult()
 
@@ -407,3 +738,70 @@ func TestResult_OrphanedFiles(t *testing.T) {
 		t.Error("orphan2.go should be in orphaned list")
 	}
 }
+
+// syntheticTree writes n annotated source files spread over nested
+// directories, mixing languages so detection and extraction both do work.
+func syntheticTree(tb testing.TB, n int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	bodies := []struct{ ext, content string }{
+		{".go", "package p\n\n// @doc docs/GO.md #handlers\nfunc Handler() {\n\treturn\n}\n"},
+		{".py", "# @doc docs/PY.md\ndef handler():\n    return None\n"},
+		{".js", "/** @doc docs/JS.md */\nexport function handler() {}\n"},
+		{".txt", "not source\n"},
+	}
+	filler := strings.Repeat("// filler line without annotations\n", 40)
+	for i := range n {
+		b := bodies[i%len(bodies)]
+		path := filepath.Join(dir, fmt.Sprintf("pkg%02d", i%50), fmt.Sprintf("sub%d", i%7), fmt.Sprintf("f%05d%s", i, b.ext))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(b.content+filler), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestScanner_ParallelIsDeterministic(t *testing.T) {
+	dir := syntheticTree(t, 400)
+	scan := func(workers int) *Result {
+		cfg := config.DefaultConfig()
+		cfg.ScanWorkers = workers
+		result, err := New(cfg, language.DefaultRegistry()).Scan(dir)
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		return result
+	}
+	serial := scan(1)
+	for range 3 {
+		parallel := scan(8)
+		if !reflect.DeepEqual(serial.AllFiles, parallel.AllFiles) {
+			t.Fatal("AllFiles order differs between serial and parallel scans")
+		}
+		if !reflect.DeepEqual(serial.FilesByDoc, parallel.FilesByDoc) {
+			t.Fatal("FilesByDoc differs between serial and parallel scans")
+		}
+		if !reflect.DeepEqual(serial.Annotations, parallel.Annotations) {
+			t.Fatal("Annotations differ between serial and parallel scans")
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	dir := syntheticTree(b, 5000)
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg := config.DefaultConfig()
+			cfg.ScanWorkers = workers
+			s := New(cfg, language.DefaultRegistry())
+			for b.Loop() {
+				if _, err := s.Scan(dir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}